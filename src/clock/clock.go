@@ -0,0 +1,83 @@
+// Package clock implements the clocks that kademlia nodes measure time with: the real one, and a manual one
+// for tests.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of time for the TTLs, refreshes and timeouts of a node. Real is used by default, tests
+// use a Manual clock to decide exactly when time passes.
+type Clock interface {
+	Now() time.Time
+	// After returns a channel that receives the time once d has passed
+	After(d time.Duration) <-chan time.Time
+	// Sleep blocks until d has passed
+	Sleep(d time.Duration)
+}
+
+// Real is the system clock
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+
+// Manual is a clock that only moves when Advance is called
+type Manual struct {
+	mutex  sync.Mutex
+	now    time.Time
+	timers []manualTimer
+}
+
+// A channel that receives the time once the clock reaches at
+type manualTimer struct {
+	at      time.Time
+	channel chan time.Time
+}
+
+// NewManual returns a manual clock that starts at some time
+func NewManual(now time.Time) *Manual {
+	return &Manual{now: now}
+}
+
+func (clock *Manual) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	return clock.now
+}
+
+func (clock *Manual) After(d time.Duration) <-chan time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	channel := make(chan time.Time, 1)
+	if d <= 0 {
+		channel <- clock.now
+	} else {
+		clock.timers = append(clock.timers, manualTimer{clock.now.Add(d), channel})
+	}
+	return channel
+}
+
+func (clock *Manual) Sleep(d time.Duration) {
+	<-clock.After(d)
+}
+
+// Advance moves the clock forward and fires the timers (and wakes the sleepers) that are due
+func (clock *Manual) Advance(d time.Duration) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	clock.now = clock.now.Add(d)
+	var pending []manualTimer
+	for _, timer := range clock.timers {
+		if timer.at.After(clock.now) {
+			pending = append(pending, timer)
+		} else {
+			timer.channel <- clock.now
+		}
+	}
+	clock.timers = pending
+}
@@ -0,0 +1,57 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestManualClock(t *testing.T) {
+	start := time.Unix(0, 0)
+	clock := NewManual(start)
+
+	early := clock.After(time.Second)
+	late := clock.After(2 * time.Second)
+	select {
+	case <-clock.After(0):
+	default:
+		t.Errorf("After(0) did not fire right away")
+	}
+
+	clock.Advance(time.Second)
+	select {
+	case now := <-early:
+		if !now.Equal(start.Add(time.Second)) {
+			t.Errorf("After() = %v, want %v", now, start.Add(time.Second))
+		}
+	default:
+		t.Errorf("After(1s) did not fire after Advance(1s)")
+	}
+	select {
+	case <-late:
+		t.Errorf("After(2s) fired after Advance(1s)")
+	default:
+	}
+
+	woken := make(chan bool)
+	go func() {
+		clock.Sleep(time.Second)
+		woken <- true
+	}()
+	// Wait until the sleeper's timer is there next to late
+	for waiting := 0; waiting < 2; {
+		time.Sleep(time.Millisecond)
+		clock.mutex.Lock()
+		waiting = len(clock.timers)
+		clock.mutex.Unlock()
+	}
+	clock.Advance(time.Second)
+	<-late
+	select {
+	case <-woken:
+	case <-time.After(time.Second):
+		t.Errorf("Sleep(1s) did not return after Advance(1s)")
+	}
+	if !clock.Now().Equal(start.Add(2 * time.Second)) {
+		t.Errorf("Now() = %v, want %v", clock.Now(), start.Add(2*time.Second))
+	}
+}
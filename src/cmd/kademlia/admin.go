@@ -0,0 +1,156 @@
+package main
+
+import (
+	"d7024e/kademlia"
+	"d7024e/routing"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// The admin API is a read-only view of the state of the node, as JSON, for debugging a node without attaching to
+// the TTY of its container:
+//
+//	GET /admin/node      The ID and address of the node, its settings and the size of its routing table and storage
+//	GET /admin/routing   The contacts in each non-empty bucket of the routing table, with when they were last seen
+//	GET /admin/storage   The data objects and records stored on the node, with their size and remaining TTL
+//	GET /admin/refresh   The data the node keeps refreshing, with the contacts it is refreshed on
+//	GET /admin/lookups   The lookups that are running
+//
+// Times are RFC 3339 and durations are in milliseconds.
+
+const AdminURLprefix = "/admin/"
+
+type adminContact struct {
+	ID       string     `json:"id"`
+	Address  string     `json:"address"`
+	Streams  bool       `json:"streams"`
+	LastSeen *time.Time `json:"last_seen,omitempty"`
+	AgeMs    int64      `json:"last_seen_ago_ms,omitempty"`
+}
+
+type adminNode struct {
+	ID               string `json:"id"`
+	Address          string `json:"address"`
+	Streams          bool   `json:"streams"`
+	Started          bool   `json:"started"`
+	HTTPAddress      string `json:"http_address,omitempty"`
+	WriteQuorum      int    `json:"write_quorum"`
+	RequestTimeoutMs int64  `json:"request_timeout_ms"`
+	Contacts         int    `json:"contacts"`
+	StoredItems      int    `json:"stored_items"`
+	CachedItems      int    `json:"cached_items"`
+	StoredBytes      int    `json:"stored_bytes"`
+	Expirations      uint64 `json:"expirations"`
+	Refreshes        uint64 `json:"refreshes"`
+	Evictions        uint64 `json:"evictions"`
+}
+
+type adminBucket struct {
+	Index    int            `json:"index"`
+	Contacts []adminContact `json:"contacts"`
+}
+
+type adminItem struct {
+	Hash   string `json:"hash"`
+	Size   int    `json:"size"`
+	Cached bool   `json:"cached"`
+	Owners int    `json:"owners"`
+	TTLMs  int64  `json:"ttl_ms"`
+
+	ContentType string `json:"content_type,omitempty"`
+}
+
+type adminRefresh struct {
+	Hash     string         `json:"hash"`
+	Contacts []adminContact `json:"contacts"`
+}
+
+type adminLookup struct {
+	ID        uint64    `json:"id"`
+	Kind      string    `json:"kind"`
+	Target    string    `json:"target"`
+	Started   time.Time `json:"started"`
+	ElapsedMs int64     `json:"elapsed_ms"`
+	Rounds    int       `json:"rounds"`
+	Visited   int       `json:"visited"`
+	Unvisited int       `json:"unvisited"`
+}
+
+// AdminHTTPhandler serves the admin API, see above
+func (api *httpAPI) AdminHTTPhandler(w http.ResponseWriter, r *http.Request) {
+	network := api.network
+	now := time.Now()
+	var response interface{}
+	switch r.URL.Path[len(AdminURLprefix):] {
+	case "node":
+		response = newAdminNode(network.Info())
+	case "routing":
+		buckets := []adminBucket{}
+		for _, bucket := range network.Buckets() {
+			buckets = append(buckets, adminBucket{bucket.Index, newAdminContacts(bucket.Contacts, now)})
+		}
+		response = map[string]interface{}{"buckets": buckets}
+	case "storage":
+		items := []adminItem{}
+		for _, item := range network.StoredItems() {
+			items = append(items, adminItem{item.Hash.String(), item.Size, item.Cached, item.Owners,
+				item.TTL.Milliseconds(), item.ContentType})
+		}
+		response = map[string]interface{}{"items": items}
+	case "refresh":
+		keys := []adminRefresh{}
+		for hash, contacts := range network.Remembered() {
+			hash := hash
+			keys = append(keys, adminRefresh{hash.String(), newAdminContacts(contacts, now)})
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].Hash < keys[j].Hash
+		})
+		response = map[string]interface{}{"keys": keys}
+	case "lookups":
+		lookups := []adminLookup{}
+		for _, lookup := range network.Lookups() {
+			lookups = append(lookups, adminLookup{lookup.ID, lookup.Kind, lookup.Target.String(), lookup.Started,
+				now.Sub(lookup.Started).Milliseconds(), lookup.Rounds, lookup.Visited, lookup.Unvisited})
+		}
+		response = map[string]interface{}{"lookups": lookups}
+	default:
+		http.Error(w, "ERROR", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, response)
+}
+
+func newAdminNode(info kademlia.NodeInfo) adminNode {
+	return adminNode{info.ID.String(), info.Address, info.Streams, info.Started, info.HTTPAddress, info.WriteQuorum,
+		info.RequestTimeout.Milliseconds(), info.Contacts, info.Storage.Items, info.Storage.CachedItems,
+		info.Storage.Bytes, info.Storage.Expirations, info.Storage.Refreshes, info.Storage.Evictions}
+}
+
+// newAdminContacts converts contacts to JSON. Contacts that aren't in the routing table have no last seen time
+func newAdminContacts(contacts []routing.Contact, now time.Time) []adminContact {
+	converted := make([]adminContact, len(contacts))
+	for i, contact := range contacts {
+		converted[i] = adminContact{ID: contact.ID.String(), Address: contact.Address, Streams: contact.Streams}
+		if !contact.LastSeen.IsZero() {
+			lastSeen := contact.LastSeen
+			converted[i].LastSeen = &lastSeen
+			converted[i].AgeMs = now.Sub(lastSeen).Milliseconds()
+		}
+	}
+	return converted
+}
+
+// writeJSON writes a response as JSON with status 200
+func writeJSON(w http.ResponseWriter, response interface{}) {
+	jsonValue, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "ERROR", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonValue)
+}
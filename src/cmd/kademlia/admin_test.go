@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"d7024e/routing"
+	"d7024e/transport"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// getAdmin sends a GET request for an admin view through the router and decodes the JSON response
+func getAdmin(t *testing.T, router http.Handler, view string, response interface{}) {
+	httpRecorder := httptest.NewRecorder()
+	router.ServeHTTP(httpRecorder, httptest.NewRequest("GET", AdminURLprefix+view, nil))
+	if httpRecorder.Code != http.StatusOK {
+		t.Fatalf("WRONG STATUS CODE: GOT %v EXPECTED %v", httpRecorder.Code, http.StatusOK)
+	}
+	if err := json.Unmarshal(httpRecorder.Body.Bytes(), response); err != nil {
+		t.Fatalf("json.Unmarshal() = %v, want %v", err, nil)
+	}
+}
+
+func TestAdminHTTPhandler(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	ip1 := net.ParseIP("0.0.0.0")
+	ip2 := net.ParseIP("0.0.0.1")
+	net1 := newMemoryNetwork(fake, &ip1)
+	net2 := newMemoryNetwork(fake, &ip2)
+	net1.Start()
+	net2.Start()
+	defer net1.Stop()
+	defer net2.Stop()
+	time.Sleep(10 * time.Millisecond)
+	net2.Join(net1.ID(), ip1.String())
+	hash, _ := net2.Put(context.Background(), []byte("Hello world!"))
+	router := httpRouter(net2)
+
+	var node adminNode
+	getAdmin(t, router, "node", &node)
+	if node.ID != net2.ID().String() || node.Address != ip2.String() || !node.Started || node.Contacts != 1 ||
+		node.StoredItems != 1 || node.StoredBytes != len("Hello world!") {
+		t.Errorf("AdminHTTPhandler() node = %+v, want node %v with 1 contact and 1 item", node, net2.ID())
+	}
+
+	var routingTable struct{ Buckets []adminBucket }
+	getAdmin(t, router, "routing", &routingTable)
+	if len(routingTable.Buckets) != 1 || len(routingTable.Buckets[0].Contacts) != 1 ||
+		routingTable.Buckets[0].Contacts[0].ID != net1.ID().String() || routingTable.Buckets[0].Contacts[0].LastSeen == nil {
+		t.Errorf("AdminHTTPhandler() routing = %+v, want a bucket with %v", routingTable, net1.ID())
+	}
+
+	var storage struct{ Items []adminItem }
+	getAdmin(t, router, "storage", &storage)
+	if len(storage.Items) != 1 || storage.Items[0].Hash != hash.String() || storage.Items[0].TTLMs <= 0 {
+		t.Errorf("AdminHTTPhandler() storage = %+v, want an item %v with a TTL", storage, hash)
+	}
+
+	var refresh struct{ Keys []adminRefresh }
+	getAdmin(t, router, "refresh", &refresh)
+	if len(refresh.Keys) != 1 || refresh.Keys[0].Hash != hash.String() || len(refresh.Keys[0].Contacts) != 2 {
+		t.Errorf("AdminHTTPhandler() refresh = %+v, want %v refreshed on 2 contacts", refresh, hash)
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	router.ServeHTTP(httpRecorder, httptest.NewRequest("GET", AdminURLprefix+"unknown", nil))
+	if httpRecorder.Code != http.StatusNotFound {
+		t.Errorf("WRONG STATUS CODE: GOT %v EXPECTED %v", httpRecorder.Code, http.StatusNotFound)
+	}
+}
+
+// A lookup that waits for a contact that never answers is listed until it is done
+func TestAdminHTTPhandler_Lookups(t *testing.T) {
+	net, stop := newSilentNetwork(transport.NewMemoryNetwork())
+	defer stop()
+	router := httpRouter(net)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool)
+	target := routing.NewKademliaIDFromData("missing")
+	go func() {
+		net.Get(ctx, target)
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	var lookups struct{ Lookups []adminLookup }
+	getAdmin(t, router, "lookups", &lookups)
+	if len(lookups.Lookups) != 1 || lookups.Lookups[0].Kind != "data" || lookups.Lookups[0].Target != target.String() ||
+		lookups.Lookups[0].Rounds != 1 {
+		t.Errorf("AdminHTTPhandler() lookups = %+v, want a data lookup of %v in round 1", lookups, target)
+	}
+
+	cancel()
+	<-done
+	getAdmin(t, router, "lookups", &lookups)
+	if len(lookups.Lookups) != 0 {
+		t.Errorf("AdminHTTPhandler() lookups = %+v, want none", lookups)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bufio"
+	"d7024e/kademlia"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// Batch mode of the command line: the commands of a script file or of piped stdin are run one per line without a
+// prompt, and the result of each command is written as a line of JSON
+
+// The longest line of a script, which may put a large object
+const MAX_SCRIPT_LINE = 16 * 1024 * 1024
+
+// batchResult is the result of a command of a script
+type batchResult struct {
+	Line    int    `json:"line"`
+	Command string `json:"command"`
+	OK      bool   `json:"ok"`
+	Output  string `json:"output"`
+}
+
+// runBatch runs the commands of a script until it ends or exits, or until a command fails if stopOnError is set.
+// Blank lines and comments (lines starting with #) are skipped. Returns the number of commands that failed
+func runBatch(script io.Reader, output io.Writer, network *kademlia.Network, stopOnError bool) (int, error) {
+	scanner := bufio.NewScanner(script)
+	scanner.Buffer(make([]byte, 64*1024), MAX_SCRIPT_LINE)
+	encoder := json.NewEncoder(output)
+	encoder.SetEscapeHTML(false)
+	failed := 0
+	for line := 1; scanner.Scan(); line++ {
+		input := scanner.Text()
+		command := strings.TrimSpace(input)
+		if command == "" || strings.HasPrefix(command, "#") {
+			continue
+		}
+		if strings.ToLower(command) == "exit" {
+			break
+		}
+		result := parseInput(input, network)
+		if err := encoder.Encode(batchResult{line, command, result.OK, result.Output}); err != nil {
+			return failed, err
+		}
+		if !result.OK {
+			failed++
+			if stopOnError {
+				break
+			}
+		}
+	}
+	return failed, scanner.Err()
+}
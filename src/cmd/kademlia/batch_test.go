@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"d7024e/routing"
+	"d7024e/transport"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestRunBatch(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	ip := net.ParseIP("0.0.0.0")
+	network := newMemoryNetwork(fake, &ip)
+	hash := routing.NewKademliaIDFromData("hello world").String()
+	missing := routing.NewKademliaIDFromData("missing").String()
+	script := "# Store and read back an object\n" +
+		"put hello world\n" +
+		"\n" +
+		"  # Indented comment\n" +
+		"get " + hash + "\r\n" +
+		"get " + missing + "\n" +
+		"nonsense\n" +
+		"exit\n" +
+		"put never run\n"
+
+	tests := []struct {
+		name        string
+		stopOnError bool
+		want        []batchResult
+	}{
+		{"Run all", false, []batchResult{
+			{2, "put hello world", true, hash},
+			{5, "get " + hash, true, "NodeID: " + network.ID().String() + "  Content: hello world"},
+			{6, "get " + missing, false, "NodeID: [NULL]  Content: Could not find node or data in the network"},
+			{7, "nonsense", false, "INVALID COMMAND, TYPE HELP"},
+		}},
+		{"Stop on error", true, []batchResult{
+			{2, "put hello world", true, hash},
+			{5, "get " + hash, true, "NodeID: " + network.ID().String() + "  Content: hello world"},
+			{6, "get " + missing, false, "NodeID: [NULL]  Content: Could not find node or data in the network"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			failed, err := runBatch(strings.NewReader(script), &output, network, tt.stopOnError)
+			if err != nil {
+				t.Fatalf("runBatch() error = %v", err)
+			}
+			var results []batchResult
+			decoder := json.NewDecoder(&output)
+			for decoder.More() {
+				var result batchResult
+				if err := decoder.Decode(&result); err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				results = append(results, result)
+			}
+			if len(results) != len(tt.want) {
+				t.Fatalf("runBatch() = %v, want %v", results, tt.want)
+			}
+			wantFailed := 0
+			for i, want := range tt.want {
+				if results[i] != want {
+					t.Errorf("runBatch() = %v, want %v", results[i], want)
+				}
+				if !want.OK {
+					wantFailed++
+				}
+			}
+			if failed != wantFailed {
+				t.Errorf("runBatch() = %v, want %v", failed, wantFailed)
+			}
+		})
+	}
+}
+
+func TestCommandResultOK(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	ip := net.ParseIP("0.0.0.0")
+	network := newMemoryNetwork(fake, &ip)
+	hash := routing.NewKademliaIDFromData("hello").String()
+	missing := routing.NewKademliaIDFromData("missing").String()
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"put hello", true},
+		{"get " + hash, true},
+		{"get " + missing, false},
+		{"get 1234", false},
+		{"put", false},
+		{"closest " + hash + " many", false},
+		{"lookup", false},
+		{"trace " + missing, false},
+		{"resolve " + missing, false},
+		{"join 00000", false},
+		{"stored", true},
+		{"help", true},
+		{"nonsense", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if result := parseInput(tt.input, network); result.OK != tt.want {
+			t.Errorf("parseInput(%q) = %v, want OK %v", tt.input, result, tt.want)
+		}
+	}
+}
@@ -0,0 +1,512 @@
+// The kademlia command runs a kademlia node with an interactive command line and an HTTP API, or without the
+// command line (serve). The put and get subcommands talk to a node running on the same host instead (see client.go),
+// and the ctl subcommand runs commands of the command line on it (see control.go).
+package main
+
+import (
+	"bufio"
+	"context"
+	"d7024e/codec"
+	"d7024e/kademlia"
+	"d7024e/logging"
+	"d7024e/routing"
+	"d7024e/storage"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+var cliLog = logging.For("cli")
+
+const DEFAULT_IDENTITY_FILE = "d7024e.key" // File the node keeps its key pair in, relative to the working directory
+
+// stopNode stops the node the command line runs on, before the process exits
+var stopNode = func() {}
+
+// Entrypoint
+func main() {
+	maxBytes := flag.Int("max-storage-bytes", storage.DEFAULT_MAX_STORAGE_BYTES,
+		"Maximum number of bytes of data this node stores for others (0 = unlimited)")
+	maxItems := flag.Int("max-storage-items", storage.DEFAULT_MAX_STORAGE_ITEMS,
+		"Maximum number of data objects this node stores for others (0 = unlimited)")
+	writeQuorum := flag.Int("write-quorum", kademlia.DEFAULT_WRITE_QUORUM,
+		"Minimum number of nodes that must store the data for a put to succeed")
+	encodingName := flag.String("encoding", codec.TLV.Name(),
+		"Encoding of the messages this node sends to other nodes (tlv or cbor)")
+	timeout := flag.Duration("timeout", kademlia.DEFAULT_REQUEST_TIMEOUT*time.Millisecond,
+		"Longest time a command or HTTP request may spend on lookups and RPCs")
+	logLevels := flag.String("log", logging.DEFAULT_LEVEL.String(),
+		"Log levels, as a default level and levels of subsystems (e.g. info,network=debug,http=warn)")
+	logFile := flag.String("log-file", "", "File the log is appended to instead of stderr")
+	scriptName := flag.String("script", "",
+		"File of commands that are run one per line instead of the interactive command line (- for stdin, the default if it is piped)")
+	stopOnError := flag.Bool("stop-on-error", false, "Stop running the script at the first command that fails")
+	controlSocket := flag.String("control-socket", DEFAULT_CONTROL_SOCKET,
+		"Unix domain socket the node takes commands on from d7024e ctl (empty = none)")
+	identityFile := flag.String("identity-file", DEFAULT_IDENTITY_FILE,
+		"File the key pair that signs the records of this node is kept in, created if it doesn't exist (empty = a new key pair on every start)")
+	flag.Usage = func() {
+		os.Stderr.WriteString("Usage: d7024e [flags] [serve]   (a script exits when it is done, unless it is served)\n" +
+			"       d7024e put [-json] [-node url] <file>\n" +
+			"       d7024e get [-json] [-node url] [-o file] <hash>\n" +
+			"       d7024e ctl [-json] [-socket path] [command]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	command := flag.Arg(0)
+	switch command {
+	case "put", "get":
+		os.Exit(runClient(command, flag.Args()[1:], os.Stdin, os.Stdout, os.Stderr))
+	case "ctl":
+		os.Exit(runControlClient(flag.Args()[1:], os.Stdin, os.Stdout, os.Stderr))
+	case "", "serve":
+		// Run a node, with the interactive command line unless it is served
+	default:
+		os.Stderr.WriteString("Oops: unknown command " + command + "\n")
+		flag.Usage()
+		os.Exit(EXIT_USAGE)
+	}
+
+	if err := logging.Configure(*logLevels); err != nil {
+		os.Stderr.WriteString("Oops: " + err.Error() + "\n")
+		os.Exit(1)
+	}
+	if *logFile != "" {
+		file, err := os.OpenFile(*logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			os.Stderr.WriteString("Oops: " + err.Error() + "\n")
+			os.Exit(1)
+		}
+		defer file.Close()
+		logging.SetOutput(file)
+	}
+
+	// Commands are read from a script instead of the interactive command line if stdin isn't a terminal
+	if info, err := os.Stdin.Stat(); *scriptName == "" && command == "" && err == nil && info.Mode()&os.ModeCharDevice == 0 {
+		*scriptName = "-"
+	}
+	script := os.Stdin
+	if *scriptName != "" && *scriptName != "-" {
+		file, err := os.Open(*scriptName)
+		if err != nil {
+			os.Stderr.WriteString("Oops: " + err.Error() + "\n")
+			os.Exit(1)
+		}
+		defer file.Close()
+		script = file
+	}
+
+	encoding, err := codec.ByName(*encodingName)
+	if err != nil {
+		os.Stderr.WriteString("Oops: " + err.Error() + "\n")
+		os.Exit(1)
+	}
+
+	addrs,err := net.InterfaceAddrs()
+	if err != nil {
+		os.Stderr.WriteString("Oops: " + err.Error() + "\n")
+		os.Exit(1)
+	}
+	var IP net.IP
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
+			if ipnet.IP.To4() != nil {
+				IP = ipnet.IP
+			}
+		}
+	}
+	config := kademlia.DefaultConfig(IP)
+	config.MaxStorageBytes = *maxBytes
+	config.MaxStorageItems = *maxItems
+	config.WriteQuorum = *writeQuorum
+	config.Encoding = encoding
+	config.RequestTimeout = *timeout
+	config.IdentityFile = *identityFile
+	network, err := kademlia.New(config)
+	if err != nil {
+		os.Stderr.WriteString("Oops: " + err.Error() + "\n")
+		os.Exit(1)
+	}
+	cliLog.Info("Started node", "node", network.ID(), "ip", IP)
+	if err := network.Start(); err != nil {
+		os.Stderr.WriteString("Oops: " + err.Error() + "\n")
+		os.Exit(1)
+	}
+	if err := network.Serve(HTTP_ADDRESS, httpRouter(network)); err != nil {
+		os.Stderr.WriteString("Oops: " + err.Error() + "\n")
+		os.Exit(1)
+	}
+	if *controlSocket != "" {
+		if err := listenControl(*controlSocket, network); err != nil {
+			network.Stop()
+			os.Stderr.WriteString("Oops: " + err.Error() + "\n")
+			os.Exit(1)
+		}
+	}
+	// Every way out stops the node first, so its listeners are closed and the control socket file is removed
+	stopNode = func() { network.Stop() }
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		if network.Stop() != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}()
+	go func() {
+		// The node stops by itself if one of its workers failed
+		if err := network.Wait(); err != nil {
+			os.Stderr.WriteString("Oops: " + err.Error() + "\n")
+			os.Exit(1)
+		}
+	}()
+
+	// Brute force method for joining a network automatically
+	joinCtx, cancelJoin := context.WithTimeout(context.Background(), 60*time.Second)
+	var join_IP net.IP
+	join_IP = IP
+	join_IP[15] = IP[15]+1
+	join_ID := routing.NewKademliaIDFromIP(&join_IP)
+	for joinCtx.Err() == nil {
+		if network.JoinContext(joinCtx, join_ID,join_IP.To4().String()) == nil {
+			break
+		}
+	}
+	cancelJoin()
+	if *scriptName != "" {
+		failed, err := runBatch(script, os.Stdout, network, *stopOnError)
+		if err != nil {
+			network.Stop()
+			os.Stderr.WriteString("Oops: " + err.Error() + "\n")
+			os.Exit(1)
+		}
+		cliLog.Info("Ran script", "script", *scriptName, "failed", failed)
+		if command != "serve" {
+			network.Stop()
+			if failed > 0 {
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+	}
+	if command == "serve" {
+		select {} // Until the node is stopped by a signal or one of its workers fails
+	}
+	for {
+		fmt.Printf("\n Enter a command: ")
+		rawInput, _ := bufio.NewReader(os.Stdin).ReadString('\n') // Takes rawinput from console.
+		result := parseInput(rawInput, network)
+		fmt.Println("Returned output:\n" + result.Output)
+	}
+}
+// commandResult is what a command outputs, and whether it succeeded
+type commandResult struct {
+	Output string
+	OK     bool
+}
+
+// okResult is the result of a command that succeeded
+func okResult(output string) commandResult {
+	return commandResult{output, true}
+}
+
+// errorResult is the result of a command that failed, output says why
+func errorResult(output string) commandResult {
+	return commandResult{output, false}
+}
+
+// Parses the input and sends you to either the single/dual input handler.
+func parseInput(input string, net *kademlia.Network) commandResult {
+	var command string
+	var value string
+
+	stringinput := strings.Fields(input) //Splits the text into an array with each entry being a word
+
+	if len(stringinput) == 0{
+		return errorResult("Blank input. Try again.\n")
+	}
+	// Introspection commands take any number of arguments (see inspect.go)
+	if result, ok := handleInspectInput(strings.ToLower(stringinput[0]), stringinput[1:], net); ok {
+		return result
+	}
+	if result, ok := handleLineInput(strings.ToLower(stringinput[0]), input, net); ok {
+		return result
+	}
+	// Single Input
+	if len(stringinput) > 0 {
+		command = stringinput[0]
+		command = strings.ToLower(strings.Trim(command, " \r\n")) //Removes hidden \n etc, which makes string comparision impossible.
+	}
+	// Dual input
+	if len(stringinput) > 1 { // Checks if you have 1 or 2 Commands and then runs the correct function accordingly.
+		value = stringinput[1]
+		value = strings.Trim(value, " \r\n") // Will not make input lowercase (untested)
+		return handleDualInput(command, value, net)
+	} else {
+		return handleSingleInput(command, 0)
+	}
+}
+// Switch for all single input functions
+func handleSingleInput(command string, testing int) commandResult {
+	switch command {
+	case "exit":
+		return okResult(exit(testing))
+	case "help":
+		return okResult(help())
+	default:
+		return errorResult("INVALID COMMAND, TYPE HELP")
+	}
+}
+// Switch for all dual input functions
+func handleDualInput(command string, value string, network *kademlia.Network) commandResult {
+	switch command {
+	case "join":
+		IP := net.ParseIP(value)
+		if IP == nil {
+			return errorResult("Invalid IP address format")
+		}
+		IP = IP[12:]
+		ID := routing.NewKademliaIDFromIP(&IP)
+		ctx, cancel := network.RequestContext(context.Background())
+		defer cancel()
+		err := network.JoinContext(ctx, ID, value)
+		if err == nil {
+			return okResult("")
+		} else {
+			return errorResult(err.Error())
+		}
+	case "get":
+		if _, invalid := parseHash(value); invalid != "" {
+			return errorResult(invalid)
+		}
+			outputNodeID, outputContent := get(value, network)
+		outputString := ("NodeID: " + outputNodeID + "  Content: " + outputContent.Output)
+		return commandResult{outputString, outputContent.OK}
+	case "forget":
+		hash, invalid := parseHash(value)
+		if invalid != "" {
+			return errorResult(invalid)
+		}
+		network.Forget(hash)
+		return okResult("Forgot data with hash: " + value)
+	case "delete":
+		hash, invalid := parseHash(value)
+		if invalid != "" {
+			return errorResult(invalid)
+		}
+		ctx, cancel := network.RequestContext(context.Background())
+		defer cancel()
+		deleted, err := network.DeleteContext(ctx, hash)
+		if deleted == 0 && err != nil {
+			return errorResult("Could not delete data with hash " + value + ": " + err.Error())
+		} else if deleted == 0 {
+			return errorResult("Could not delete data with hash " + value + ", no node that stores it accepted the request")
+		}
+		return okResult("Deleted data with hash " + value + " from " + strconv.Itoa(deleted) + " nodes")
+	case "resolve":
+		if _, invalid := parseHash(value); invalid != "" {
+			return errorResult(invalid)
+		}
+		return resolve(value, network)
+	default:
+		return errorResult("INVALID COMMAND, TYPE HELP")
+	}
+}
+
+// Switch for the commands whose last argument is the rest of the line, spaces included
+func handleLineInput(command string, input string, network *kademlia.Network) (commandResult, bool) {
+	switch command {
+	case "put":
+		content := restOfLine(input, 1)
+		if content == "" {
+			return errorResult("Put takes the contents of the object"), true
+		}
+		return put(content, network), true
+	case "putfile":
+		path := restOfLine(input, 1)
+		if path == "" {
+			return errorResult("Putfile takes the path of a file"), true
+		}
+		return putFile(path, network), true
+	case "publish":
+		content := restOfLine(input, 1)
+		if content == "" {
+			return errorResult("Publish takes the new contents of the record"), true
+		}
+		return publish(content, network), true
+	case "getfile":
+		path := restOfLine(input, 2)
+		if path == "" {
+			return errorResult("Getfile takes a hash and the path of a file"), true
+		}
+		return getFile(strings.Fields(input)[1], path, network), true
+	default:
+		return commandResult{}, false
+	}
+}
+
+// Returns what is left of a line of input after skipping some words, without the line ending
+func restOfLine(input string, skip int) string {
+	line := strings.TrimRight(input, "\r\n")
+	for i := 0; i < skip; i++ {
+		line = strings.TrimLeft(line, " \t")
+		end := strings.IndexAny(line, " \t")
+		if end < 0 {
+			return ""
+		}
+		line = line[end:]
+	}
+	return strings.TrimLeft(line, " \t")
+}
+
+// Parses a hash given by the user. Returns a message describing what is wrong with it if it is invalid
+func parseHash(value string) (*routing.KademliaID, string) {
+	if len(value) != 40 {
+		return nil, "Invalid hash length"
+	}
+	hash, err := routing.ParseKademliaID(value)
+	if err != nil {
+		return nil, "Invalid hash format"
+	}
+	return hash, ""
+}
+
+// Upload data of file downloaded. Check if it can be uploaded. If so, output the objects hash
+// Reports a failure if fewer nodes than the write quorum stored the data
+func put(content string, net *kademlia.Network) commandResult {
+	return putData([]byte(content), net)
+}
+
+// Upload the contents of a local file, like put
+func putFile(path string, net *kademlia.Network) commandResult {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errorResult("Could not read " + path + ": " + err.Error())
+	}
+	return putData(data, net)
+}
+
+func putData(data []byte, net *kademlia.Network) commandResult {
+	ctx, cancel := net.RequestContext(context.Background())
+	defer cancel()
+	hashedFileString, err := net.Put(ctx, data)
+	if err != nil {
+		return errorResult("Failed to store " + hashedFileString.String() + ": " + err.Error())
+	}
+	return okResult(hashedFileString.String())
+}
+
+// Take hash value as output. Check if that exists in kademlia and download
+// if so, output the contents of the objects and the node it was retrieved from.
+func get(hashValue string, net *kademlia.Network) (string, commandResult) {
+	hash, invalid := parseHash(hashValue)
+	if invalid != "" {
+		return "[NULL]", errorResult(invalid)
+	}
+	ctx, cancel := net.RequestContext(context.Background())
+	defer cancel()
+	data, nodes, err := net.DataLookupContext(ctx, hash)
+	if data == nil && err != nil {
+		return "[NULL]", errorResult("Lookup stopped: " + err.Error())
+	}
+
+	// TODO What ID should this be?
+	if data != nil {
+		return nodes[0].ID.String(),okResult(string(data))
+	} else if len(nodes) > 0{
+		return nodes[0].ID.String(), errorResult("Hashvalue Does Not Exist In The Network")
+	} else {
+		return "[NULL]", errorResult("Could not find node or data in the network")
+	}
+}
+
+// Take a hash value and the path of a local file, and download the object into the file
+func getFile(hashValue string, path string, net *kademlia.Network) commandResult {
+	hash, invalid := parseHash(hashValue)
+	if invalid != "" {
+		return errorResult(invalid)
+	}
+	ctx, cancel := net.RequestContext(context.Background())
+	defer cancel()
+	data, err := net.Get(ctx, hash)
+	if err == kademlia.ErrNoData {
+		return errorResult("Hashvalue Does Not Exist In The Network")
+	} else if err != nil {
+		return errorResult("Lookup stopped: " + err.Error())
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return errorResult("Could not write " + path + ": " + err.Error())
+	}
+	return okResult("Wrote " + strconv.Itoa(len(data)) + " bytes to " + path)
+}
+
+// Publish a new version of the mutable record of this node. Outputs the key that the record can be resolved with,
+// which stays the same for every version that this node publishes.
+func publish(content string, net *kademlia.Network) commandResult {
+	ctx, cancel := net.RequestContext(context.Background())
+	defer cancel()
+	key, _, replicas, err := net.PublishContext(ctx, []byte(content))
+	if err != nil && replicas < net.WriteQuorum() {
+		return errorResult("Failed to publish: " + err.Error())
+	}
+	if replicas < net.WriteQuorum() {
+		return errorResult("Failed to publish " + key.String() + ": stored on " + strconv.Itoa(replicas) +
+			" nodes, write quorum is " + strconv.Itoa(net.WriteQuorum()))
+	}
+	return okResult(key.String())
+}
+
+// Take the key of a mutable record, and output the latest version of it that could be found in the network.
+func resolve(key string, net *kademlia.Network) commandResult {
+	hash, invalid := parseHash(key)
+	if invalid != "" {
+		return errorResult(invalid)
+	}
+	ctx, cancel := net.RequestContext(context.Background())
+	defer cancel()
+	record, _, err := net.ResolveContext(ctx, hash)
+	if record == nil && err != nil {
+		return errorResult("Lookup stopped: " + err.Error())
+	}
+	if record == nil {
+		return errorResult("Record Does Not Exist In The Network")
+	}
+	return okResult("Sequence: " + strconv.FormatUint(record.Sequence, 10) + "  Content: " + string(record.Value))
+}
+
+// Terminate node.
+func exit(test int) string {
+	if test != 0 {
+		return "Exit (Test)"
+	}
+	stopNode()
+	os.Exit(1)
+	return "Exit (Will not be reached)"
+}
+
+// Prints every command possible (return value due to testability)
+func help() string {
+	return "Put - Takes the rest of the line, the contents of the object you are uploading, and outputs the hash of the object, if it could be uploaded successfully." + "\n" +
+		    "Get - Takes a hash as its only argument, and outputs the contents of the object and the node it was retrieved from, if it could be downloaded successfully. " + "\n" +
+			"Putfile - Takes the path of a local file, uploads its contents, and outputs the hash of the object, like Put." + "\n" +
+			"Getfile - Takes a hash and the path of a local file, and downloads the object into the file." + "\n" +
+			"Forget - Takes the hash of the object that is no longer to be refreshed"     + "\n" +
+			"Delete - Takes the hash of an object that this node has uploaded, and removes it from all nodes that store it" + "\n" +
+			"Publish - Takes the rest of the line, the new contents of the mutable record of this node, and outputs the key of the record." + "\n" +
+			"Resolve - Takes the key of a mutable record as its only argument, and outputs the latest contents of the record." + "\n" +
+			"Id - Outputs the ID and address of this node, and the size of its routing table and storage." + "\n" +
+			"Table - Outputs the contacts in each bucket of the routing table, and when they were last seen." + "\n" +
+			"Closest - Takes an ID and optionally a number of contacts (20 by default), and outputs the closest contacts to the ID in the routing table." + "\n" +
+			"Lookup - Takes an ID, looks up the closest nodes to it in the network, and outputs every round of the lookup." + "\n" +
+			"Trace - Takes a hash, looks up the object in the network, and outputs every round of the lookup and where the object was found." + "\n" +
+			"Stored - Outputs the objects stored on this node, with their size and remaining TTL." + "\n" +
+			"Remembered - Outputs the hashes of the objects this node keeps refreshing, and the nodes they are refreshed on." + "\n" +
+			"Exit -Terminates the node. " + "\n"
+}
\ No newline at end of file
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"d7024e/kademlia"
+	"d7024e/routing"
+	"d7024e/transport"
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newUDPNetwork creates a node that talks UDP but isn't connected to any other node
+func newUDPNetwork(ip net.IP) *kademlia.Network {
+	network := kademlia.NewNetwork(&ip, transport.NewUDPTransport(kademlia.KAD_PORT, kademlia.TIMEOUT*time.Millisecond), nil)
+	return &network
+}
+
+// newMemoryNetwork creates a node on an in-memory network, with the same limits as UDP and TCP
+func newMemoryNetwork(fake *transport.MemoryNetwork, ip *net.IP) *kademlia.Network {
+	config := kademlia.DefaultConfig(*ip)
+	config.Transport = fake.NewTransport(ip.String(), kademlia.KAD_PORT, kademlia.MAX_PACKET_SIZE,
+		kademlia.TIMEOUT*time.Millisecond)
+	config.Streams = fake.NewTransport(ip.String(), kademlia.STREAM_PORT, transport.MAX_STREAM_MESSAGE_SIZE,
+		kademlia.STREAM_TIMEOUT*time.Millisecond)
+	network, _ := kademlia.New(config)
+	return network
+}
+
+func TestExit(t *testing.T) {
+	// Test Exit
+	output1 := exit(1)
+	groundtruth1 := "Exit (Test)"
+	if output1 != groundtruth1 {
+		t.Errorf("Answer was incorrect, got: %s, want: %s.", output1, groundtruth1)
+	} else {
+		fmt.Println("EXIT - Test Exit = Passed") // -v must be added to go test for prints to appear.
+	}
+}
+func TestHelp(t *testing.T) {
+	// Functionality Test
+	output1 := help()
+	groundtruth1 := "Put - Takes the rest of the line, the contents of the object you are uploading, and outputs the hash of the object, if it could be uploaded successfully." + "\n" +
+		"Get - Takes a hash as its only argument, and outputs the contents of the object and the node it was retrieved from, if it could be downloaded successfully. " + "\n" +
+		"Putfile - Takes the path of a local file, uploads its contents, and outputs the hash of the object, like Put." + "\n" +
+		"Getfile - Takes a hash and the path of a local file, and downloads the object into the file." + "\n" +
+		"Forget - Takes the hash of the object that is no longer to be refreshed"     + "\n" +
+		"Delete - Takes the hash of an object that this node has uploaded, and removes it from all nodes that store it" + "\n" +
+		"Publish - Takes the rest of the line, the new contents of the mutable record of this node, and outputs the key of the record." + "\n" +
+		"Resolve - Takes the key of a mutable record as its only argument, and outputs the latest contents of the record." + "\n" +
+		"Id - Outputs the ID and address of this node, and the size of its routing table and storage." + "\n" +
+		"Table - Outputs the contacts in each bucket of the routing table, and when they were last seen." + "\n" +
+		"Closest - Takes an ID and optionally a number of contacts (20 by default), and outputs the closest contacts to the ID in the routing table." + "\n" +
+		"Lookup - Takes an ID, looks up the closest nodes to it in the network, and outputs every round of the lookup." + "\n" +
+		"Trace - Takes a hash, looks up the object in the network, and outputs every round of the lookup and where the object was found." + "\n" +
+		"Stored - Outputs the objects stored on this node, with their size and remaining TTL." + "\n" +
+		"Remembered - Outputs the hashes of the objects this node keeps refreshing, and the nodes they are refreshed on." + "\n" +
+		"Exit -Terminates the node. " + "\n"
+	if output1 != groundtruth1 {
+		t.Errorf("Answer was incorrect, got: %s, want: %s.", output1, groundtruth1)
+	} else {
+		fmt.Println("Help - Functionality Test = Passed") // -v must be added to go test for prints to appear.
+	}
+}
+func TestHandleSingleInput(t *testing.T) {
+	// Test Help
+	output1 := handleSingleInput("help", 1).Output
+	groundtruth1 := "Put - Takes the rest of the line, the contents of the object you are uploading, and outputs the hash of the object, if it could be uploaded successfully." + "\n" +
+		"Get - Takes a hash as its only argument, and outputs the contents of the object and the node it was retrieved from, if it could be downloaded successfully. " + "\n" +
+		"Putfile - Takes the path of a local file, uploads its contents, and outputs the hash of the object, like Put." + "\n" +
+		"Getfile - Takes a hash and the path of a local file, and downloads the object into the file." + "\n" +
+		"Forget - Takes the hash of the object that is no longer to be refreshed"     + "\n" +
+		"Delete - Takes the hash of an object that this node has uploaded, and removes it from all nodes that store it" + "\n" +
+		"Publish - Takes the rest of the line, the new contents of the mutable record of this node, and outputs the key of the record." + "\n" +
+		"Resolve - Takes the key of a mutable record as its only argument, and outputs the latest contents of the record." + "\n" +
+		"Id - Outputs the ID and address of this node, and the size of its routing table and storage." + "\n" +
+		"Table - Outputs the contacts in each bucket of the routing table, and when they were last seen." + "\n" +
+		"Closest - Takes an ID and optionally a number of contacts (20 by default), and outputs the closest contacts to the ID in the routing table." + "\n" +
+		"Lookup - Takes an ID, looks up the closest nodes to it in the network, and outputs every round of the lookup." + "\n" +
+		"Trace - Takes a hash, looks up the object in the network, and outputs every round of the lookup and where the object was found." + "\n" +
+		"Stored - Outputs the objects stored on this node, with their size and remaining TTL." + "\n" +
+		"Remembered - Outputs the hashes of the objects this node keeps refreshing, and the nodes they are refreshed on." + "\n" +
+		"Exit -Terminates the node. " + "\n"
+	if output1 != groundtruth1 {
+		t.Errorf("Answer was incorrect, got: %s, want: %s.", output1, groundtruth1)
+	} else {
+		fmt.Println("TestHandleSingleInput - Test Help = Passed") // -v must be added to go test for prints to appear.
+	}
+
+	// Test Default
+	output2 := handleSingleInput("loremipsum", 1).Output
+	groundtruth2 := "INVALID COMMAND, TYPE HELP"
+	if output2 != groundtruth2 {
+		t.Errorf("Answer was incorrect, got: %s, want: %s.", output2, groundtruth2)
+	} else {
+		fmt.Println("TestHandleSingleInput - Test Default = Passed") // -v must be added to go test for prints to appear.
+	}
+
+	// Test Exit
+	output3 := handleSingleInput("exit", 1).Output
+	groundtruth3 := "Exit (Test)"
+	if output3 != groundtruth3 {
+		t.Errorf("Answer was incorrect, got: %s, want: %s.", output3, groundtruth3)
+	} else {
+		fmt.Println("TestHandleSingleInput - Test Exit = Passed") // -v must be added to go test for prints to appear.
+	}
+
+}
+func TestParseInput(t *testing.T) {
+	// Set Up
+	addrs,_ := net.InterfaceAddrs()
+	var testIP net.IP
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
+			if ipnet.IP.To4() != nil {
+				testIP = ipnet.IP
+			}
+		}
+	}
+	net:= newUDPNetwork(testIP)
+
+	// Test no input
+	output_0 := parseInput("", nil).Output
+	groundTruth_0 := "Blank input. Try again.\n"
+	if output_0 != groundTruth_0 {
+		t.Errorf("Answer was incorrect, got: %s, want: %s.", output_0, groundTruth_0)
+	} else {
+		fmt.Println("TestParseInput - Test No Input = Passed") // -v must be added to go test for prints to appear.
+	}
+
+	// Test Single Input
+	output_1 := parseInput("help", nil).Output
+	groundTruth_1 := "Put - Takes the rest of the line, the contents of the object you are uploading, and outputs the hash of the object, if it could be uploaded successfully." + "\n" +
+		"Get - Takes a hash as its only argument, and outputs the contents of the object and the node it was retrieved from, if it could be downloaded successfully. " + "\n" +
+		"Putfile - Takes the path of a local file, uploads its contents, and outputs the hash of the object, like Put." + "\n" +
+		"Getfile - Takes a hash and the path of a local file, and downloads the object into the file." + "\n" +
+		"Forget - Takes the hash of the object that is no longer to be refreshed"     + "\n" +
+		"Delete - Takes the hash of an object that this node has uploaded, and removes it from all nodes that store it" + "\n" +
+		"Publish - Takes the rest of the line, the new contents of the mutable record of this node, and outputs the key of the record." + "\n" +
+		"Resolve - Takes the key of a mutable record as its only argument, and outputs the latest contents of the record." + "\n" +
+		"Id - Outputs the ID and address of this node, and the size of its routing table and storage." + "\n" +
+		"Table - Outputs the contacts in each bucket of the routing table, and when they were last seen." + "\n" +
+		"Closest - Takes an ID and optionally a number of contacts (20 by default), and outputs the closest contacts to the ID in the routing table." + "\n" +
+		"Lookup - Takes an ID, looks up the closest nodes to it in the network, and outputs every round of the lookup." + "\n" +
+		"Trace - Takes a hash, looks up the object in the network, and outputs every round of the lookup and where the object was found." + "\n" +
+		"Stored - Outputs the objects stored on this node, with their size and remaining TTL." + "\n" +
+		"Remembered - Outputs the hashes of the objects this node keeps refreshing, and the nodes they are refreshed on." + "\n" +
+		"Exit -Terminates the node. " + "\n"
+	if output_1 != groundTruth_1 {
+		t.Errorf("Answer was incorrect, got: %s, want: %s.", output_1, groundTruth_1)
+	} else {
+		fmt.Println("TestParseInput - Test Single Input = Passed") // -v must be added to go test for prints to appear.
+	}
+	// Test Dual Input
+	output_2 := parseInput("put test", net).Output
+	groundTruth_2 := "a94a8fe5ccb19ba61c4c0873d391e987982fbbd3"
+	if output_2 != groundTruth_2 {
+		t.Errorf("Answer was incorrect, got: %s, want: %s.", output_2, groundTruth_2)
+	} else {
+		fmt.Println("TestParseInput - Test Dual Input = Passed") // -v must be added to go test for prints to appear.
+	}
+}
+
+func TestHandleDualInput(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	// Set Up
+	addrs,_ := net.InterfaceAddrs()
+	var testIP net.IP
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
+			if ipnet.IP.To4() != nil {
+				testIP = ipnet.IP
+			}
+		}
+	}
+	network:= newUDPNetwork(testIP)
+	// Test join
+	{
+		ip1 := net.ParseIP("0.0.0.0")
+		ip2 := net.ParseIP("0.0.0.1")
+
+		net1 := newMemoryNetwork(fake, &ip1)
+		net2 := newMemoryNetwork(fake, &ip2)
+
+		net1.Start()
+		time.Sleep(50*time.Millisecond)
+
+		output := handleDualInput("join","0.0.0.0",net2).Output
+		groundTruth := ""
+		if output != groundTruth {
+			t.Errorf("Answer was incorrect, got: %s, want: %s.", output, groundTruth)
+		} else {
+			fmt.Println("TestHandleDualInput - Test join = Passed") // -v must be added to go test for prints to appear.
+		}
+
+		net1.Stop()
+	}
+	// Test join with error
+	{
+		ip2 := net.ParseIP("0.0.0.1")
+		net2 := newMemoryNetwork(fake, &ip2)
+
+		output := handleDualInput("join","0.0.0.0",net2).Output
+		groundTruth := "could not join network node"
+		if output != groundTruth {
+			t.Errorf("Answer was incorrect, got: %s, want: %s.", output, groundTruth)
+		} else {
+			fmt.Println("TestHandleDualInput - Test join fail = Passed") // -v must be added to go test for prints to appear.
+		}
+
+	}
+	// Test join with invalid IP
+	{
+		ip2 := net.ParseIP("0.0.0.1")
+		net2 := newMemoryNetwork(fake, &ip2)
+
+		output := handleDualInput("join","00000",net2).Output
+		groundTruth := "Invalid IP address format"
+		if output != groundTruth {
+			t.Errorf("Answer was incorrect, got: %s, want: %s.", output, groundTruth)
+		} else {
+			fmt.Println("TestHandleDualInput - Test join fail = Passed") // -v must be added to go test for prints to appear.
+		}
+	}
+
+	// Test Put, which takes the rest of the line instead (see handleLineInput)
+	output_1 := handleDualInput("put", "test", network).Output
+	groundTruth_1 := "INVALID COMMAND, TYPE HELP"
+	if output_1 != groundTruth_1 {
+		t.Errorf("Answer was incorrect, got: %s, want: %s.", output_1, groundTruth_1)
+	} else {
+		fmt.Println("TestHandleDualInput - Test Put = Passed") // -v must be added to go test for prints to appear.
+	}
+	// Test Default
+	output_2 := handleDualInput("lorem", "ipsum", network).Output
+	groundTruth_2 := "INVALID COMMAND, TYPE HELP"
+	if output_2 != groundTruth_2 {
+		t.Errorf("Answer was incorrect, got: %s, want: %s.", output_2, groundTruth_2)
+	} else {
+		fmt.Println("TestHandleDualInput - Test Default = Passed") // -v must be added to go test for prints to appear.
+	}
+	// Test Get
+	inputString := "test"
+	put(inputString, network)
+	output_3 := handleDualInput("get", routing.NewKademliaIDFromData(inputString).String(), network).Output
+	groundTruth_3 := "NodeID: "+ network.ID().String() +"  Content: test"
+	if output_3 != groundTruth_3 {
+		t.Errorf("Answer was incorrect, got: %s, want: %s.", output_3, groundTruth_3)
+	} else {
+		fmt.Println("TestHandleDualInput - Test Put = Passed") // -v must be added to go test for prints to appear.
+	}
+
+	// Test Get
+	{
+		inputString := "test"
+		put(inputString, network)
+		output := handleDualInput("get", "0000000000", network).Output
+		groundTruth := "Invalid hash length"
+		if output != groundTruth {
+			t.Errorf("Answer was incorrect, got: %s, want: %s.", output_3, groundTruth_3)
+		} else {
+			fmt.Println("TestHandleDualInput - Test Put = Passed") // -v must be added to go test for prints to appear.
+		}
+	}
+}
+
+
+func TestPut(t *testing.T) {
+	// Set Up
+	addrs,_ := net.InterfaceAddrs()
+	var testIP net.IP
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
+			if ipnet.IP.To4() != nil {
+				testIP = ipnet.IP
+			}
+		}
+	}
+	net:= newUDPNetwork(testIP)
+
+	// Test Good Input
+	output_1 := put("testing", net).Output
+	groundTruth_1 := "dc724af18fbdd4e59189f5fe768a5f8311527050"
+	if output_1 != groundTruth_1 {
+		t.Errorf("Answer was incorrect, got: %s, want: %s.", output_1, groundTruth_1)
+	} else {
+		fmt.Println("PUT - Good Input = Passed") // -v must be added to go test for prints to appear.
+	}
+
+	// Test Write Quorum Not Reached (there are no other nodes to store the data on)
+	net.SetWriteQuorum(2)
+	output_2 := put("quorum", net).Output
+	groundTruth_2 := "Failed to store " + routing.NewKademliaIDFromData("quorum").String() + ": stored on 1 nodes, write quorum is 2"
+	if output_2 != groundTruth_2 {
+		t.Errorf("Answer was incorrect, got: %s, want: %s.", output_2, groundTruth_2)
+	} else {
+		fmt.Println("PUT - Write Quorum Not Reached = Passed") // -v must be added to go test for prints to appear.
+	}
+}
+
+func TestPutRestOfLine(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	ip := net.ParseIP("0.0.0.0")
+	net1 := newMemoryNetwork(fake, &ip)
+
+	output := parseInput("put  hello   world \r\n", net1).Output
+	want := routing.NewKademliaIDFromData("hello   world ").String()
+	if output != want {
+		t.Errorf("parseInput() = %v, want %v", output, want)
+	}
+	if output := parseInput("put", net1).Output; output != "Put takes the contents of the object" {
+		t.Errorf("parseInput() = %v, want %v", output, "Put takes the contents of the object")
+	}
+}
+
+func TestPublishRestOfLine(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	ip := net.ParseIP("0.0.0.0")
+	net1 := newMemoryNetwork(fake, &ip)
+
+	key := parseInput("publish  hello   world \r\n", net1).Output
+	if _, invalid := parseHash(key); invalid != "" {
+		t.Fatalf("parseInput() = %v, want the key of the record", key)
+	}
+	output := parseInput("resolve "+key, net1).Output
+	if !strings.HasSuffix(output, "  Content: hello   world ") {
+		t.Errorf("parseInput() = %v, want the content %v", output, "hello   world ")
+	}
+	if output := parseInput("publish", net1).Output; output != "Publish takes the new contents of the record" {
+		t.Errorf("parseInput() = %v, want %v", output, "Publish takes the new contents of the record")
+	}
+}
+
+func TestPutFileGetFile(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	ip := net.ParseIP("0.0.0.0")
+	net1 := newMemoryNetwork(fake, &ip)
+	dir := t.TempDir()
+	data := []byte{0, 1, 2, 255, '\n', '\r', ' ', 254}
+	source := filepath.Join(dir, "source file")
+	if err := os.WriteFile(source, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	hash := parseInput("putfile "+source, net1).Output
+	if want := routing.NewKademliaIDFromData(string(data)).String(); hash != want {
+		t.Errorf("parseInput() = %v, want %v", hash, want)
+	}
+	target := filepath.Join(dir, "target file")
+	output := parseInput("getfile "+hash+" "+target, net1).Output
+	if want := "Wrote 8 bytes to " + target; output != want {
+		t.Errorf("parseInput() = %v, want %v", output, want)
+	}
+	if written, _ := os.ReadFile(target); !bytes.Equal(written, data) {
+		t.Errorf("os.ReadFile() = %v, want %v", written, data)
+	}
+
+	missing := routing.NewKademliaIDFromData("missing").String()
+	tests := map[string]string{
+		"putfile " + filepath.Join(dir, "none"): "Could not read " + filepath.Join(dir, "none") + ": ",
+		"putfile":                            "Putfile takes the path of a file",
+		"getfile " + hash:                    "Getfile takes a hash and the path of a file",
+		"getfile 1234 " + target:             "Invalid hash length",
+		"getfile " + missing + " " + target:  "Hashvalue Does Not Exist In The Network",
+	}
+	for input, want := range tests {
+		if output := parseInput(input, net1).Output; len(output) < len(want) || output[:len(want)] != want {
+			t.Errorf("parseInput() = %v, want %v", output, want)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	// Set Up
+	addrs,_ := net.InterfaceAddrs()
+	var testIP net.IP
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
+			if ipnet.IP.To4() != nil {
+				testIP = ipnet.IP
+			}
+		}
+	}
+	network:= newUDPNetwork(testIP)
+
+	// Test Find Valid Input
+	inputString := "test"
+	input_1 := put(inputString, network).Output
+	_, output_1_2 := get(input_1, network)
+	groundTruth_1 := inputString
+	if output_1_2.Output != groundTruth_1 || !output_1_2.OK {
+		t.Errorf("Answer was incorrect, got: %s, want: %s.", output_1_2.Output, groundTruth_1)
+	} else {
+		fmt.Println("GET - Find Valid Input = Passed")
+	}
+
+	// Test Find Null Input
+	inputHash := "0000000000000000000000000000000000000000"
+	_, output_2_2 := get(inputHash, network)
+	groundTruth_2 := "Could not find node or data in the network"
+	if output_2_2.Output != groundTruth_2 || output_2_2.OK {
+		t.Errorf("Answer was incorrect, got: %s, want: %s.", output_2_2.Output, groundTruth_2)
+	} else {
+		fmt.Println("GET - Find Null Input and null nodes = Passed")
+	}
+
+	// Test with nodes and null input
+	{
+		ip1 := net.ParseIP("0.0.0.0")
+		ip2 := net.ParseIP("0.0.0.1")
+
+		net1 := newMemoryNetwork(fake, &ip1)
+		net2 := newMemoryNetwork(fake, &ip2)
+
+		net1.Start()
+		time.Sleep(50*time.Millisecond)
+		net2.Join(routing.NewKademliaIDFromIP(&ip1),"0.0.0.0")
+
+		data := "Hello world!"
+		_,answer := get(routing.NewKademliaIDFromData(data).String(),net2)
+		groundTruth := "Hashvalue Does Not Exist In The Network"
+
+		if answer.Output != groundTruth || answer.OK {
+			t.Errorf("Answer was incorrect, got: %s, want: %s.", answer.Output, groundTruth)
+		} else {
+			fmt.Println("GET - Find Null Input with nodes = Passed")
+		}
+
+
+		net1.Stop()
+	}
+}
+
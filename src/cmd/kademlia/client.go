@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"d7024e/routing"
+	"encoding/json"
+	"flag"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Subcommands that talk to a node running on the same host over its HTTP API instead of running a node, so a
+// node can be scripted with e.g. docker exec. They print the result to stdout, as text or as JSON with -json,
+// errors to stderr (or as JSON to stdout), and return one of the exit codes below
+
+const (
+	EXIT_OK        = 0
+	EXIT_FAILED    = 1 // The node couldn't be reached or failed to answer
+	EXIT_USAGE     = 2 // Invalid arguments
+	EXIT_NOT_FOUND = 3 // The data does not exist in the network
+)
+
+const DEFAULT_NODE_URL = "http://localhost" + HTTP_ADDRESS
+
+// The longest time a subcommand waits for the node, which gives up on its own lookups much sooner
+const CLIENT_TIMEOUT = 60 * time.Second
+
+// A subcommand with the flags it was given
+type client struct {
+	json   bool
+	node   string
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	http   *http.Client
+}
+
+// runClient runs the put or get subcommand with its arguments, and returns the exit code
+func runClient(command string, args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	c := client{stdin: stdin, stdout: stdout, stderr: stderr, http: &http.Client{Timeout: CLIENT_TIMEOUT}}
+	flags.BoolVar(&c.json, "json", false, "Print the result as JSON")
+	flags.StringVar(&c.node, "node", DEFAULT_NODE_URL, "URL of the HTTP API of the node")
+	usage := "Usage: d7024e put [-json] [-node url] <file>   (- reads the data from stdin)\n"
+	var output string
+	if command == "get" {
+		usage = "Usage: d7024e get [-json] [-node url] [-o file] <hash>\n"
+		flags.StringVar(&output, "o", "", "File the data is written to instead of stdout")
+	}
+	flags.Usage = func() {
+		stderr.Write([]byte(usage))
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return EXIT_USAGE
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+	c.node = strings.TrimSuffix(c.node, "/")
+
+	if command == "put" {
+		return c.put(flags.Arg(0))
+	}
+	return c.get(flags.Arg(0), output)
+}
+
+// put uploads the contents of a file, or of stdin if the path is -, and prints its hash
+func (c *client) put(path string) int {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = ioutil.ReadAll(c.stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return c.fail(EXIT_FAILED, "Could not read "+path+": "+err.Error())
+	}
+	if len(data) == 0 {
+		return c.fail(EXIT_USAGE, "Nothing to put, "+path+" is empty")
+	}
+	response, err := c.http.Post(c.node+"/objects", "application/octet-stream", bytes.NewReader(data))
+	if err != nil {
+		return c.fail(EXIT_FAILED, err.Error())
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusCreated {
+		return c.fail(EXIT_FAILED, "The node answered "+response.Status)
+	}
+	var result postResponse
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return c.fail(EXIT_FAILED, "Invalid answer from the node: "+err.Error())
+	}
+	if c.json {
+		c.printJSON(map[string]interface{}{"hash": result.Hash, "replicas": result.Replicas, "size": len(data)})
+	} else {
+		c.stdout.Write([]byte(result.Hash + "\n"))
+	}
+	return EXIT_OK
+}
+
+// get downloads the data of a hash, and prints it or writes it to a file
+func (c *client) get(hashValue string, path string) int {
+	hash, err := routing.ParseKademliaID(hashValue)
+	if err != nil {
+		return c.fail(EXIT_USAGE, "Invalid hash: "+err.Error())
+	}
+	response, err := c.http.Get(c.node + URLprefix + hash.String())
+	if err != nil {
+		return c.fail(EXIT_FAILED, err.Error())
+	}
+	defer response.Body.Close()
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return c.fail(EXIT_NOT_FOUND, "Hashvalue Does Not Exist In The Network")
+	default:
+		return c.fail(EXIT_FAILED, "The node answered "+response.Status)
+	}
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return c.fail(EXIT_FAILED, err.Error())
+	}
+	if path != "" {
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return c.fail(EXIT_FAILED, "Could not write "+path+": "+err.Error())
+		}
+	}
+	if c.json {
+		result := map[string]interface{}{"hash": hash.String(), "size": len(data)}
+		if path == "" {
+			result["data"] = data // Base64, the data may be binary
+		} else {
+			result["file"] = path
+		}
+		c.printJSON(result)
+	} else if path == "" {
+		c.stdout.Write(data)
+	}
+	return EXIT_OK
+}
+
+// fail reports an error and returns its exit code
+func (c *client) fail(code int, message string) int {
+	if c.json {
+		c.printJSON(map[string]string{"error": message})
+	} else {
+		c.stderr.Write([]byte("Oops: " + message + "\n"))
+	}
+	return code
+}
+
+func (c *client) printJSON(value interface{}) {
+	jsonValue, _ := json.Marshal(value)
+	c.stdout.Write(append(jsonValue, '\n'))
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"d7024e/routing"
+	"d7024e/transport"
+	"encoding/json"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunClient(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	ip1 := net.ParseIP("0.0.0.0")
+	ip2 := net.ParseIP("0.0.0.1")
+	network := newMemoryNetwork(fake, &ip1)
+	contact := newMemoryNetwork(fake, &ip2)
+	contact.Start()
+	defer contact.Stop()
+	time.Sleep(10 * time.Millisecond)
+	network.Join(contact.ID(), ip2.String())
+	server := httptest.NewServer(httpRouter(network))
+	defer server.Close()
+
+	run := func(stdin string, args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		code := runClient(args[0], append([]string{"-node", server.URL}, args[1:]...), strings.NewReader(stdin),
+			&stdout, &stderr)
+		return code, stdout.String(), stderr.String()
+	}
+
+	data := []byte{0, 1, 2, 255, '\n'}
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source")
+	os.WriteFile(source, data, 0644)
+	hash := routing.NewKademliaIDFromData(string(data)).String()
+
+	// Put a file, and get it back
+	if code, stdout, _ := run("", "put", source); code != EXIT_OK || stdout != hash+"\n" {
+		t.Errorf("runClient() = %v, %v, want %v, %v", code, stdout, EXIT_OK, hash+"\n")
+	}
+	if code, stdout, _ := run("", "get", hash); code != EXIT_OK || stdout != string(data) {
+		t.Errorf("runClient() = %v, %v, want %v, %v", code, stdout, EXIT_OK, string(data))
+	}
+	target := filepath.Join(dir, "target")
+	if code, stdout, _ := run("", "get", "-o", target, hash); code != EXIT_OK || stdout != "" {
+		t.Errorf("runClient() = %v, %v, want %v, %v", code, stdout, EXIT_OK, "")
+	}
+	if written, _ := os.ReadFile(target); !bytes.Equal(written, data) {
+		t.Errorf("os.ReadFile() = %v, want %v", written, data)
+	}
+
+	// Put stdin, and get it back as JSON
+	stdinHash := routing.NewKademliaIDFromData("from stdin").String()
+	code, stdout, _ := run("from stdin", "put", "-json", "-")
+	if want := `{"hash":"` + stdinHash + `","replicas":2,"size":10}` + "\n"; code != EXIT_OK || stdout != want {
+		t.Errorf("runClient() = %v, %v, want %v, %v", code, stdout, EXIT_OK, want)
+	}
+	code, stdout, _ = run("", "get", "-json", stdinHash)
+	var result struct {
+		Hash string
+		Size int
+		Data []byte
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); code != EXIT_OK || err != nil || string(result.Data) != "from stdin" {
+		t.Errorf("runClient() = %v, %v, want %v, %v", code, stdout, EXIT_OK, "from stdin")
+	}
+
+	// Failures
+	missing := routing.NewKademliaIDFromData("missing").String()
+	tests := []struct {
+		name string
+		args []string
+		code int
+		want string // Start of stderr
+	}{
+		{"Missing data", []string{"get", missing}, EXIT_NOT_FOUND, "Oops: Hashvalue Does Not Exist In The Network"},
+		{"Invalid hash", []string{"get", "1234"}, EXIT_USAGE, "Oops: Invalid hash"},
+		{"Missing file", []string{"put", filepath.Join(dir, "none")}, EXIT_FAILED, "Oops: Could not read "},
+		{"Empty stdin", []string{"put", "-"}, EXIT_USAGE, "Oops: Nothing to put"},
+		{"No arguments", []string{"get"}, EXIT_USAGE, "Usage: d7024e get "},
+		{"Unknown flag", []string{"put", "-unknown", source}, EXIT_USAGE, "flag provided but not defined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, _, stderr := run("", tt.args...); code != tt.code || !strings.HasPrefix(stderr, tt.want) {
+				t.Errorf("runClient() = %v, %v, want %v, %v", code, stderr, tt.code, tt.want)
+			}
+		})
+	}
+	if code, stdout, _ := run("", "get", "-json", missing); code != EXIT_NOT_FOUND ||
+		stdout != `{"error":"Hashvalue Does Not Exist In The Network"}`+"\n" {
+		t.Errorf("runClient() = %v, %v, want %v", code, stdout, EXIT_NOT_FOUND)
+	}
+
+	// No node is running
+	server.Close()
+	if code, _, _ := run("", "get", hash); code != EXIT_FAILED {
+		t.Errorf("runClient() = %v, want %v", code, EXIT_FAILED)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"d7024e/kademlia"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+// The control socket of a node: a Unix domain socket that takes the commands of the command line, so they can be run
+// with the ctl subcommand from e.g. docker exec without attaching to the stdin of the node. A connection is a script
+// (see batch.go), the node answers every command with a line of JSON and closes the connection when the client
+// has sent all of its commands
+
+const DEFAULT_CONTROL_SOCKET = "/tmp/d7024e.sock"
+
+var ErrControlSocketInUse = errors.New("the control socket is used by a running node")
+
+// listenControl listens on the control socket at path as a worker of a started node, and runs the commands of every
+// connection. When the node is stopped the listener and the open connections are closed, and the socket file is
+// removed. A socket file left behind by a node that didn't stop cleanly is replaced
+func listenControl(path string, network *kademlia.Network) error {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return ErrControlSocketInUse
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(true)
+	err = network.ServeListener(listener, func(listener net.Listener) error {
+		return serveControl(listener, network)
+	})
+	if err != nil {
+		listener.Close()
+	}
+	return err
+}
+
+// serveControl accepts connections to the control socket until the listener is closed, and then closes the
+// connections that are still open
+func serveControl(listener net.Listener, network *kademlia.Network) error {
+	var mutex sync.Mutex
+	conns := make(map[net.Conn]bool)
+	defer func() {
+		mutex.Lock()
+		defer mutex.Unlock()
+		for conn := range conns {
+			conn.Close()
+		}
+	}()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		mutex.Lock()
+		conns[conn] = true
+		mutex.Unlock()
+		go func() {
+			failed, err := runBatch(conn, conn, network, false)
+			cliLog.Debug("Ran control commands", "failed", failed, "error", err)
+			mutex.Lock()
+			delete(conns, conn)
+			mutex.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+// runControlClient runs the ctl subcommand: sends the command given as arguments, or the commands read from stdin,
+// to the control socket of a running node and prints the outputs. Returns the exit code
+func runControlClient(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("ctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	socket := flags.String("socket", DEFAULT_CONTROL_SOCKET, "Path of the control socket of the node")
+	printJSON := flags.Bool("json", false, "Print the result of every command as a line of JSON")
+	flags.Usage = func() {
+		stderr.Write([]byte("Usage: d7024e ctl [-json] [-socket path] [command]   (commands are read from stdin if none is given)\n"))
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return EXIT_USAGE
+	}
+	commands := stdin
+	if flags.NArg() > 0 {
+		commands = strings.NewReader(strings.Join(flags.Args(), " ") + "\n")
+	}
+
+	conn, err := net.Dial("unix", *socket)
+	if err != nil {
+		stderr.Write([]byte("Oops: " + err.Error() + "\n"))
+		return EXIT_FAILED
+	}
+	defer conn.Close()
+	go func() {
+		io.Copy(conn, commands)
+		conn.(*net.UnixConn).CloseWrite() // The node closes the connection once it has answered everything
+	}()
+
+	code := EXIT_OK
+	decoder := json.NewDecoder(conn)
+	encoder := json.NewEncoder(stdout)
+	encoder.SetEscapeHTML(false)
+	for {
+		var result batchResult
+		if err := decoder.Decode(&result); err == io.EOF {
+			return code
+		} else if err != nil {
+			stderr.Write([]byte("Oops: " + err.Error() + "\n"))
+			return EXIT_FAILED
+		}
+		if !result.OK {
+			code = EXIT_FAILED
+		}
+		switch {
+		case *printJSON:
+			encoder.Encode(result)
+		case result.OK:
+			stdout.Write([]byte(result.Output + "\n"))
+		default:
+			stderr.Write([]byte(result.Output + "\n"))
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"d7024e/kademlia"
+	"d7024e/routing"
+	"d7024e/transport"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestControlSocket(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	ip := net.ParseIP("0.0.0.0")
+	network := newMemoryNetwork(fake, &ip)
+	socket := filepath.Join(t.TempDir(), "control.sock")
+	if err := listenControl(socket, network); err != kademlia.ErrNodeNotStarted {
+		t.Errorf("listenControl() error = %v, want %v", err, kademlia.ErrNodeNotStarted)
+	}
+	network.Start()
+	defer network.Stop()
+	if err := listenControl(socket, network); err != nil {
+		t.Fatalf("listenControl() error = %v", err)
+	}
+
+	// A second node can't take over the socket of a running one
+	if err := listenControl(socket, network); err != ErrControlSocketInUse {
+		t.Errorf("listenControl() error = %v, want %v", err, ErrControlSocketInUse)
+	}
+
+	run := func(stdin string, args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		code := runControlClient(append([]string{"-socket", socket}, args...), strings.NewReader(stdin), &stdout, &stderr)
+		return code, stdout.String(), stderr.String()
+	}
+	hash := routing.NewKademliaIDFromData("hello world").String()
+
+	// A command given as arguments
+	if code, stdout, _ := run("", "put", "hello", "world"); code != EXIT_OK || stdout != hash+"\n" {
+		t.Errorf("runControlClient() = %v, %v, want %v, %v", code, stdout, EXIT_OK, hash+"\n")
+	}
+	if code, stdout, _ := run("", "id"); code != EXIT_OK || !strings.HasPrefix(stdout, "ID: "+network.ID().String()) {
+		t.Errorf("runControlClient() = %v, %v, want %v, %v", code, stdout, EXIT_OK, "ID: "+network.ID().String())
+	}
+
+	// Commands read from stdin, one of them fails
+	code, stdout, stderr := run("# Comment\nget " + hash + "\nnonsense\nstored\n")
+	if want := "NodeID: " + network.ID().String() + "  Content: hello world\n" + hash + "  11 bytes  TTL "; code != EXIT_FAILED ||
+		!strings.HasPrefix(stdout, want) || stderr != "INVALID COMMAND, TYPE HELP\n" {
+		t.Errorf("runControlClient() = %v, %v, %v, want %v, %v", code, stdout, stderr, EXIT_FAILED, want)
+	}
+
+	// The results as JSON
+	code, stdout, _ = run("", "-json", "get", hash)
+	want := `{"line":1,"command":"get ` + hash + `","ok":true,"output":"NodeID: ` + network.ID().String() +
+		`  Content: hello world"}` + "\n"
+	if code != EXIT_OK || stdout != want {
+		t.Errorf("runControlClient() = %v, %v, want %v, %v", code, stdout, EXIT_OK, want)
+	}
+
+	// Stopping the node closes the socket and removes its file
+	network.Stop()
+	if code, _, stderr := run("", "id"); code != EXIT_FAILED || !strings.HasPrefix(stderr, "Oops: ") {
+		t.Errorf("runControlClient() = %v, %v, want %v", code, stderr, EXIT_FAILED)
+	}
+	if _, err := os.Stat(socket); !os.IsNotExist(err) {
+		t.Errorf("os.Stat() error = %v, want the socket file to be removed", err)
+	}
+
+	// Another node can listen on the socket once the node stopped
+	ip2 := net.ParseIP("0.0.0.1")
+	network2 := newMemoryNetwork(fake, &ip2)
+	network2.Start()
+	defer network2.Stop()
+	if err := listenControl(socket, network2); err != nil {
+		t.Errorf("listenControl() error = %v", err)
+	}
+}
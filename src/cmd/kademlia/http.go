@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"d7024e/kademlia"
+	"d7024e/logging"
+	"d7024e/routing"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+// Printas ut i http://localhost:3000/
+
+const HTTP_ADDRESS = ":3000" // Address the HTTP API of the node listens on
+
+var httpLog = logging.For("http")
+
+const URLprefix = "/objects/"
+const RecordURLprefix = "/records/"
+
+// The HTTP API of a node
+type httpAPI struct {
+	network *kademlia.Network
+}
+
+// Allows you to either POST (put) data, to GET (get) data, to check that data exists with HEAD and to DELETE data.
+// POST stores the body with its Content-Type, GET answers the data with the content type it was stored with
+// (see httpWriteObject)
+func (api *httpAPI) HTTPhandler(w http.ResponseWriter, r *http.Request){
+	network := api.network
+	switch r.Method {
+	case "POST":
+		body, error := ioutil.ReadAll(r.Body) // Read Request
+		defer r.Body.Close() // Always CLOSE.
+		// Check for errors or if body is empty.
+		if error != nil || len(body) == 0 {
+			http.Error(w, "ERROR", http.StatusBadRequest)
+			httpLog.Warn("Invalid request body", "method", r.Method, "path", r.URL.Path)
+			return
+		}
+		// Same as in Cli.go Store
+		ctx, cancel := network.RequestContext(r.Context())
+		defer cancel()
+		object := &kademlia.Object{Data: body, ContentType: r.Header.Get("Content-Type")}
+		hash, replicas, err := network.PutObject(ctx, object)
+		if err == kademlia.ErrContentTypeTooLong {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			httpLog.Warn("Invalid content type", "method", r.Method, "path", r.URL.Path)
+			return
+		}
+		if _, ok := err.(*kademlia.QuorumError); ok {
+			// Not enough nodes stored the data
+			http.Error(w, "ERROR", http.StatusServiceUnavailable)
+			httpLog.Warn("Write quorum not reached", "method", r.Method, "path", r.URL.Path, "error", err)
+			return
+		}
+		if err != nil {
+			httpContextError(w, r, err)
+			return
+		}
+
+		jsonValue, _ := json.Marshal(postResponse{hash.String(), replicas})
+		w.Header().Set("Location", URLprefix+hash.String())
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(http.StatusCreated)	// Status 201 as detailed
+		w.Write(jsonValue)
+		httpLog.Info("Data written", "method", r.Method, "path", r.URL.Path, "hash", hash, "replicas", replicas)
+	case "GET", "HEAD":
+		URLcomponents := strings.Split(r.URL.Path, "/")	// [ "", "objects", "hash" ]
+		// Same as in Cli.go Get
+		hash, err := routing.ParseKademliaID(URLcomponents[2])
+		if err != nil {
+			http.Error(w, "ERROR", http.StatusBadRequest)
+			httpLog.Warn("Invalid hash", "method", r.Method, "path", r.URL.Path)
+			return
+		}
+		ctx, cancel := network.RequestContext(r.Context())
+		defer cancel()
+		if r.Method == "GET" && r.URL.Query().Get("trace") == "1" {
+			httpTracedGet(ctx, w, r, network, hash)
+			return
+		}
+		object, nodes, err := network.ObjectLookup(ctx, hash)
+		if object == nil && err != nil {
+			httpContextError(w, r, err)
+			return
+		}
+		if object != nil {
+			httpWriteObject(w, r, hash, object, nodes[0])
+		} else if len(nodes) > 0 {
+			http.Error(w, "ERROR", http.StatusNotFound)
+			httpLog.Info("Data not found", "method", r.Method, "path", r.URL.Path)
+		} else {
+			// There is nobody to ask
+			http.Error(w, "ERROR", http.StatusServiceUnavailable)
+			httpLog.Warn("Data not found, no nodes in the network", "method", r.Method, "path", r.URL.Path)
+		}
+	case "DELETE":
+		URLcomponents := strings.Split(r.URL.Path, "/")	// [ "", "objects", "hash" ]
+		hashValue := URLcomponents[2]
+		hash, err := routing.ParseKademliaID(hashValue)
+		if err != nil {
+			http.Error(w, "ERROR", http.StatusBadRequest)
+			httpLog.Warn("Invalid hash", "method", r.Method, "path", r.URL.Path)
+			return
+		}
+		// Same as in Cli.go Delete
+		ctx, cancel := network.RequestContext(r.Context())
+		defer cancel()
+		deleted, err := network.DeleteContext(ctx, hash)
+		if deleted == 0 && err != nil {
+			httpContextError(w, r, err)
+			return
+		}
+		if deleted == 0 {
+			http.Error(w, "ERROR", http.StatusNotFound)
+			httpLog.Info("No node deleted the data", "method", r.Method, "path", r.URL.Path)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		httpLog.Info("Data deleted", "method", r.Method, "path", r.URL.Path, "deleted", deleted)
+	default:
+		http.Error(w, "Wrong. Use POST, GET, HEAD or DELETE", http.StatusMethodNotAllowed)
+	}
+}
+
+type postResponse struct {
+	Hash     string `json:"hash"`
+	Replicas int    `json:"replicas"` // Nodes that stored the data, including this one
+}
+
+type getResponse struct {
+	Hash        string `json:"hash"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int    `json:"size"`
+	Data        []byte `json:"data"` // Base64, the data may be binary
+	Node        string `json:"node"` // The node the data was found on
+}
+
+// httpWriteObject answers a GET or HEAD of an object that was found. The data is sent as it is, with the content
+// type it was stored with (application/octet-stream if it has none), unless the client accepts application/json
+// and not that content type: then it is wrapped in a getResponse. A HEAD gets the same headers without the body
+func httpWriteObject(w http.ResponseWriter, r *http.Request, hash *routing.KademliaID, object *kademlia.Object, node routing.Contact) {
+	contentType := object.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	body := object.Data
+	accepted := acceptedMediaTypes(r)
+	if accepted["application/json"] && !accepted[mediaType(contentType)] {
+		contentType = "application/json; charset=UTF-8"
+		body, _ = json.Marshal(getResponse{hash.String(), object.ContentType, len(object.Data), object.Data,
+			node.ID.String()})
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != "HEAD" {
+		w.Write(body)
+	}
+	httpLog.Info("Data read", "method", r.Method, "path", r.URL.Path, "length", len(object.Data),
+		"content_type", object.ContentType)
+}
+
+// acceptedMediaTypes returns the media types listed in the Accept header of a request, without their parameters.
+// Types with q=0 are not accepted
+func acceptedMediaTypes(r *http.Request) map[string]bool {
+	accepted := make(map[string]bool)
+	for _, header := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(header, ",") {
+			parameters := strings.Split(mediaRange, ";")
+			refused := false
+			for _, parameter := range parameters[1:] {
+				name, value, _ := strings.Cut(strings.TrimSpace(parameter), "=")
+				if q, err := strconv.ParseFloat(value, 64); name == "q" && err == nil && q == 0 {
+					refused = true
+				}
+			}
+			if !refused {
+				accepted[mediaType(parameters[0])] = true
+			}
+		}
+	}
+	return accepted
+}
+
+// mediaType returns the media type of a content type, without its parameters, in lower case
+func mediaType(contentType string) string {
+	return strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+}
+
+// Allows you to either POST (publish) a new version of the mutable record of this node
+// and to GET (resolve) the latest version of any record.
+func (api *httpAPI) RecordHTTPhandler(w http.ResponseWriter, r *http.Request){
+	network := api.network
+	switch r.Method {
+	case "POST":
+		body, error := ioutil.ReadAll(r.Body) // Read Request
+		defer r.Body.Close() // Always CLOSE.
+		if error != nil || len(body) == 0 {
+			http.Error(w, "ERROR", http.StatusBadRequest)
+			httpLog.Warn("Invalid request body", "method", r.Method, "path", r.URL.Path)
+			return
+		}
+		ctx, cancel := network.RequestContext(r.Context())
+		defer cancel()
+		key, sequence, replicas, err := network.PublishContext(ctx, body)
+		if err == context.Canceled || err == context.DeadlineExceeded {
+			// The record may still have reached enough nodes
+			if replicas < network.WriteQuorum() {
+				httpContextError(w, r, err)
+				return
+			}
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			httpLog.Warn("Could not publish record", "method", r.Method, "path", r.URL.Path, "error", err)
+			return
+		}
+		if replicas < network.WriteQuorum() {
+			http.Error(w, "ERROR", http.StatusServiceUnavailable)
+			httpLog.Warn("Write quorum not reached", "method", r.Method, "path", r.URL.Path, "replicas", replicas, "quorum", network.WriteQuorum())
+			return
+		}
+		message := map[string]interface{}{"key": key.String(), "sequence": sequence}
+		jsonValue,_ := json.Marshal(message)
+
+		w.Header().Set("Location", RecordURLprefix+key.String())
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(jsonValue)
+		httpLog.Info("Record published", "method", r.Method, "path", r.URL.Path, "hash", key, "sequence", sequence)
+	case "GET":
+		URLcomponents := strings.Split(r.URL.Path, "/")	// [ "", "records", "key" ]
+		key := URLcomponents[2]
+		hash, err := routing.ParseKademliaID(key)
+		if err != nil {
+			http.Error(w, "ERROR", http.StatusBadRequest)
+			httpLog.Warn("Invalid key", "method", r.Method, "path", r.URL.Path)
+			return
+		}
+		ctx, cancel := network.RequestContext(r.Context())
+		defer cancel()
+		record, _, err := network.ResolveContext(ctx, hash)
+		if record == nil && err != nil {
+			httpContextError(w, r, err)
+			return
+		}
+		if record == nil {
+			http.Error(w, "ERROR", http.StatusNotFound)
+			httpLog.Info("Record not found", "method", r.Method, "path", r.URL.Path)
+			return
+		}
+		w.Header().Set("X-Record-Sequence", strconv.FormatUint(record.Sequence, 10))
+		w.Header().Set("Content-Type", "application/octet-stream") // Records are stored without a content type
+		w.WriteHeader(http.StatusOK)
+		w.Write(record.Value)
+	default:
+		http.Error(w, "Wrong. Use POST or GET", http.StatusMethodNotAllowed)
+	}
+}
+
+// httpContextError answers a request whose network operations were stopped before they had a result. Nothing is
+// written if the client went away, and 504 is sent if the request timeout of the node was reached
+func httpContextError(w http.ResponseWriter, r *http.Request, err error) {
+	if r.Context().Err() != nil {
+		httpLog.Info("Client went away", "method", r.Method, "path", r.URL.Path)
+		return
+	}
+	http.Error(w, "ERROR", http.StatusGatewayTimeout)
+	httpLog.Warn("Request timed out", "method", r.Method, "path", r.URL.Path, "error", err)
+}
+
+// httpTracedGet answers GET /objects/{hash}?trace=1 with the data (if it was found) and the trace of the lookup
+// as JSON. The status is 200 if the data was found, 404 if it wasn't and 504 if the request timeout was reached
+func httpTracedGet(ctx context.Context, w http.ResponseWriter, r *http.Request, network *kademlia.Network, hash *routing.KademliaID) {
+	data, nodes, trace, err := network.DataLookupTrace(ctx, hash)
+	if r.Context().Err() != nil {
+		httpLog.Info("Client went away", "method", r.Method, "path", r.URL.Path)
+		return
+	}
+	response := tracedGetResponse{Hash: hash.String(), Trace: newTraceJSON(trace)}
+	status := http.StatusNotFound
+	if data != nil {
+		status = http.StatusOK
+		response.Found = true
+		response.Data = data
+		response.Node = nodes[0].ID.String()
+	} else if err != nil {
+		status = http.StatusGatewayTimeout
+		response.Error = err.Error()
+	}
+	jsonValue, _ := json.Marshal(response)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	w.Write(jsonValue)
+	httpLog.Info("Traced lookup", "method", r.Method, "path", r.URL.Path, "found", response.Found,
+		"rounds", len(trace.Rounds), "rpcs", trace.RPCs())
+}
+
+type tracedGetResponse struct {
+	Hash  string    `json:"hash"`
+	Found bool      `json:"found"`
+	Data  []byte    `json:"data,omitempty"` // Base64, the data may be binary
+	Node  string    `json:"node,omitempty"` // The node the data was found on
+	Error string    `json:"error,omitempty"`
+	Trace traceJSON `json:"trace"`
+}
+
+// httpRouter routes the HTTP API of a node to its handlers. main serves it on HTTP_ADDRESS
+func httpRouter(network *kademlia.Network) http.Handler {
+	api := &httpAPI{network}
+	r := mux.NewRouter()
+	r.HandleFunc("/objects/{hashvalue}", api.HTTPhandler).Methods("GET", "HEAD", "DELETE")
+	r.HandleFunc("/objects", api.HTTPhandler).Methods("POST")
+	r.HandleFunc("/records/{key}", api.RecordHTTPhandler).Methods("GET")
+	r.HandleFunc("/records", api.RecordHTTPhandler).Methods("POST")
+	r.Handle("/metrics", network.MetricsHandler()).Methods("GET")
+	r.HandleFunc(AdminURLprefix+"{view}", api.AdminHTTPhandler).Methods("GET")
+	return r
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"d7024e/kademlia"
+	"d7024e/routing"
+	"d7024e/transport"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newSilentNetwork creates a node whose only contact never answers, so that its lookups only end when their
+// context is done. stop closes the contact
+func newSilentNetwork(fake *transport.MemoryNetwork) (*kademlia.Network, func()) {
+	ip1 := net.ParseIP("0.0.0.0")
+	ip2 := net.ParseIP("0.0.0.1")
+	network := kademlia.NewNetwork(&ip1, fake.NewTransport("0.0.0.0", kademlia.KAD_PORT, kademlia.MAX_PACKET_SIZE,
+		10*time.Second), nil)
+
+	// The contact answers the join, and then stops answering
+	contact := newMemoryNetwork(fake, &ip2)
+	contact.Start()
+	time.Sleep(10 * time.Millisecond)
+	network.Join(contact.ID(), "0.0.0.1")
+	contact.Stop()
+	silent := fake.NewTransport("0.0.0.1", kademlia.KAD_PORT, kademlia.MAX_PACKET_SIZE, kademlia.TIMEOUT*time.Millisecond)
+	go silent.Listen(func(ctx context.Context, request []byte, from string, reply func([]byte) error) {})
+	time.Sleep(10 * time.Millisecond)
+	return &network, func() { silent.Close() }
+}
+
+// Tests for POST, GET and INVALID inputs through HTTP Requests.
+func TestHTTPhandler(t *testing.T) {
+	// Setup
+	addrs,_ := net.InterfaceAddrs()
+	var testIP net.IP
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
+			if ipnet.IP.To4() != nil {
+				testIP = ipnet.IP
+			}
+		}
+	}
+	prefix := "/objects/"
+	net:= newUDPNetwork(testIP)
+	api := &httpAPI{net}
+
+	// POST valid
+	httpRecorder1 := httptest.NewRecorder()
+	input1 := "test"
+	jsonInput1, _ := json.Marshal(input1)
+	request1 := httptest.NewRequest("POST", ("/objects"), bytes.NewBuffer(jsonInput1))
+	request1.Close =true
+
+	api.HTTPhandler(httpRecorder1, request1)
+	status1 := httpRecorder1.Code
+	expectedStatus1 := http.StatusCreated
+
+	if(status1 != expectedStatus1){
+		t.Errorf("WRONG STATUS CODE: GOT %v EXPECTED %v", status1, expectedStatus1)
+	}else{
+		fmt.Println("HTTP - POST Valid Input = Passed")
+	}
+
+	// Get Valid
+	httpRecorder2 := httptest.NewRecorder()
+	input2 := "5006d6f8302000e8b87fef5c50c071d6d97b4e88"
+	request2 := httptest.NewRequest("GET", (prefix+input2),nil)
+	request2.Close =true
+
+	api.HTTPhandler(httpRecorder2, request2)
+	status2 := httpRecorder2.Code
+	expectedStatus2 := http.StatusOK
+
+	if(status2 != expectedStatus2){
+		t.Errorf("WRONG STATUS CODE: GOT %v EXPECTED %v", status2, expectedStatus2)
+	}else{
+		fmt.Println("HTTP - GET Valid Input = Passed")
+	}
+
+	// Invalid Method Name
+	httpRecorder3 := httptest.NewRecorder()
+	input3 := ""
+	request3 := httptest.NewRequest("KADEMLIA", (prefix+input3), nil)
+	request3.Close =true
+
+	api.HTTPhandler(httpRecorder3, request3)
+	status3 := httpRecorder3.Code
+	expectedStatus3 := http.StatusMethodNotAllowed
+
+	if(status3 != expectedStatus3){
+		t.Errorf("WRONG STATUS CODE: GOT %v EXPECTED %v", status3, expectedStatus3)
+	}else{
+		fmt.Println("HTTP - Invalid Method Name = Passed")
+	}
+	// POST Invalid (Empty Body)
+	httpRecorder4 := httptest.NewRecorder()
+	input4 := ""
+	request4 := httptest.NewRequest("POST", (prefix+input4), bytes.NewBufferString(input4))
+	request4.Close =true
+
+	api.HTTPhandler(httpRecorder4, request4)
+	status4 := httpRecorder4.Code
+	expectedStatus4 := http.StatusBadRequest
+
+	if(status4 != expectedStatus4){
+		t.Errorf("WRONG STATUS CODE: GOT %v EXPECTED %v", status4, expectedStatus4)
+	}else{
+		fmt.Println("HTTP - POST Invalid Input (Empty String) = Passed")
+	}
+
+	// Get Invaid (Bad length of Hash)
+	httpRecorder5 := httptest.NewRecorder()
+	input5 := "thisisnothash!"
+	request5 := httptest.NewRequest("GET", (prefix+input5), nil)
+	request5.Close =true
+
+	api.HTTPhandler(httpRecorder5, request5)
+	status5 := httpRecorder5.Code
+	expectedStatus5 := http.StatusBadRequest
+
+	if(status5 != expectedStatus5){
+		t.Errorf("WRONG STATUS CODE: GOT %v EXPECTED %v", status5, expectedStatus5)
+	}else{
+		fmt.Println("HTTP - GET Invalid Input (Len) = Passed")
+	}
+	// Get Invaid (Non-Existing Hash, and no other nodes to ask)
+	httpRecorder6 := httptest.NewRecorder()
+	input6 := "a94a8fe5ccb19ba61c4c0873d391e98798200000"
+	request6 := httptest.NewRequest("GET", (prefix+input6), nil)
+	request6.Close =true
+
+	api.HTTPhandler(httpRecorder6, request6)
+	status6 := httpRecorder6.Code
+	expectedStatus6 := http.StatusServiceUnavailable
+
+	if(status6 != expectedStatus6){
+		t.Errorf("WRONG STATUS CODE: GOT %v EXPECTED %v", status6, expectedStatus6)
+	}else{
+		fmt.Println("HTTP - GET Valid Input = Passed")
+	}
+
+	// DELETE Valid (the data posted in the first test)
+	httpRecorder8 := httptest.NewRecorder()
+	request8 := httptest.NewRequest("DELETE", (prefix+input2), nil)
+	request8.Close =true
+
+	api.HTTPhandler(httpRecorder8, request8)
+	status8 := httpRecorder8.Code
+	expectedStatus8 := http.StatusNoContent
+
+	if(status8 != expectedStatus8){
+		t.Errorf("WRONG STATUS CODE: GOT %v EXPECTED %v", status8, expectedStatus8)
+	}else{
+		fmt.Println("HTTP - DELETE Valid Input = Passed")
+	}
+
+	// DELETE Invalid (Non-Existing Hash)
+	httpRecorder9 := httptest.NewRecorder()
+	request9 := httptest.NewRequest("DELETE", (prefix+input6), nil)
+	request9.Close =true
+
+	api.HTTPhandler(httpRecorder9, request9)
+	status9 := httpRecorder9.Code
+	expectedStatus9 := http.StatusNotFound
+
+	if(status9 != expectedStatus9){
+		t.Errorf("WRONG STATUS CODE: GOT %v EXPECTED %v", status9, expectedStatus9)
+	}else{
+		fmt.Println("HTTP - DELETE Invalid Input (Non-Existing Hash) = Passed")
+	}
+
+	// POST Invalid (Write quorum not reached, there are no other nodes to store the data on)
+	httpRecorder7 := httptest.NewRecorder()
+	input7 := "quorum"
+	jsonInput7, _ := json.Marshal(input7)
+	request7 := httptest.NewRequest("POST", ("/objects"), bytes.NewBuffer(jsonInput7))
+	request7.Close =true
+
+	net.SetWriteQuorum(2)
+	api.HTTPhandler(httpRecorder7, request7)
+	status7 := httpRecorder7.Code
+	expectedStatus7 := http.StatusServiceUnavailable
+
+	if(status7 != expectedStatus7){
+		t.Errorf("WRONG STATUS CODE: GOT %v EXPECTED %v", status7, expectedStatus7)
+	}else{
+		fmt.Println("HTTP - POST Write Quorum Not Reached = Passed")
+	}
+}
+// Lookups of a GET stop when the request timeout is reached or the client goes away
+func TestHTTPhandler_Context(t *testing.T) {
+	net, stop := newSilentNetwork(transport.NewMemoryNetwork())
+	defer stop()
+	api := &httpAPI{net}
+	path := "/objects/a94a8fe5ccb19ba61c4c0873d391e98798200000"
+
+	// Request timeout reached
+	net.SetRequestTimeout(20 * time.Millisecond)
+	httpRecorder1 := httptest.NewRecorder()
+	api.HTTPhandler(httpRecorder1, httptest.NewRequest("GET", path, nil))
+	if httpRecorder1.Code != http.StatusGatewayTimeout {
+		t.Errorf("WRONG STATUS CODE: GOT %v EXPECTED %v", httpRecorder1.Code, http.StatusGatewayTimeout)
+	}
+
+	// Client went away, nothing is written
+	net.SetRequestTimeout(kademlia.DEFAULT_REQUEST_TIMEOUT * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	httpRecorder2 := httptest.NewRecorder()
+	start := time.Now()
+	api.HTTPhandler(httpRecorder2, httptest.NewRequest("GET", path, nil).WithContext(ctx))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("HTTPhandler() took %v, want less than %v", elapsed, time.Second)
+	}
+	if httpRecorder2.Body.Len() != 0 {
+		t.Errorf("HTTPhandler() wrote %v, want nothing", httpRecorder2.Body.String())
+	}
+}
+
+// The router serves the metrics of the node
+func TestHTTPRouter_Metrics(t *testing.T) {
+	ip := net.ParseIP("0.0.0.0")
+	network := newMemoryNetwork(transport.NewMemoryNetwork(), &ip)
+	httpRecorder := httptest.NewRecorder()
+	httpRouter(network).ServeHTTP(httpRecorder, httptest.NewRequest("GET", "/metrics", nil))
+	if httpRecorder.Code != http.StatusOK {
+		t.Errorf("WRONG STATUS CODE: GOT %v EXPECTED %v", httpRecorder.Code, http.StatusOK)
+	}
+	if !strings.Contains(httpRecorder.Body.String(), "# TYPE kademlia_rpcs_sent_total counter") {
+		t.Errorf("httpRouter() = %v, want the metrics of the node", httpRecorder.Body.String())
+	}
+}
+
+// GET with ?trace=1 answers with the data and the trace of the lookup as JSON
+func TestHTTPhandler_Trace(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	ip1 := net.ParseIP("0.0.0.0")
+	ip2 := net.ParseIP("0.0.0.1")
+	net1 := newMemoryNetwork(fake, &ip1)
+	net2 := newMemoryNetwork(fake, &ip2)
+	net1.Start()
+	net2.Start()
+	defer net1.Stop()
+	defer net2.Stop()
+	time.Sleep(10 * time.Millisecond)
+	// Only net1 stores the data, it doesn't know net2 yet
+	hash, _ := net1.Put(context.Background(), []byte("traced"))
+	net2.Join(net1.ID(), ip1.String())
+	router := httpRouter(net2)
+
+	tests := []struct {
+		name      string
+		hash      string
+		wantCode  int
+		wantFound bool
+		wantData  string
+	}{
+		{"Found", hash.String(), http.StatusOK, true, "traced"},
+		{"Not found", routing.NewKademliaIDFromData("missing").String(), http.StatusNotFound, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpRecorder := httptest.NewRecorder()
+			router.ServeHTTP(httpRecorder, httptest.NewRequest("GET", URLprefix+tt.hash+"?trace=1", nil))
+			if httpRecorder.Code != tt.wantCode {
+				t.Errorf("WRONG STATUS CODE: GOT %v EXPECTED %v", httpRecorder.Code, tt.wantCode)
+			}
+			var response tracedGetResponse
+			if err := json.Unmarshal(httpRecorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("json.Unmarshal() = %v, want %v", err, nil)
+			}
+			if response.Found != tt.wantFound || string(response.Data) != tt.wantData || response.Trace.Kind != "data" ||
+				response.Trace.Target != tt.hash {
+				t.Errorf("HTTPhandler() = %+v, want found %v and data %v", response, tt.wantFound, tt.wantData)
+			}
+			if response.Trace.Local || response.Trace.RPCs != 1 || len(response.Trace.Rounds) != 1 ||
+				response.Trace.Rounds[0].Queries[0].Contact.ID != net1.ID().String() {
+				t.Errorf("HTTPhandler() trace = %+v, want 1 RPC to %v", response.Trace, net1.ID())
+			}
+		})
+	}
+}
+
+// POST answers with the hash and the number of replicas, GET and HEAD answer with the content type the data was
+// posted with, as raw bytes or as JSON depending on the Accept header
+func TestHTTPhandler_REST(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	ip1 := net.ParseIP("0.0.0.0")
+	ip2 := net.ParseIP("0.0.0.1")
+	net1 := newMemoryNetwork(fake, &ip1)
+	net2 := newMemoryNetwork(fake, &ip2)
+	net1.Start()
+	net2.Start()
+	defer net1.Stop()
+	defer net2.Stop()
+	time.Sleep(10 * time.Millisecond)
+	net2.Join(net1.ID(), ip1.String())
+	router := httpRouter(net2)
+	serve := func(method string, path string, body []byte, header map[string]string) *httptest.ResponseRecorder {
+		httpRecorder := httptest.NewRecorder()
+		request := httptest.NewRequest(method, path, bytes.NewReader(body))
+		for name, value := range header {
+			request.Header.Set(name, value)
+		}
+		router.ServeHTTP(httpRecorder, request)
+		return httpRecorder
+	}
+
+	// POST
+	html := []byte("<p>Hello world!</p>")
+	hash := routing.NewKademliaIDFromData(string(html)).String()
+	httpRecorder := serve("POST", "/objects", html, map[string]string{"Content-Type": "text/html; charset=utf-8"})
+	if httpRecorder.Code != http.StatusCreated {
+		t.Errorf("WRONG STATUS CODE: GOT %v EXPECTED %v", httpRecorder.Code, http.StatusCreated)
+	}
+	if want := `{"hash":"` + hash + `","replicas":2}`; httpRecorder.Body.String() != want ||
+		httpRecorder.Header().Get("Location") != URLprefix+hash {
+		t.Errorf("HTTPhandler() = %v, %v, want %v, %v", httpRecorder.Body.String(), httpRecorder.Header().Get("Location"),
+			want, URLprefix+hash)
+	}
+	// A body of two bytes is not an empty JSON string
+	if httpRecorder := serve("POST", "/objects", []byte("hi"), nil); httpRecorder.Code != http.StatusCreated {
+		t.Errorf("WRONG STATUS CODE: GOT %v EXPECTED %v", httpRecorder.Code, http.StatusCreated)
+	}
+	binary := []byte{0, 1, 2, 255}
+	binaryHash := routing.NewKademliaIDFromData(string(binary)).String()
+	serve("POST", "/objects", binary, nil)
+	tooLong := map[string]string{"Content-Type": strings.Repeat("a", kademlia.MAX_CONTENT_TYPE_LEN+1)}
+	if httpRecorder := serve("POST", "/objects", []byte("data"), tooLong); httpRecorder.Code != http.StatusBadRequest {
+		t.Errorf("WRONG STATUS CODE: GOT %v EXPECTED %v", httpRecorder.Code, http.StatusBadRequest)
+	}
+
+	// GET and HEAD
+	missing := routing.NewKademliaIDFromData("missing").String()
+	tests := []struct {
+		name            string
+		method          string
+		hash            string
+		accept          string
+		wantCode        int
+		wantContentType string
+		wantBody        string
+	}{
+		{"Raw", "GET", hash, "", http.StatusOK, "text/html; charset=utf-8", string(html)},
+		{"Raw accepted", "GET", hash, "text/html, application/json", http.StatusOK, "text/html; charset=utf-8", string(html)},
+		{"Raw without content type", "GET", binaryHash, "*/*", http.StatusOK, "application/octet-stream", string(binary)},
+		{"JSON", "GET", hash, "application/json", http.StatusOK, "application/json; charset=UTF-8",
+			`{"hash":"` + hash + `","content_type":"text/html; charset=utf-8","size":19,"data":"PHA+SGVsbG8gd29ybGQhPC9wPg==","node":"` +
+				net2.ID().String() + `"}`},
+		{"JSON refused", "GET", binaryHash, "application/json;q=0", http.StatusOK, "application/octet-stream", string(binary)},
+		{"HEAD", "HEAD", hash, "", http.StatusOK, "text/html; charset=utf-8", ""},
+		{"Not found", "GET", missing, "", http.StatusNotFound, "text/plain; charset=utf-8", "ERROR\n"},
+		{"HEAD not found", "HEAD", missing, "", http.StatusNotFound, "text/plain; charset=utf-8", "ERROR\n"},
+		{"Invalid hash length", "GET", "1234", "", http.StatusBadRequest, "text/plain; charset=utf-8", "ERROR\n"},
+		{"Invalid hash", "HEAD", strings.Repeat("x", 40), "", http.StatusBadRequest, "text/plain; charset=utf-8", "ERROR\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpRecorder := serve(tt.method, URLprefix+tt.hash, nil, map[string]string{"Accept": tt.accept})
+			if httpRecorder.Code != tt.wantCode {
+				t.Errorf("WRONG STATUS CODE: GOT %v EXPECTED %v", httpRecorder.Code, tt.wantCode)
+			}
+			if contentType := httpRecorder.Header().Get("Content-Type"); contentType != tt.wantContentType ||
+				httpRecorder.Body.String() != tt.wantBody {
+				t.Errorf("HTTPhandler() = %v, %v, want %v, %v", contentType, httpRecorder.Body.String(),
+					tt.wantContentType, tt.wantBody)
+			}
+		})
+	}
+	if httpRecorder := serve("HEAD", URLprefix+hash, nil, nil); httpRecorder.Header().Get("Content-Length") != "19" {
+		t.Errorf("HTTPhandler() Content-Length = %v, want %v", httpRecorder.Header().Get("Content-Length"), 19)
+	}
+}
+
+// A published record can be resolved, with its sequence number and a content type
+func TestRecordHTTPhandler(t *testing.T) {
+	ip := net.ParseIP("0.0.0.0")
+	network := newMemoryNetwork(transport.NewMemoryNetwork(), &ip)
+	router := httpRouter(network)
+
+	httpRecorder := httptest.NewRecorder()
+	router.ServeHTTP(httpRecorder, httptest.NewRequest("POST", "/records", bytes.NewBufferString("v1")))
+	if httpRecorder.Code != http.StatusCreated {
+		t.Fatalf("WRONG STATUS CODE: GOT %v EXPECTED %v", httpRecorder.Code, http.StatusCreated)
+	}
+	location := httpRecorder.Header().Get("Location")
+	var published struct {
+		Key      string
+		Sequence uint64
+	}
+	if err := json.Unmarshal(httpRecorder.Body.Bytes(), &published); err != nil || published.Sequence == 0 {
+		t.Errorf("RecordHTTPhandler() = %v, want the key and sequence of the record", httpRecorder.Body.String())
+	}
+
+	httpRecorder = httptest.NewRecorder()
+	router.ServeHTTP(httpRecorder, httptest.NewRequest("GET", location, nil))
+	if httpRecorder.Code != http.StatusOK {
+		t.Errorf("WRONG STATUS CODE: GOT %v EXPECTED %v", httpRecorder.Code, http.StatusOK)
+	}
+	if contentType := httpRecorder.Header().Get("Content-Type"); contentType != "application/octet-stream" ||
+		httpRecorder.Body.String() != "v1" ||
+		httpRecorder.Header().Get("X-Record-Sequence") != strconv.FormatUint(published.Sequence, 10) {
+		t.Errorf("RecordHTTPhandler() = %v, %v, want %v, %v", contentType, httpRecorder.Body.String(),
+			"application/octet-stream", "v1")
+	}
+}
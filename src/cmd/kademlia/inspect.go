@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"d7024e/kademlia"
+	"d7024e/routing"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Commands that show the state of the node while attached to its container. Unlike the other commands they take
+// any number of arguments
+
+// handleInspectInput runs an introspection command with its arguments. Returns false if command isn't one
+func handleInspectInput(command string, args []string, network *kademlia.Network) (commandResult, bool) {
+	switch command {
+	case "id":
+		return okResult(showID(network)), true
+	case "table":
+		return okResult(showTable(network)), true
+	case "closest":
+		return closest(args, network), true
+	case "lookup":
+		return lookup(args, network), true
+	case "trace":
+		return traceData(args, network), true
+	case "stored":
+		return okResult(showStored(network)), true
+	case "remembered":
+		return okResult(showRemembered(network)), true
+	default:
+		return commandResult{}, false
+	}
+}
+
+// Output the ID and address of the node, and the size of its routing table and storage
+func showID(network *kademlia.Network) string {
+	info := network.Info()
+	return "ID: " + info.ID.String() + "\n" +
+		"Address: " + info.Address + "\n" +
+		"Contacts: " + strconv.Itoa(info.Contacts) + "\n" +
+		"Stored: " + strconv.Itoa(info.Storage.Items) + " objects, " + strconv.Itoa(info.Storage.Bytes) + " bytes (" +
+		strconv.Itoa(info.Storage.CachedItems) + " cached)"
+}
+
+// Output the contacts in each non-empty bucket of the routing table, most recently seen first
+func showTable(network *kademlia.Network) string {
+	buckets := network.Buckets()
+	if len(buckets) == 0 {
+		return "The routing table is empty"
+	}
+	now := time.Now()
+	var output strings.Builder
+	for _, bucket := range buckets {
+		output.WriteString("Bucket " + strconv.Itoa(bucket.Index) + ": " + strconv.Itoa(len(bucket.Contacts)) + " contacts\n")
+		for _, contact := range bucket.Contacts {
+			output.WriteString("  " + formatContact(contact) + "  last seen " + formatDuration(now.Sub(contact.LastSeen)) + " ago\n")
+		}
+	}
+	return strings.TrimSuffix(output.String(), "\n")
+}
+
+// Take an ID and optionally a count (k by default), and output the closest contacts to the ID in the routing table
+func closest(args []string, network *kademlia.Network) commandResult {
+	if len(args) == 0 || len(args) > 2 {
+		return errorResult("Closest takes an ID and optionally the number of contacts")
+	}
+	target, invalid := parseHash(args[0])
+	if invalid != "" {
+		return errorResult(invalid)
+	}
+	count := routing.K
+	if len(args) == 2 {
+		var err error
+		if count, err = strconv.Atoi(args[1]); err != nil || count < 1 {
+			return errorResult("Invalid number of contacts")
+		}
+	}
+	contacts := network.ClosestContacts(target, count)
+	if len(contacts) == 0 {
+		return okResult("The routing table is empty")
+	}
+	var output strings.Builder
+	output.WriteString(strconv.Itoa(len(contacts)) + " closest contacts to " + target.String() + ":")
+	for _, contact := range contacts {
+		output.WriteString("\n  " + formatContact(contact) + "  distance " + contact.Distance.String())
+	}
+	return okResult(output.String())
+}
+
+// Take an ID, look up the closest nodes to it in the network, and output every round of the lookup and the result
+func lookup(args []string, network *kademlia.Network) commandResult {
+	if len(args) != 1 {
+		return errorResult("Lookup takes an ID as its only argument")
+	}
+	target, invalid := parseHash(args[0])
+	if invalid != "" {
+		return errorResult(invalid)
+	}
+	ctx, cancel := network.RequestContext(context.Background())
+	defer cancel()
+	contacts, trace, err := network.NodeLookupTrace(ctx, target)
+
+	var output strings.Builder
+	output.WriteString(formatTrace(trace) + "\nFound " + strconv.Itoa(len(contacts)) + " contacts:")
+	for _, contact := range contacts {
+		output.WriteString("\n  " + formatContact(contact))
+	}
+	if err != nil {
+		output.WriteString("\nLookup stopped: " + err.Error())
+		return errorResult(output.String())
+	}
+	return okResult(output.String())
+}
+
+// Output the data objects and records stored on the node, with their size and remaining TTL
+func showStored(network *kademlia.Network) string {
+	items := network.StoredItems()
+	if len(items) == 0 {
+		return "Nothing is stored"
+	}
+	var output strings.Builder
+	for i, item := range items {
+		if i > 0 {
+			output.WriteString("\n")
+		}
+		output.WriteString(item.Hash.String() + "  " + strconv.Itoa(item.Size) + " bytes  TTL " + formatDuration(item.TTL))
+		if item.Cached {
+			output.WriteString("  (cached)")
+		}
+	}
+	return output.String()
+}
+
+// Output the hashes of the data the node keeps refreshing, with the addresses of the contacts it is refreshed on
+func showRemembered(network *kademlia.Network) string {
+	remembered := network.Remembered()
+	if len(remembered) == 0 {
+		return "Nothing is remembered"
+	}
+	lines := make([]string, 0, len(remembered))
+	for hash, contacts := range remembered {
+		hash := hash
+		addresses := make([]string, len(contacts))
+		for i, contact := range contacts {
+			addresses[i] = contact.Address
+		}
+		lines = append(lines, hash.String()+"  refreshed on "+strconv.Itoa(len(contacts))+" contacts: "+
+			strings.Join(addresses, ", "))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+func formatContact(contact routing.Contact) string {
+	return contact.ID.String() + "  " + contact.Address
+}
+
+// formatDuration formats a duration rounded to milliseconds, or to tenths of a second if it is longer than a second
+func formatDuration(duration time.Duration) string {
+	if duration >= time.Second {
+		return duration.Round(100 * time.Millisecond).String()
+	}
+	return duration.Round(time.Millisecond).String()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"d7024e/routing"
+	"d7024e/transport"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleInspectInput(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	ip1 := net.ParseIP("0.0.0.0")
+	ip2 := net.ParseIP("0.0.0.1")
+	net1 := newMemoryNetwork(fake, &ip1)
+	net2 := newMemoryNetwork(fake, &ip2)
+	net1.Start()
+	net2.Start()
+	defer net1.Stop()
+	defer net2.Stop()
+	time.Sleep(10 * time.Millisecond)
+	net2.Join(net1.ID(), ip1.String())
+	hash, _ := net2.Put(context.Background(), []byte("test"))
+
+	tests := []struct {
+		name  string
+		input string
+		want  []string // Lines the output must contain
+	}{
+		{"ID", "id", []string{"ID: " + net2.ID().String(), "Address: 0.0.0.1", "Contacts: 1", "Stored: 1 objects, 4 bytes (0 cached)"}},
+		{"Table", "TABLE", []string{"Bucket ", ": 1 contacts", "  " + net1.ID().String() + "  0.0.0.0  last seen "}},
+		{"Closest", "closest " + net1.ID().String(), []string{"1 closest contacts to " + net1.ID().String() + ":",
+			"  " + net1.ID().String() + "  0.0.0.0  distance 0000000000000000000000000000000000000000"}},
+		{"Closest with count", "closest " + net1.ID().String() + " 5", []string{"1 closest contacts"}},
+		{"Closest with invalid count", "closest " + net1.ID().String() + " none", []string{"Invalid number of contacts"}},
+		{"Closest without ID", "closest", []string{"Closest takes an ID and optionally the number of contacts"}},
+		{"Lookup", "lookup " + net2.ID().String(), []string{"Round 1:", "  " + net1.ID().String() + "  0.0.0.0  distance ",
+			"  answered in ", "1 rounds, 1 RPCs, 0 timeouts, ", "Found 1 contacts:\n  " + net1.ID().String() + "  0.0.0.0"}},
+		{"Lookup with invalid ID", "lookup 1234", []string{"Invalid hash length"}},
+		{"Trace of local data", "trace " + hash.String(), []string{"Found the data in the local storage",
+			"0 rounds, 0 RPCs, 0 timeouts, ", "Found 4 bytes on " + net2.ID().String() + "  0.0.0.1"}},
+		{"Trace of missing data", "trace " + routing.NewKademliaIDFromData("missing").String(), []string{"Round 1:",
+			"  " + net1.ID().String() + "  0.0.0.0  distance ", "  answered in ", "Hashvalue Does Not Exist In The Network"}},
+		{"Trace without hash", "trace", []string{"Trace takes a hash as its only argument"}},
+		{"Stored", "stored", []string{hash.String() + "  4 bytes  TTL "}},
+		{"Remembered", "remembered", []string{hash.String() + "  refreshed on 2 contacts: "}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := parseInput(tt.input, net2).Output
+			for _, want := range tt.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("parseInput() = %v, want %v", output, want)
+				}
+			}
+		})
+	}
+
+	// A node that knows nobody has nothing to show
+	ip3 := net.ParseIP("0.0.0.2")
+	net3 := newMemoryNetwork(fake, &ip3)
+	empty := map[string]string{
+		"table": "The routing table is empty",
+		"closest " + routing.NewKademliaIDFromData("").String(): "The routing table is empty",
+		"stored":     "Nothing is stored",
+		"remembered": "Nothing is remembered",
+	}
+	for input, want := range empty {
+		if output := parseInput(input, net3).Output; output != want {
+			t.Errorf("parseInput() = %v, want %v", output, want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"d7024e/kademlia"
+	"d7024e/routing"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Lookup traces, as text for the lookup and trace commands of the CLI and as JSON for GET /objects/{hash}?trace=1
+
+type traceContact struct {
+	ID       string `json:"id"`
+	Address  string `json:"address"`
+	Distance string `json:"distance,omitempty"`
+}
+
+type traceQuery struct {
+	Contact    traceContact   `json:"contact"`
+	Answered   bool           `json:"answered"`
+	TimedOut   bool           `json:"timed_out"`
+	Error      string         `json:"error,omitempty"`
+	DurationMs float64        `json:"duration_ms"`
+	FoundData  bool           `json:"found_data"`
+	Returned   []traceContact `json:"returned"`
+}
+
+type traceRound struct {
+	Queries []traceQuery `json:"queries"`
+}
+
+type traceJSON struct {
+	Kind       string       `json:"kind"`
+	Target     string       `json:"target"`
+	Started    time.Time    `json:"started"`
+	DurationMs float64      `json:"duration_ms"`
+	Local      bool         `json:"local"`
+	RPCs       int          `json:"rpcs"`
+	Rounds     []traceRound `json:"rounds"`
+}
+
+func newTraceJSON(trace *kademlia.LookupTrace) traceJSON {
+	converted := traceJSON{trace.Kind, trace.Target.String(), trace.Started, milliseconds(trace.Duration), trace.Local,
+		trace.RPCs(), []traceRound{}}
+	for _, round := range trace.Rounds {
+		queries := []traceQuery{}
+		for _, query := range round.Queries {
+			converted := traceQuery{Contact: newTraceContact(query.Contact), Answered: query.Answered,
+				TimedOut: query.TimedOut(), DurationMs: milliseconds(query.Duration), FoundData: query.FoundData,
+				Returned: []traceContact{}}
+			if query.Err != nil {
+				converted.Error = query.Err.Error()
+			}
+			for _, contact := range query.Returned {
+				converted.Returned = append(converted.Returned, newTraceContact(contact))
+			}
+			queries = append(queries, converted)
+		}
+		converted.Rounds = append(converted.Rounds, traceRound{queries})
+	}
+	return converted
+}
+
+func newTraceContact(contact routing.Contact) traceContact {
+	converted := traceContact{ID: contact.ID.String(), Address: contact.Address}
+	if contact.Distance != nil {
+		converted.Distance = contact.Distance.String()
+	}
+	return converted
+}
+
+// milliseconds converts a duration to fractional milliseconds, RPCs on a LAN often take less than one
+func milliseconds(duration time.Duration) float64 {
+	return float64(duration) / float64(time.Millisecond)
+}
+
+// formatTrace formats every round of a lookup, and a summary of the whole lookup
+func formatTrace(trace *kademlia.LookupTrace) string {
+	var output strings.Builder
+	timeouts := 0
+	for i, round := range trace.Rounds {
+		output.WriteString("Round " + strconv.Itoa(i+1) + ":\n")
+		for _, query := range round.Queries {
+			output.WriteString("  " + formatContact(query.Contact))
+			if query.Contact.Distance != nil {
+				output.WriteString("  distance " + query.Contact.Distance.String())
+			}
+			switch {
+			case query.FoundData:
+				output.WriteString("  returned the data in " + formatDuration(query.Duration) + "\n")
+			case query.Answered:
+				output.WriteString("  answered in " + formatDuration(query.Duration) + ", returned " +
+					strconv.Itoa(len(query.Returned)) + " contacts\n")
+			case query.TimedOut():
+				timeouts++
+				output.WriteString("  timed out after " + formatDuration(query.Duration) + "\n")
+			default:
+				output.WriteString("  failed after " + formatDuration(query.Duration) + ": " + query.Err.Error() + "\n")
+			}
+		}
+	}
+	if trace.Local {
+		output.WriteString("Found the data in the local storage\n")
+	}
+	output.WriteString(strconv.Itoa(len(trace.Rounds)) + " rounds, " + strconv.Itoa(trace.RPCs()) + " RPCs, " +
+		strconv.Itoa(timeouts) + " timeouts, " + formatDuration(trace.Duration))
+	return output.String()
+}
+
+// Take a hash, look up the data in the network, and output every round of the lookup and where the data was found
+func traceData(args []string, network *kademlia.Network) commandResult {
+	if len(args) != 1 {
+		return errorResult("Trace takes a hash as its only argument")
+	}
+	hash, invalid := parseHash(args[0])
+	if invalid != "" {
+		return errorResult(invalid)
+	}
+	ctx, cancel := network.RequestContext(context.Background())
+	defer cancel()
+	data, nodes, trace, err := network.DataLookupTrace(ctx, hash)
+
+	output := formatTrace(trace)
+	if data != nil {
+		return okResult(output + "\nFound " + strconv.Itoa(len(data)) + " bytes on " + formatContact(nodes[0]))
+	} else if err != nil {
+		return errorResult(output + "\nLookup stopped: " + err.Error())
+	}
+	return errorResult(output + "\nHashvalue Does Not Exist In The Network")
+}
@@ -0,0 +1,368 @@
+package codec
+
+import (
+	"encoding/binary"
+	"reflect"
+)
+
+// The CBOR encoding (RFC 8949) writes every message as a self-described CBOR map:
+//
+// 	55799({0: version, 1: type, 2: flags, 3: request ID, 4: sender ID (bytes), 5: body})
+//
+// The body is a map from field numbers to values. Byte slices and arrays are byte strings, a byte is an unsigned
+// integer and a slice of structs is an array of maps. Only definite lengths are supported.
+// Any CBOR decoder can read the messages, and the tag 55799 is what Detect uses to tell the encodings apart.
+
+// CBOR major types
+const (
+	cborUint  byte = 0
+	cborBytes byte = 2
+	cborText  byte = 3
+	cborArray byte = 4
+	cborMap   byte = 5
+	cborTag   byte = 6
+	cborOther byte = 7
+)
+
+// cborSelfDescribed is the encoded tag 55799 that starts every message
+var cborSelfDescribed = []byte{0xd9, 0xd9, 0xf7}
+
+// Keys of the message map
+const (
+	cborKeyVersion   = 0
+	cborKeyType      = 1
+	cborKeyFlags     = 2
+	cborKeyRequestID = 3
+	cborKeySender    = 4
+	cborKeyBody      = 5
+)
+
+func isCBOR(data []byte) bool {
+	return len(data) >= len(cborSelfDescribed) && string(data[:len(cborSelfDescribed)]) == string(cborSelfDescribed)
+}
+
+type cborEncoding struct{}
+
+func (cborEncoding) Name() string {
+	return "cbor"
+}
+
+func (cborEncoding) Encode(header Header, body interface{}) ([]byte, error) {
+	data := append([]byte{}, cborSelfDescribed...)
+	data = appendHead(data, cborMap, 6)
+	data = appendHead(data, cborUint, cborKeyVersion)
+	data = appendHead(data, cborUint, uint64(header.Version))
+	data = appendHead(data, cborUint, cborKeyType)
+	data = appendHead(data, cborUint, uint64(header.Type))
+	data = appendHead(data, cborUint, cborKeyFlags)
+	data = appendHead(data, cborUint, uint64(header.Flags))
+	data = appendHead(data, cborUint, cborKeyRequestID)
+	data = appendHead(data, cborUint, uint64(header.RequestID))
+	data = appendHead(data, cborUint, cborKeySender)
+	data = appendHead(data, cborBytes, IDLen)
+	data = append(data, header.Sender[:]...)
+	data = appendHead(data, cborUint, cborKeyBody)
+	return appendBody(data, structOf(body))
+}
+
+func (cborEncoding) Decode(data []byte, newBody func(msgType byte) interface{}) (Header, interface{}, error) {
+	var header Header
+	if !isCBOR(data) {
+		return header, nil, ErrBadMagic
+	}
+	reader := cborReader{data[len(cborSelfDescribed):]}
+	entries, err := reader.expect(cborMap)
+	if err != nil {
+		return header, nil, err
+	}
+
+	// The keys can be in any order, so the body is decoded when the type is known
+	var bodyData []byte
+	var seen [cborKeyBody + 1]bool
+	for i := uint64(0); i < entries; i++ {
+		key, err := reader.expect(cborUint)
+		if err != nil {
+			return header, nil, err
+		}
+		if key > cborKeyBody {
+			if err := reader.skip(); err != nil {
+				return header, nil, err
+			}
+			continue
+		}
+		seen[key] = true
+		switch key {
+		case cborKeyVersion:
+			header.Version, err = reader.byteValue()
+		case cborKeyType:
+			header.Type, err = reader.byteValue()
+		case cborKeyFlags:
+			header.Flags, err = reader.byteValue()
+		case cborKeyRequestID:
+			var requestID uint64
+			requestID, err = reader.expect(cborUint)
+			if requestID > 0xffffffff {
+				err = ErrInvalidField
+			}
+			header.RequestID = uint32(requestID)
+		case cborKeySender:
+			var sender []byte
+			sender, err = reader.bytes()
+			if err == nil && len(sender) != IDLen {
+				err = ErrInvalidField
+			}
+			copy(header.Sender[:], sender)
+		case cborKeyBody:
+			start := reader.data
+			err = reader.skip()
+			bodyData = start[:len(start)-len(reader.data)]
+		}
+		if err != nil {
+			return header, nil, err
+		}
+	}
+	for key, found := range seen {
+		if !found && key != cborKeyFlags {
+			return header, nil, ErrMissingField
+		}
+	}
+	if header.Version < MinVersion {
+		return header, nil, ErrUnsupportedVersion
+	}
+
+	body := newBody(header.Type)
+	if body == nil {
+		return header, nil, ErrUnknownType
+	}
+	bodyReader := cborReader{bodyData}
+	if err := bodyReader.body(structOf(body)); err != nil {
+		return header, nil, err
+	}
+	return header, body, nil
+}
+
+// appendHead appends the first bytes of a CBOR item: the major type and an argument (a value or a length)
+func appendHead(data []byte, major byte, argument uint64) []byte {
+	major <<= 5
+	switch {
+	case argument < 24:
+		return append(data, major|byte(argument))
+	case argument <= 0xff:
+		return append(data, major|24, byte(argument))
+	case argument <= 0xffff:
+		data = append(data, major|25, 0, 0)
+		binary.BigEndian.PutUint16(data[len(data)-2:], uint16(argument))
+	case argument <= 0xffffffff:
+		data = append(data, major|26, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(data[len(data)-4:], uint32(argument))
+	default:
+		data = append(data, major|27, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(data[len(data)-8:], argument)
+	}
+	return data
+}
+
+// appendBody appends the numbered fields of a struct as a CBOR map. Optional fields are left out if they are empty
+func appendBody(data []byte, body reflect.Value) ([]byte, error) {
+	var present []field
+	for _, field := range fieldsOf(body) {
+		if !field.optional || !field.value.IsZero() {
+			present = append(present, field)
+		}
+	}
+	data = appendHead(data, cborMap, uint64(len(present)))
+	for _, field := range present {
+		data = appendHead(data, cborUint, uint64(field.number))
+		switch {
+		case isBytes(field.value):
+			value := bytesOf(field.value)
+			data = appendHead(data, cborBytes, uint64(len(value)))
+			data = append(data, value...)
+		case field.value.Kind() == reflect.Uint8:
+			data = appendHead(data, cborUint, field.value.Uint())
+		case isStructSlice(field.value):
+			data = appendHead(data, cborArray, uint64(field.value.Len()))
+			for i := 0; i < field.value.Len(); i++ {
+				var err error
+				if data, err = appendBody(data, field.value.Index(i)); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			panic("codec: unsupported field type " + field.value.Type().String())
+		}
+	}
+	return data, nil
+}
+
+// cborReader reads CBOR items from the start of data
+type cborReader struct {
+	data []byte
+}
+
+// head reads the major type and argument of the next item
+func (reader *cborReader) head() (byte, uint64, error) {
+	if len(reader.data) < 1 {
+		return 0, 0, ErrTruncated
+	}
+	major, info := reader.data[0]>>5, reader.data[0]&0x1f
+	reader.data = reader.data[1:]
+	if info < 24 {
+		return major, uint64(info), nil
+	}
+	if info > 27 {
+		// Indefinite lengths and reserved values
+		return 0, 0, ErrInvalidField
+	}
+	size := 1 << (info - 24)
+	if len(reader.data) < size {
+		return 0, 0, ErrTruncated
+	}
+	var argument uint64
+	for _, b := range reader.data[:size] {
+		argument = argument<<8 | uint64(b)
+	}
+	reader.data = reader.data[size:]
+	return major, argument, nil
+}
+
+// expect reads the head of an item of some major type
+func (reader *cborReader) expect(major byte) (uint64, error) {
+	found, argument, err := reader.head()
+	if err != nil {
+		return 0, err
+	}
+	if found != major {
+		return 0, ErrInvalidField
+	}
+	return argument, nil
+}
+
+// byteValue reads an unsigned integer that must fit in a byte
+func (reader *cborReader) byteValue() (byte, error) {
+	value, err := reader.expect(cborUint)
+	if err == nil && value > 0xff {
+		err = ErrInvalidField
+	}
+	return byte(value), err
+}
+
+// bytes reads a byte string. The result points into the data of the reader
+func (reader *cborReader) bytes() ([]byte, error) {
+	length, err := reader.expect(cborBytes)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(reader.data)) < length {
+		return nil, ErrTruncated
+	}
+	value := reader.data[:length]
+	reader.data = reader.data[length:]
+	return value, nil
+}
+
+// skip reads past the next item, whatever it is
+func (reader *cborReader) skip() error {
+	major, argument, err := reader.head()
+	if err != nil {
+		return err
+	}
+	switch major {
+	case cborBytes, cborText:
+		if uint64(len(reader.data)) < argument {
+			return ErrTruncated
+		}
+		reader.data = reader.data[argument:]
+	case cborArray, cborMap:
+		// Every item is at least one byte, this stops huge lengths before the loop does
+		if argument > uint64(len(reader.data)) {
+			return ErrTruncated
+		}
+		items := argument
+		if major == cborMap {
+			items *= 2
+		}
+		for i := uint64(0); i < items; i++ {
+			if err := reader.skip(); err != nil {
+				return err
+			}
+		}
+	case cborTag:
+		return reader.skip()
+	}
+	// Integers and simple values have nothing after the head
+	return nil
+}
+
+// body reads a map into the numbered fields of a struct. Unknown keys are skipped
+func (reader *cborReader) body(body reflect.Value) error {
+	entries, err := reader.expect(cborMap)
+	if err != nil {
+		return err
+	}
+	fields := fieldsOf(body)
+	found := make([]bool, len(fields))
+	for i := uint64(0); i < entries; i++ {
+		key, err := reader.expect(cborUint)
+		if err != nil {
+			return err
+		}
+		index := -1
+		for j, field := range fields {
+			if uint64(field.number) == key {
+				index = j
+			}
+		}
+		if index < 0 {
+			if err := reader.skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		found[index] = true
+		if err := reader.value(fields[index].value); err != nil {
+			return err
+		}
+	}
+	for i, field := range fields {
+		if !found[i] && !field.optional {
+			return ErrMissingField
+		}
+	}
+	return nil
+}
+
+// value reads an item into a field of a struct
+func (reader *cborReader) value(value reflect.Value) error {
+	switch {
+	case isBytes(value):
+		data, err := reader.bytes()
+		if err != nil {
+			return err
+		}
+		return setBytes(value, data)
+	case value.Kind() == reflect.Uint8:
+		data, err := reader.byteValue()
+		value.SetUint(uint64(data))
+		return err
+	case isStructSlice(value):
+		length, err := reader.expect(cborArray)
+		if err != nil {
+			return err
+		}
+		if length > uint64(len(reader.data)) {
+			return ErrTruncated
+		}
+		value.Set(reflect.MakeSlice(value.Type(), 0, 0))
+		for i := uint64(0); i < length; i++ {
+			element := reflect.New(value.Type().Elem()).Elem()
+			if err := reader.body(element); err != nil {
+				return err
+			}
+			value.Set(reflect.Append(value, element))
+		}
+		return nil
+	default:
+		panic("codec: unsupported field type " + value.Type().String())
+	}
+}
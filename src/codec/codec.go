@@ -0,0 +1,168 @@
+// Package codec implements the encodings of the messages that are sent between kademlia nodes.
+//
+// A message is a header that is the same for every type of message, and a body. The body is a pointer to a struct
+// whose fields are numbered with a kad tag:
+//
+//	type storeRequest struct {
+//		Target [20]byte `kad:"1"`
+//		Data   []byte   `kad:"2"`
+//		Extra  []byte   `kad:"8,optional"`
+//	}
+//
+// The supported field types are []byte, byte, byte arrays and slices of structs that are numbered the same way.
+// The numbers, not the names, identify the fields on the wire. A decoder skips fields with numbers that it doesn't
+// know about and fails if a field that isn't optional is missing, so new (optional) fields can be added to messages
+// and a cluster can be upgraded one node at a time.
+//
+// There are two encodings: TLV, a compact framed format (see tlv.go), and CBOR (RFC 8949, see cbor.go) that can be
+// read by standard tools. Decode detects the encoding of a message, so nodes using different encodings can talk.
+package codec
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Version is the protocol version that this codec writes. Messages from every version since MinVersion can be decoded.
+const Version byte = 1
+const MinVersion byte = 1
+
+// IDLen is the length of the sender ID in bytes
+const IDLen = 20
+
+// MaxHeaderLen and MaxFieldHeaderLen are the largest overhead of the header and of a body field (up to MaxFieldLen
+// bytes long) in any of the encodings. Used to calculate how much data fits in a datagram
+const MaxHeaderLen = 42
+const MaxFieldHeaderLen = 4
+
+var ErrBadMagic = errors.New("codec: not a kademlia message")
+var ErrUnsupportedVersion = errors.New("codec: unsupported protocol version")
+var ErrTruncated = errors.New("codec: message is truncated")
+var ErrTooLarge = errors.New("codec: message is too large")
+var ErrUnknownType = errors.New("codec: unknown message type")
+var ErrMissingField = errors.New("codec: required field is missing")
+var ErrInvalidField = errors.New("codec: field has an invalid value")
+var ErrUnknownEncoding = errors.New("codec: unknown encoding")
+
+// Header is the part of a message that is the same for every type of message
+type Header struct {
+	Version   byte
+	Type      byte
+	Flags     byte
+	RequestID uint32
+	Sender    [IDLen]byte
+}
+
+// NewHeader returns the header of a message of the current protocol version
+func NewHeader(msgType byte, requestID uint32, sender [IDLen]byte) Header {
+	return Header{Version: Version, Type: msgType, RequestID: requestID, Sender: sender}
+}
+
+// Encoding converts messages to bytes and back
+type Encoding interface {
+	// Name is the name used to select the encoding, see ByName
+	Name() string
+	// Encode serializes a message. body is a pointer to a struct with kad tags
+	Encode(header Header, body interface{}) ([]byte, error)
+	// Decode parses a message. newBody returns an empty body for a type of message, or nil if the type is unknown
+	Decode(data []byte, newBody func(msgType byte) interface{}) (Header, interface{}, error)
+}
+
+var TLV Encoding = tlvEncoding{}
+var CBOR Encoding = cborEncoding{}
+
+// ByName returns the encoding with some name ("tlv" or "cbor")
+func ByName(name string) (Encoding, error) {
+	for _, encoding := range []Encoding{TLV, CBOR} {
+		if encoding.Name() == name {
+			return encoding, nil
+		}
+	}
+	return nil, ErrUnknownEncoding
+}
+
+// Detect returns the encoding of a message
+func Detect(data []byte) Encoding {
+	if isCBOR(data) {
+		return CBOR
+	}
+	return TLV
+}
+
+// Decode parses a message in any encoding. Returns the encoding of the message so that a reply can use the same one
+func Decode(data []byte, newBody func(msgType byte) interface{}) (Header, interface{}, Encoding, error) {
+	encoding := Detect(data)
+	header, body, err := encoding.Decode(data, newBody)
+	return header, body, encoding, err
+}
+
+// field is a numbered field of a body struct
+type field struct {
+	number   byte
+	optional bool
+	value    reflect.Value
+}
+
+// fieldsOf returns the numbered fields of a struct value. Panics if body is not a struct or if a tag is invalid,
+// that is a bug in the definition of the message
+func fieldsOf(body reflect.Value) []field {
+	var fields []field
+	for i := 0; i < body.NumField(); i++ {
+		tag, found := body.Type().Field(i).Tag.Lookup("kad")
+		if !found {
+			continue
+		}
+		options := strings.Split(tag, ",")
+		number, err := strconv.ParseUint(options[0], 10, 8)
+		if err != nil {
+			panic("codec: invalid kad tag " + strconv.Quote(tag))
+		}
+		fields = append(fields, field{byte(number), len(options) > 1 && options[1] == "optional", body.Field(i)})
+	}
+	return fields
+}
+
+// isBytes returns true if a value is a []byte or a byte array
+func isBytes(value reflect.Value) bool {
+	kind := value.Kind()
+	return (kind == reflect.Slice || kind == reflect.Array) && value.Type().Elem().Kind() == reflect.Uint8
+}
+
+// bytesOf returns the contents of a []byte or byte array
+func bytesOf(value reflect.Value) []byte {
+	if value.Kind() == reflect.Slice {
+		return value.Bytes()
+	}
+	data := make([]byte, value.Len())
+	reflect.Copy(reflect.ValueOf(data), value)
+	return data
+}
+
+// setBytes copies data into a []byte or byte array. Fails if the length of data doesn't match the array
+func setBytes(value reflect.Value, data []byte) error {
+	if value.Kind() == reflect.Slice {
+		value.SetBytes(append([]byte{}, data...))
+		return nil
+	}
+	if len(data) != value.Len() {
+		return ErrInvalidField
+	}
+	reflect.Copy(value, reflect.ValueOf(data))
+	return nil
+}
+
+// isStructSlice returns true if a value is a slice of structs
+func isStructSlice(value reflect.Value) bool {
+	return value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Struct
+}
+
+// structOf returns the struct a body points to
+func structOf(body interface{}) reflect.Value {
+	value := reflect.ValueOf(body)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		panic("codec: the body of a message must be a pointer to a struct")
+	}
+	return value.Elem()
+}
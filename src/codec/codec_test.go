@@ -0,0 +1,189 @@
+package codec
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+type testElement struct {
+	ID [4]byte `kad:"1"`
+	IP []byte  `kad:"2"`
+}
+
+type testBody struct {
+	Target   [IDLen]byte   `kad:"1"`
+	Data     []byte        `kad:"2"`
+	Reason   byte          `kad:"7"`
+	Elements []testElement `kad:"3,optional"`
+	Extra    []byte        `kad:"9,optional"`
+	Ignored  int
+}
+
+// testBodyV2 is testBody with a field that older nodes don't know about
+type testBodyV2 struct {
+	Target [IDLen]byte `kad:"1"`
+	Data   []byte      `kad:"2"`
+	Reason byte        `kad:"7"`
+	New    []byte      `kad:"20"`
+}
+
+type testEmpty struct{}
+
+func newTestBody(msgType byte) interface{} {
+	switch msgType {
+	case 1:
+		return &testBody{}
+	case 2:
+		return &testEmpty{}
+	}
+	return nil
+}
+
+func TestEncoding_RoundTrip(t *testing.T) {
+	var sender [IDLen]byte
+	copy(sender[:], "01234567890123456789")
+	tests := []struct {
+		name string
+		body interface{}
+	}{
+		{"empty", &testEmpty{}},
+		{"required only", &testBody{Target: sender, Data: []byte{}}},
+		{"all fields", &testBody{Target: sender, Data: []byte("data"), Reason: 200, Extra: []byte{1},
+			Elements: []testElement{{[4]byte{1, 2, 3, 4}, []byte("a")}, {[4]byte{5, 6, 7, 8}, []byte{}}}}},
+		{"large", &testBody{Data: make([]byte, 900)}},
+	}
+	for _, encoding := range []Encoding{TLV, CBOR} {
+		for _, tt := range tests {
+			t.Run(encoding.Name()+" "+tt.name, func(t *testing.T) {
+				msgType := byte(1)
+				if _, empty := tt.body.(*testEmpty); empty {
+					msgType = 2
+				}
+				header := NewHeader(msgType, 0xdeadbeef, sender)
+				header.Flags = 3
+				data, err := encoding.Encode(header, tt.body)
+				if err != nil {
+					t.Fatalf("Encode() = %v, want %v", err, nil)
+				}
+				if Detect(data) != encoding {
+					t.Errorf("Detect() = %v, want %v", Detect(data).Name(), encoding.Name())
+				}
+				gotHeader, gotBody, gotEncoding, err := Decode(data, newTestBody)
+				if err != nil {
+					t.Fatalf("Decode() = %v, want %v", err, nil)
+				}
+				if gotHeader != header || gotEncoding != encoding {
+					t.Errorf("Decode() = %+v, want %+v", gotHeader, header)
+				}
+				if !reflect.DeepEqual(gotBody, tt.body) {
+					t.Errorf("Decode() = %+v, want %+v", gotBody, tt.body)
+				}
+			})
+		}
+	}
+}
+
+// Fields added in later versions are skipped by older nodes
+func TestEncoding_UnknownFields(t *testing.T) {
+	body := &testBodyV2{Data: []byte("data"), Reason: 1, New: []byte("new")}
+	for _, encoding := range []Encoding{TLV, CBOR} {
+		data, err := encoding.Encode(NewHeader(1, 1, [IDLen]byte{}), body)
+		if err != nil {
+			t.Fatalf("Encode() = %v, want %v", err, nil)
+		}
+		_, got, err := encoding.Decode(data, newTestBody)
+		if err != nil {
+			t.Fatalf("%s Decode() = %v, want %v", encoding.Name(), err, nil)
+		}
+		if string(got.(*testBody).Data) != "data" || got.(*testBody).Reason != 1 {
+			t.Errorf("%s Decode() = %+v, want %+v", encoding.Name(), got, body)
+		}
+	}
+}
+
+func TestEncoding_Invalid(t *testing.T) {
+	for _, encoding := range []Encoding{TLV, CBOR} {
+		// A message without the required fields of its type
+		data, _ := encoding.Encode(NewHeader(1, 1, [IDLen]byte{}), &testEmpty{})
+		if _, _, err := encoding.Decode(data, newTestBody); err != ErrMissingField {
+			t.Errorf("%s Decode() = %v, want %v", encoding.Name(), err, ErrMissingField)
+		}
+		// An array field of the wrong length
+		type shortTarget struct {
+			Target [4]byte `kad:"1"`
+			Data   []byte  `kad:"2"`
+			Reason byte    `kad:"7"`
+		}
+		data, _ = encoding.Encode(NewHeader(1, 1, [IDLen]byte{}), &shortTarget{})
+		if _, _, err := encoding.Decode(data, newTestBody); err != ErrInvalidField {
+			t.Errorf("%s Decode() = %v, want %v", encoding.Name(), err, ErrInvalidField)
+		}
+		// A type that the node doesn't know about
+		data, _ = encoding.Encode(NewHeader(3, 1, [IDLen]byte{}), &testEmpty{})
+		if _, _, err := encoding.Decode(data, newTestBody); err != ErrUnknownType {
+			t.Errorf("%s Decode() = %v, want %v", encoding.Name(), err, ErrUnknownType)
+		}
+		// Every truncated message is an error
+		data, _ = encoding.Encode(NewHeader(1, 1, [IDLen]byte{}), &testBody{Data: []byte("data"),
+			Elements: []testElement{{}}})
+		for i := 0; i < len(data); i++ {
+			if _, _, err := encoding.Decode(data[:i], newTestBody); err == nil {
+				t.Errorf("%s Decode() accepted a message truncated to %d bytes", encoding.Name(), i)
+			}
+		}
+	}
+}
+
+// The CBOR encoding must be readable by standard decoders, so check it byte by byte
+func TestCBOR_Encode(t *testing.T) {
+	data, _ := CBOR.Encode(NewHeader(2, 500, [IDLen]byte{}), &testElement{[4]byte{1, 2, 3, 4}, []byte("a")})
+	want := []byte{0xd9, 0xd9, 0xf7, // tag 55799
+		0xa6,       // map with 6 entries
+		0x00, 0x01, // 0: version 1
+		0x01, 0x02, // 1: type 2
+		0x02, 0x00, // 2: flags 0
+		0x03, 0x19, 0x01, 0xf4, // 3: request ID 500
+		0x04, 0x54, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // 4: 20 byte sender
+		0x05, 0xa2, // 5: body map with 2 entries
+		0x01, 0x44, 1, 2, 3, 4, // 1: 4 bytes
+		0x02, 0x41, 'a', // 2: 1 byte
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("Encode() = %x, want %x", data, want)
+	}
+}
+
+func TestByName(t *testing.T) {
+	for _, encoding := range []Encoding{TLV, CBOR} {
+		if got, err := ByName(encoding.Name()); got != encoding || err != nil {
+			t.Errorf("ByName() = %v, want %v", got, encoding)
+		}
+	}
+	if _, err := ByName("json"); err != ErrUnknownEncoding {
+		t.Errorf("ByName() = %v, want %v", err, ErrUnknownEncoding)
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	body := &testBody{Data: []byte("data"), Elements: []testElement{{}}}
+	for _, encoding := range []Encoding{TLV, CBOR} {
+		data, _ := encoding.Encode(NewHeader(1, 1, [IDLen]byte{}), body)
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		header, body, encoding, err := Decode(data, newTestBody)
+		if err != nil {
+			return
+		}
+		// Everything that decodes must encode to the same message again
+		encoded, err := encoding.Encode(header, body)
+		if err != nil {
+			t.Fatalf("Encode() = %v, want %v", err, nil)
+		}
+		againHeader, againBody, err := encoding.Decode(encoded, newTestBody)
+		if err != nil || againHeader != header || !reflect.DeepEqual(againBody, body) {
+			t.Errorf("Decode(Encode()) = %+v, want %+v", againBody, body)
+		}
+	})
+}
@@ -0,0 +1,297 @@
+package codec
+
+import (
+	"encoding/binary"
+	"reflect"
+)
+
+// The TLV encoding frames every message with a fixed size header followed by a body of TLV (tag, length, value)
+// encoded fields:
+//
+// 	[MAGIC (2 bytes), VERSION (1), TYPE (1), FLAGS (1), REQUEST ID (4), SENDER ID (20), BODY LENGTH (2), BODY...]
+// 	BODY: [TAG (1), LENGTH (2), VALUE...] repeated
+//
+// All integers are big endian. The header layout is the same in every protocol version. The tag of a field is its
+// number. A byte is a value of length 1, and every element of a slice of structs is a field of its own with the
+// same tag, whose value is the TLV encoded fields of the element.
+//
+// Messages that don't fit in 16 bit lengths (sent over a stream, not in a datagram) have the FlagLongLengths flag
+// set, and then the body length and the lengths of the fields are 4 bytes instead. The fields of the elements of
+// slices always have 2 byte lengths.
+
+// Magic identifies a TLV frame ("KD")
+const Magic uint16 = 0x4b44
+
+// HeaderLen is the length of the frame header in bytes, FieldHeaderLen is the length of the tag and length of a field
+const HeaderLen = 2 + 1 + 1 + 1 + 4 + IDLen + 2
+const FieldHeaderLen = 1 + 2
+
+// The lengths of the header and the fields of a message with FlagLongLengths
+const LongHeaderLen = HeaderLen + 2
+const LongFieldHeaderLen = FieldHeaderLen + 2
+
+// MaxFieldLen is the largest value a single field can hold without FlagLongLengths
+const MaxFieldLen = 0xffff
+
+// FlagLongLengths is the flag in the header that the codec uses for messages with 4 byte lengths. It is never set
+// in a decoded header, and the other flags are free to be used by the application
+const FlagLongLengths byte = 0x80
+
+// Frame is a message in the TLV encoding, before the fields are decoded into a body
+type Frame struct {
+	Header
+	Fields []Field
+}
+
+// Field is a single TLV encoded value in the body of a frame
+type Field struct {
+	Tag   byte
+	Value []byte
+}
+
+// NewFrame returns a frame of the current protocol version without any fields
+func NewFrame(msgType byte, requestID uint32, sender [IDLen]byte) *Frame {
+	return &Frame{Header: NewHeader(msgType, requestID, sender)}
+}
+
+// Add appends a field to the body of the frame. Returns the frame so that calls can be chained
+func (frame *Frame) Add(tag byte, value []byte) *Frame {
+	frame.Fields = append(frame.Fields, Field{tag, value})
+	return frame
+}
+
+// Get returns the value of the first field with some tag, and false if the frame has no such field
+func (frame *Frame) Get(tag byte) ([]byte, bool) {
+	for _, field := range frame.Fields {
+		if field.Tag == tag {
+			return field.Value, true
+		}
+	}
+	return nil, false
+}
+
+// EncodeFrame serializes a frame. Uses 4 byte lengths if a field or the body doesn't fit in 2 bytes
+func EncodeFrame(frame *Frame) ([]byte, error) {
+	body, err := appendFields(nil, frame.Fields, false)
+	long := err == ErrTooLarge || len(body) > 0xffff
+	if long {
+		body, err = appendFields(nil, frame.Fields, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	headerLen := HeaderLen
+	flags := frame.Flags &^ FlagLongLengths
+	if long {
+		headerLen = LongHeaderLen
+		flags |= FlagLongLengths
+	}
+	data := make([]byte, headerLen, headerLen+len(body))
+	binary.BigEndian.PutUint16(data[0:], Magic)
+	data[2] = frame.Version
+	data[3] = frame.Type
+	data[4] = flags
+	binary.BigEndian.PutUint32(data[5:], frame.RequestID)
+	copy(data[9:9+IDLen], frame.Sender[:])
+	if long {
+		binary.BigEndian.PutUint32(data[9+IDLen:], uint32(len(body)))
+	} else {
+		binary.BigEndian.PutUint16(data[9+IDLen:], uint16(len(body)))
+	}
+	return append(data, body...), nil
+}
+
+// DecodeFrame parses a frame. Bytes after the end of the body are ignored. The values of the fields
+// point into data, so data must not be modified while the frame is in use
+func DecodeFrame(data []byte) (*Frame, error) {
+	if len(data) < HeaderLen {
+		if len(data) >= 2 && binary.BigEndian.Uint16(data) != Magic {
+			return nil, ErrBadMagic
+		}
+		return nil, ErrTruncated
+	}
+	if binary.BigEndian.Uint16(data) != Magic {
+		return nil, ErrBadMagic
+	}
+	frame := &Frame{Header: Header{
+		Version:   data[2],
+		Type:      data[3],
+		Flags:     data[4] &^ FlagLongLengths,
+		RequestID: binary.BigEndian.Uint32(data[5:]),
+	}}
+	if frame.Version < MinVersion {
+		return nil, ErrUnsupportedVersion
+	}
+	copy(frame.Sender[:], data[9:9+IDLen])
+
+	long := data[4]&FlagLongLengths != 0
+	headerLen := HeaderLen
+	bodyLen := uint64(binary.BigEndian.Uint16(data[9+IDLen:]))
+	if long {
+		if len(data) < LongHeaderLen {
+			return nil, ErrTruncated
+		}
+		headerLen = LongHeaderLen
+		bodyLen = uint64(binary.BigEndian.Uint32(data[9+IDLen:]))
+	}
+	if uint64(len(data)-headerLen) < bodyLen {
+		return nil, ErrTruncated
+	}
+	fields, err := decodeFields(data[headerLen:headerLen+int(bodyLen)], long)
+	if err != nil {
+		return nil, err
+	}
+	frame.Fields = fields
+	return frame, nil
+}
+
+// appendFields appends the TLV encoding of some fields to data, with 2 or 4 byte (long) lengths.
+// Fails with ErrTooLarge if a value doesn't fit in its length
+func appendFields(data []byte, fields []Field, long bool) ([]byte, error) {
+	for _, field := range fields {
+		if long {
+			if uint64(len(field.Value)) > 0xffffffff {
+				return nil, ErrTooLarge
+			}
+			data = append(data, field.Tag, 0, 0, 0, 0)
+			binary.BigEndian.PutUint32(data[len(data)-4:], uint32(len(field.Value)))
+		} else {
+			if len(field.Value) > MaxFieldLen {
+				return nil, ErrTooLarge
+			}
+			data = append(data, field.Tag, 0, 0)
+			binary.BigEndian.PutUint16(data[len(data)-2:], uint16(len(field.Value)))
+		}
+		data = append(data, field.Value...)
+	}
+	return data, nil
+}
+
+// decodeFields parses a list of TLV encoded fields with 2 or 4 byte (long) lengths
+func decodeFields(data []byte, long bool) ([]Field, error) {
+	fieldHeaderLen := FieldHeaderLen
+	if long {
+		fieldHeaderLen = LongFieldHeaderLen
+	}
+	var fields []Field
+	for len(data) > 0 {
+		if len(data) < fieldHeaderLen {
+			return nil, ErrTruncated
+		}
+		valueLen := uint64(binary.BigEndian.Uint16(data[1:]))
+		if long {
+			valueLen = uint64(binary.BigEndian.Uint32(data[1:]))
+		}
+		if uint64(len(data)-fieldHeaderLen) < valueLen {
+			return nil, ErrTruncated
+		}
+		end := fieldHeaderLen + int(valueLen)
+		fields = append(fields, Field{data[0], data[fieldHeaderLen:end]})
+		data = data[end:]
+	}
+	return fields, nil
+}
+
+type tlvEncoding struct{}
+
+func (tlvEncoding) Name() string {
+	return "tlv"
+}
+
+func (tlvEncoding) Encode(header Header, body interface{}) ([]byte, error) {
+	fields, err := bodyToFields(structOf(body))
+	if err != nil {
+		return nil, err
+	}
+	return EncodeFrame(&Frame{header, fields})
+}
+
+func (tlvEncoding) Decode(data []byte, newBody func(msgType byte) interface{}) (Header, interface{}, error) {
+	frame, err := DecodeFrame(data)
+	if err != nil {
+		return Header{}, nil, err
+	}
+	body := newBody(frame.Type)
+	if body == nil {
+		return frame.Header, nil, ErrUnknownType
+	}
+	if err := fieldsToBody(frame.Fields, structOf(body)); err != nil {
+		return frame.Header, nil, err
+	}
+	return frame.Header, body, nil
+}
+
+// bodyToFields converts the numbered fields of a struct to TLV fields. Optional fields are left out if they are empty
+func bodyToFields(body reflect.Value) ([]Field, error) {
+	var fields []Field
+	for _, field := range fieldsOf(body) {
+		switch {
+		case isBytes(field.value):
+			if field.optional && field.value.IsZero() {
+				continue
+			}
+			fields = append(fields, Field{field.number, bytesOf(field.value)})
+		case field.value.Kind() == reflect.Uint8:
+			if field.optional && field.value.IsZero() {
+				continue
+			}
+			fields = append(fields, Field{field.number, []byte{byte(field.value.Uint())}})
+		case isStructSlice(field.value):
+			for i := 0; i < field.value.Len(); i++ {
+				elementFields, err := bodyToFields(field.value.Index(i))
+				if err != nil {
+					return nil, err
+				}
+				element, err := appendFields(nil, elementFields, false)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, Field{field.number, element})
+			}
+		default:
+			panic("codec: unsupported field type " + field.value.Type().String())
+		}
+	}
+	return fields, nil
+}
+
+// fieldsToBody sets the numbered fields of a struct from TLV fields. Unknown fields are skipped
+func fieldsToBody(fields []Field, body reflect.Value) error {
+	for _, field := range fieldsOf(body) {
+		found := false
+		for _, received := range fields {
+			if received.Tag != field.number {
+				continue
+			}
+			found = true
+			switch {
+			case isBytes(field.value):
+				if err := setBytes(field.value, received.Value); err != nil {
+					return err
+				}
+			case field.value.Kind() == reflect.Uint8:
+				if len(received.Value) != 1 {
+					return ErrInvalidField
+				}
+				field.value.SetUint(uint64(received.Value[0]))
+			case isStructSlice(field.value):
+				elementFields, err := decodeFields(received.Value, false)
+				if err != nil {
+					return err
+				}
+				element := reflect.New(field.value.Type().Elem()).Elem()
+				if err := fieldsToBody(elementFields, element); err != nil {
+					return err
+				}
+				field.value.Set(reflect.Append(field.value, element))
+			default:
+				panic("codec: unsupported field type " + field.value.Type().String())
+			}
+		}
+		if !found && !field.optional {
+			return ErrMissingField
+		}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package codec
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeDecodeFrame(t *testing.T) {
+	var sender [IDLen]byte
+	copy(sender[:], "01234567890123456789")
+	tests := []struct {
+		name  string
+		frame *Frame
+	}{
+		{"no fields", NewFrame(0, 1, sender)},
+		{"fields", NewFrame(2, 0xdeadbeef, sender).Add(1, []byte("target")).Add(2, []byte{}).Add(2, []byte("data"))},
+		{"flags", &Frame{Header: Header{Version: Version, Type: 255, Flags: 0x41, RequestID: 7, Sender: sender},
+			Fields: []Field{{255, make([]byte, MaxFieldLen-FieldHeaderLen)}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := EncodeFrame(tt.frame)
+			if err != nil {
+				t.Fatalf("EncodeFrame() = %v, want %v", err, nil)
+			}
+			got, err := DecodeFrame(data)
+			if err != nil {
+				t.Fatalf("DecodeFrame() = %v, want %v", err, nil)
+			}
+			if got.Version != tt.frame.Version || got.Type != tt.frame.Type || got.Flags != tt.frame.Flags ||
+				got.RequestID != tt.frame.RequestID || got.Sender != tt.frame.Sender {
+				t.Errorf("DecodeFrame() = %+v, want %+v", got, tt.frame)
+			}
+			if len(got.Fields) != len(tt.frame.Fields) {
+				t.Fatalf("DecodeFrame() returned %d fields, want %d", len(got.Fields), len(tt.frame.Fields))
+			}
+			for i := range got.Fields {
+				if got.Fields[i].Tag != tt.frame.Fields[i].Tag || !bytes.Equal(got.Fields[i].Value, tt.frame.Fields[i].Value) {
+					t.Errorf("DecodeFrame() field %d = %v, want %v", i, got.Fields[i], tt.frame.Fields[i])
+				}
+			}
+			// Encoding the decoded frame gives the same bytes
+			if again, _ := EncodeFrame(got); !bytes.Equal(again, data) {
+				t.Errorf("EncodeFrame(DecodeFrame()) = %v, want %v", again, data)
+			}
+		})
+	}
+}
+
+func TestFrame_Get(t *testing.T) {
+	frame := NewFrame(0, 0, [IDLen]byte{}).Add(1, []byte("first")).Add(1, []byte("second")).Add(3, nil)
+	if value, found := frame.Get(1); !found || string(value) != "first" {
+		t.Errorf("Get() = %v, want %v", string(value), "first")
+	}
+	if _, found := frame.Get(3); !found {
+		t.Errorf("Get() did not find an empty field")
+	}
+	if _, found := frame.Get(2); found {
+		t.Errorf("Get() found a field that doesn't exist")
+	}
+}
+
+// Frames from newer versions can be read as long as the header is the same, and trailing bytes are ignored
+func TestDecodeFrame_Compatibility(t *testing.T) {
+	frame := NewFrame(4, 1, [IDLen]byte{})
+	frame.Version = Version + 1
+	data, _ := EncodeFrame(frame.Add(1, []byte("known")).Add(200, []byte("added in a later version")))
+	got, err := DecodeFrame(append(data, 0, 0, 0))
+	if err != nil {
+		t.Fatalf("DecodeFrame() = %v, want %v", err, nil)
+	}
+	if got.Version != Version+1 || len(got.Fields) != 2 {
+		t.Errorf("DecodeFrame() = %+v, want %+v", got, frame)
+	}
+	if value, _ := got.Get(1); string(value) != "known" {
+		t.Errorf("Get() = %v, want %v", string(value), "known")
+	}
+}
+
+func TestDecodeFrame_Invalid(t *testing.T) {
+	valid, _ := EncodeFrame(NewFrame(2, 1, [IDLen]byte{}).Add(1, []byte("value")))
+	oldVersion := append([]byte{}, valid...)
+	oldVersion[2] = MinVersion - 1
+	badMagic := append([]byte{}, valid...)
+	badMagic[0] = 'X'
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"empty", []byte{}, ErrTruncated},
+		{"short header", valid[:HeaderLen-1], ErrTruncated},
+		{"truncated body", valid[:len(valid)-1], ErrTruncated},
+		{"truncated field header", append(append([]byte{}, valid[:HeaderLen-2]...), 0, 2, 1, 0), ErrTruncated},
+		{"bad magic", badMagic, ErrBadMagic},
+		{"old version", oldVersion, ErrUnsupportedVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeFrame(tt.data); err != tt.want {
+				t.Errorf("DecodeFrame() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// Frames that don't fit in 2 byte lengths switch to 4 byte lengths
+func TestEncodeFrame_LongLengths(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame *Frame
+		long  bool
+	}{
+		{"short", NewFrame(2, 1, [IDLen]byte{}).Add(1, make([]byte, MaxFieldLen-FieldHeaderLen)), false},
+		{"large field", NewFrame(2, 1, [IDLen]byte{}).Add(1, make([]byte, MaxFieldLen+1)), true},
+		{"large body", NewFrame(2, 1, [IDLen]byte{}).Add(1, make([]byte, MaxFieldLen)).Add(2, nil), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.frame.Flags = 1
+			data, err := EncodeFrame(tt.frame)
+			if err != nil {
+				t.Fatalf("EncodeFrame() = %v, want %v", err, nil)
+			}
+			if long := data[4]&FlagLongLengths != 0; long != tt.long {
+				t.Errorf("EncodeFrame() long lengths = %v, want %v", long, tt.long)
+			}
+			got, err := DecodeFrame(data)
+			if err != nil {
+				t.Fatalf("DecodeFrame() = %v, want %v", err, nil)
+			}
+			if got.Flags != 1 || !reflect.DeepEqual(normalize(got), normalize(tt.frame)) {
+				t.Errorf("DecodeFrame() = %+v, want %+v", got.Header, tt.frame.Header)
+			}
+			for i := 1; i < len(data); i += 1 + i/2 {
+				if _, err := DecodeFrame(data[:i]); err == nil {
+					t.Errorf("DecodeFrame() accepted a frame truncated to %d bytes", i)
+				}
+			}
+		})
+	}
+}
+
+func FuzzDecodeFrame(f *testing.F) {
+	valid, _ := EncodeFrame(NewFrame(2, 1, [IDLen]byte{}).Add(1, []byte("value")).Add(2, nil))
+	f.Add(valid)
+	long := append([]byte{}, valid...)
+	long[4] |= FlagLongLengths
+	f.Add(long)
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		frame, err := DecodeFrame(data)
+		if err != nil {
+			return
+		}
+		// Everything that decodes must encode to the same frame again
+		encoded, err := EncodeFrame(frame)
+		if err != nil {
+			t.Fatalf("EncodeFrame() = %v, want %v", err, nil)
+		}
+		again, err := DecodeFrame(encoded)
+		if err != nil || !reflect.DeepEqual(normalize(again), normalize(frame)) {
+			t.Errorf("DecodeFrame(EncodeFrame()) = %+v, want %+v", again, frame)
+		}
+	})
+}
+
+// normalize replaces empty values with nil so that frames can be compared with reflect.DeepEqual
+func normalize(frame *Frame) *Frame {
+	normalized := *frame
+	normalized.Fields = nil
+	for _, field := range frame.Fields {
+		if len(field.Value) == 0 {
+			field.Value = nil
+		}
+		normalized.Fields = append(normalized.Fields, field)
+	}
+	return &normalized
+}
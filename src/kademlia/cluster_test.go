@@ -0,0 +1,294 @@
+package kademlia
+
+import (
+	"context"
+	"d7024e/clock"
+	"d7024e/routing"
+	"d7024e/storage"
+	"d7024e/transport"
+	"math/bits"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testCluster is a set of nodes in one simulator that are joined into one overlay, with assertions on the
+// state of the whole cluster. The test takes part in the simulator with ctx, which it must use for every request
+// it makes the nodes send, so that virtual time only advances while it waits for them
+type testCluster struct {
+	t       *testing.T
+	sim     *transport.Simulator
+	ctx     context.Context
+	finish  func()        // Ends the part of the test in the simulator
+	clock   *clock.Manual // The clock of every node
+	nodes   []*Network
+	stopped []bool
+	done    chan bool
+}
+
+// newTestCluster starts n listening nodes in a simulator with some seed and joins them one by one via the
+// first node (with a few retries like main, the PING can get lost). Call stop when the test is done
+func newTestCluster(t *testing.T, seed int64, n int, link transport.LinkConfig) *testCluster {
+	cluster := &testCluster{t: t, sim: transport.NewSimulator(seed), clock: clock.NewManual(time.Unix(0, 0)),
+		nodes: make([]*Network, n), stopped: make([]bool, n),
+		done: make(chan bool)}
+	cluster.sim.SetDefaultLink(link)
+	cluster.ctx, cluster.finish = cluster.sim.Participate(context.Background())
+	for i := range cluster.nodes {
+		ip := net.IPv4(10, 0, byte(i>>8), byte(i))
+		network := NewNetwork(&ip, cluster.sim.NewTransport(ip.String(), KAD_PORT, MAX_PACKET_SIZE,
+			TIMEOUT*time.Millisecond, false), cluster.sim.NewTransport(ip.String(), STREAM_PORT,
+			transport.MAX_STREAM_MESSAGE_SIZE, STREAM_TIMEOUT*time.Millisecond, true))
+		network.SetClock(cluster.clock)
+		cluster.nodes[i] = &network
+		go func() {
+			network.Listen()
+			cluster.done <- true
+		}()
+	}
+	cluster.sim.Wait(cluster.ctx)
+	for i := 1; i < n; i++ {
+		first := cluster.nodes[0].routingTable.Me()
+		err := cluster.nodes[i].JoinContext(cluster.ctx, first.ID, first.Address)
+		for retries := 0; err != nil && retries < 5; retries++ {
+			err = cluster.nodes[i].JoinContext(cluster.ctx, first.ID, first.Address)
+		}
+		if err != nil {
+			t.Errorf("Join() = %v, want %v", err, nil)
+		}
+	}
+	return cluster
+}
+
+// stop shuts down all nodes that are still running and the simulator, once the messages on their way arrived
+func (cluster *testCluster) stop() {
+	cluster.sim.Wait(cluster.ctx)
+	for i := range cluster.nodes {
+		cluster.leave(i)
+	}
+	for range cluster.nodes {
+		<-cluster.done
+	}
+	cluster.finish()
+	cluster.sim.Stop()
+}
+
+// leave shuts down a node without telling anyone
+func (cluster *testCluster) leave(i int) {
+	if !cluster.stopped[i] {
+		cluster.stopped[i] = true
+		cluster.nodes[i].shutdown()
+	}
+}
+
+// running returns the indexes of the nodes that haven't left
+func (cluster *testCluster) running() []int {
+	var result []int
+	for i := range cluster.nodes {
+		if !cluster.stopped[i] {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// store stores some data from a node and checks that at least one node accepted it
+func (cluster *testCluster) store(from int, data string) *routing.KademliaID {
+	hash := routing.NewKademliaIDFromData(data)
+	if replicas, _ := cluster.nodes[from].StoreContext(cluster.ctx, []byte(data), hash); replicas == 0 {
+		cluster.t.Errorf("Store() = %v, want more than %v", replicas, 0)
+	}
+	return hash
+}
+
+// elapse advances the clock by some milliseconds, with a refresh round (see Remember) on every running node
+// every REMEMBER_UPDATE_FREQ and expired data deleted (see UpdateTTL) after every step
+func (cluster *testCluster) elapse(elapsed int) {
+	for elapsed > 0 {
+		step := REMEMBER_UPDATE_FREQ
+		if elapsed < step {
+			step = elapsed
+		}
+		for _, i := range cluster.running() {
+			cluster.nodes[i].refreshAll()
+		}
+		cluster.sim.Wait(cluster.ctx)
+		cluster.clock.Advance(time.Duration(step) * time.Millisecond)
+		for _, i := range cluster.running() {
+			cluster.nodes[i].localNode.Expire()
+		}
+		elapsed -= step
+	}
+}
+
+// assertRetrievable checks that every running node finds the data of every hash
+func (cluster *testCluster) assertRetrievable(data map[routing.KademliaID]string) {
+	for _, i := range cluster.running() {
+		for hash, value := range data {
+			hash := hash
+			if result, _, _ := cluster.nodes[i].DataLookupContext(cluster.ctx, &hash); string(result) != value {
+				cluster.t.Errorf("DataLookup() on node %d = %v, want %v", i, string(result), value)
+			}
+		}
+	}
+}
+
+// countStored returns the number of running nodes that store the data of a hash
+func (cluster *testCluster) countStored(hash *routing.KademliaID) int {
+	count := 0
+	for _, i := range cluster.running() {
+		if cluster.nodes[i].localNode.LookupData(hash) != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// assertGone checks that no running node stores the data of a hash anymore
+func (cluster *testCluster) assertGone(hash *routing.KademliaID) {
+	for _, i := range cluster.running() {
+		if data := cluster.nodes[i].localNode.LookupData(hash); data != nil {
+			cluster.t.Errorf("LookupData() on node %d = %v, want %v", i, string(data), nil)
+		}
+	}
+}
+
+// assertConverged checks that the routing table of every running node contains its k closest running nodes
+func (cluster *testCluster) assertConverged() {
+	for _, i := range cluster.running() {
+		me := cluster.nodes[i].routingTable.Me()
+		var candidates routing.ContactCandidates
+		for _, j := range cluster.running() {
+			if j != i {
+				contact := cluster.nodes[j].routingTable.Me()
+				contact.CalcDistance(me.ID)
+				candidates.AppendContact(contact)
+			}
+		}
+		candidates.Sort()
+		closest := candidates.Len()
+		if closest > k {
+			closest = k
+		}
+		known := cluster.nodes[i].routingTable.FindClosestContacts(me.ID, k+1)
+		for _, want := range candidates.GetContacts(closest) {
+			found := false
+			for _, contact := range known {
+				found = found || contact.ID.Equals(want.ID)
+			}
+			if !found {
+				cluster.t.Errorf("routing table of node %d is missing %v", i, want.ID)
+			}
+		}
+	}
+}
+
+// assertLookupHops runs a node lookup from a node and checks that it reaches the target in at most maxHops rounds,
+// i.e. that the target is queried or returned by the round maxHops. The rounds after that only confirm the closest
+// contacts, so they are not counted
+func (cluster *testCluster) assertLookupHops(from int, target *routing.KademliaID, maxHops int) {
+	contacts, trace, err := cluster.nodes[from].NodeLookupTrace(cluster.ctx, target)
+	if err != nil || len(contacts) == 0 || !contacts[0].ID.Equals(target) {
+		cluster.t.Errorf("NodeLookupTrace() from node %d did not find %v, error = %v", from, target, err)
+		return
+	}
+	if hops := lookupHops(trace); hops == 0 || hops > maxHops {
+		cluster.t.Errorf("NodeLookupTrace() from node %d reached the target in round %d, want 1 to %d", from, hops,
+			maxHops)
+	}
+}
+
+// lookupHops returns the number of the first round of a lookup in which the target was queried or returned, 0 if
+// it never was
+func lookupHops(trace *LookupTrace) int {
+	for i, round := range trace.Rounds {
+		for _, query := range round.Queries {
+			if query.Contact.ID.Equals(trace.Target) {
+				return i + 1
+			}
+			for _, contact := range query.Returned {
+				if contact.ID.Equals(trace.Target) {
+					return i + 1
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// maxLookupHops is the number of rounds a lookup needs at most to reach any node of a network of n nodes:
+// every round at least halves the distance to the target, log2(n) rounds
+func maxLookupHops(n int) int {
+	return bits.Len(uint(n - 1))
+}
+
+func TestCluster_Join(t *testing.T) {
+	cluster := newTestCluster(t, 1, 50, transport.LinkConfig{Latency: time.Millisecond})
+	defer cluster.stop()
+
+	// Every node refreshes its neighbourhood once, like after a bucket refresh
+	for _, node := range cluster.nodes {
+		node.NodeLookupContext(cluster.ctx, node.routingTable.Me().ID)
+	}
+	cluster.assertConverged()
+	for i := 0; i < 5; i++ {
+		cluster.assertLookupHops(i*10, cluster.nodes[49-i*10].routingTable.Me().ID, maxLookupHops(len(cluster.nodes)))
+	}
+}
+
+// The simulator only advances time when every node is idle, so the same seed gives the same run
+func TestCluster_Deterministic(t *testing.T) {
+	run := func() (time.Duration, int) {
+		cluster := newTestCluster(t, 5, 20, transport.LinkConfig{Latency: time.Millisecond,
+			Jitter: 3 * time.Millisecond, Loss: 0.1, Duplication: 0.1})
+		defer cluster.stop()
+		replicas, _ := cluster.nodes[3].StoreContext(cluster.ctx, []byte("deterministic"),
+			routing.NewKademliaIDFromData("deterministic"))
+		cluster.sim.Wait(cluster.ctx)
+		return cluster.sim.Now(), replicas
+	}
+	now1, replicas1 := run()
+	now2, replicas2 := run()
+	if now1 != now2 || replicas1 != replicas2 {
+		t.Errorf("run() = %v, %v, want %v, %v", now2, replicas2, now1, replicas1)
+	}
+}
+
+func TestCluster_StoreAndGet(t *testing.T) {
+	cluster := newTestCluster(t, 2, 50, transport.LinkConfig{Latency: time.Millisecond, Jitter: 2 * time.Millisecond})
+	defer cluster.stop()
+
+	data := make(map[routing.KademliaID]string)
+	for i := 0; i < 10; i++ {
+		value := "value " + strconv.Itoa(i)
+		data[*cluster.store(i*5, value)] = value
+	}
+	cluster.assertRetrievable(data)
+}
+
+// Data lives for as long as its publisher refreshes it, and expires TIME_TO_LIVE after it is forgotten
+func TestCluster_ExpiryAndForget(t *testing.T) {
+	cluster := newTestCluster(t, 3, 30, transport.LinkConfig{Latency: time.Millisecond})
+	defer cluster.stop()
+
+	kept := cluster.store(1, "kept")
+	forgotten := cluster.store(2, "forgotten")
+	cluster.elapse(2 * storage.TIME_TO_LIVE)
+	cluster.assertRetrievable(map[routing.KademliaID]string{*kept: "kept", *forgotten: "forgotten"})
+
+	cluster.nodes[2].localNode.Forget(forgotten)
+	// The replicas were last refreshed REMEMBER_UPDATE_FREQ ago
+	cluster.elapse(storage.TIME_TO_LIVE - REMEMBER_UPDATE_FREQ - 1)
+	if copies := cluster.countStored(forgotten); copies != len(cluster.nodes) {
+		t.Errorf("countStored() = %v, want %v", copies, len(cluster.nodes))
+	}
+	cluster.elapse(1)
+	if copies := cluster.countStored(forgotten); copies == 0 || copies == len(cluster.nodes) {
+		t.Errorf("countStored() = %v, want only the cached copies", copies)
+	}
+	// The copies that the lookups above cached are the last to expire
+	cluster.elapse(REMEMBER_UPDATE_FREQ)
+	cluster.assertGone(forgotten)
+	cluster.assertRetrievable(map[routing.KademliaID]string{*kept: "kept"})
+}
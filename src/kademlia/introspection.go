@@ -0,0 +1,131 @@
+package kademlia
+
+import (
+	"d7024e/routing"
+	"d7024e/storage"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Read-only snapshots of the state of a node, for debugging a node from a frontend (the admin HTTP API and the
+// CLI of the kademlia binary). They are copies, so they can be used while the node keeps running.
+
+// NodeInfo describes the node itself
+type NodeInfo struct {
+	ID             *routing.KademliaID
+	Address        string
+	Streams        bool // The node accepts streams
+	Started        bool
+	HTTPAddress    string // Empty if the node doesn't serve HTTP
+	WriteQuorum    int
+	RequestTimeout time.Duration
+	Contacts       int // Number of contacts in the routing table
+	Storage        storage.Stats
+}
+
+// BucketInfo is a non-empty bucket of the routing table
+type BucketInfo struct {
+	Index    int
+	Contacts []routing.Contact // Most recently seen first
+}
+
+// LookupInfo describes a lookup that is running
+type LookupInfo struct {
+	ID        uint64
+	Kind      string // "node" or "data"
+	Target    *routing.KademliaID
+	Started   time.Time
+	Rounds    int // Rounds of RPCs sent so far
+	Visited   int // Nodes that answered
+	Unvisited int // Nodes that are known but haven't been asked yet
+}
+
+// lookupTracker keeps the lookups that are running
+type lookupTracker struct {
+	mutex   sync.Mutex
+	lastID  uint64
+	lookups map[uint64]*LookupInfo
+}
+
+func newLookupTracker() *lookupTracker {
+	return &lookupTracker{lookups: make(map[uint64]*LookupInfo)}
+}
+
+// start adds a lookup, and returns the ID that it is updated and finished with
+func (tracker *lookupTracker) start(kind string, target *routing.KademliaID, started time.Time) uint64 {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	tracker.lastID++
+	tracker.lookups[tracker.lastID] = &LookupInfo{ID: tracker.lastID, Kind: kind, Target: target, Started: started}
+	return tracker.lastID
+}
+
+// update sets the progress of a lookup at the start of a round
+func (tracker *lookupTracker) update(id uint64, rounds int, visited int, unvisited int) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	if lookup := tracker.lookups[id]; lookup != nil {
+		lookup.Rounds, lookup.Visited, lookup.Unvisited = rounds, visited, unvisited
+	}
+}
+
+// finish removes a lookup
+func (tracker *lookupTracker) finish(id uint64) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	delete(tracker.lookups, id)
+}
+
+// Info describes the node
+func (network *Network) Info() NodeInfo {
+	me := network.routingTable.Me()
+	contacts := 0
+	for _, size := range network.routingTable.BucketSizes() {
+		contacts += size
+	}
+	return NodeInfo{me.ID, me.Address, network.streams != nil, network.lifecycle != nil, network.HTTPAddress(),
+		network.writeQuorum, network.requestTimeout, contacts, network.localNode.Stats()}
+}
+
+// Buckets returns the contacts in the non-empty buckets of the routing table
+func (network *Network) Buckets() []BucketInfo {
+	var buckets []BucketInfo
+	for index, contacts := range network.routingTable.Buckets() {
+		if len(contacts) > 0 {
+			buckets = append(buckets, BucketInfo{index, contacts})
+		}
+	}
+	return buckets
+}
+
+// ClosestContacts returns the count closest contacts to some ID in the routing table, closest first, without
+// asking other nodes (see NodeLookup)
+func (network *Network) ClosestContacts(target *routing.KademliaID, count int) []routing.Contact {
+	return network.routingTable.FindClosestContacts(target, count)
+}
+
+// StoredItems returns the data objects and records this node stores, sorted by hash
+func (network *Network) StoredItems() []storage.Item {
+	return network.localNode.Items()
+}
+
+// Remembered returns the hashes of the data this node keeps refreshing (see Put and Forget), with the contacts
+// that the data is refreshed on
+func (network *Network) Remembered() map[routing.KademliaID][]routing.Contact {
+	return network.localNode.RefreshContacts()
+}
+
+// Lookups returns the lookups that are running, oldest first
+func (network *Network) Lookups() []LookupInfo {
+	network.lookups.mutex.Lock()
+	defer network.lookups.mutex.Unlock()
+	lookups := make([]LookupInfo, 0, len(network.lookups.lookups))
+	for _, lookup := range network.lookups.lookups {
+		lookups = append(lookups, *lookup)
+	}
+	sort.Slice(lookups, func(i, j int) bool {
+		return lookups[i].ID < lookups[j].ID
+	})
+	return lookups
+}
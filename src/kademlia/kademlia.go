@@ -0,0 +1,234 @@
+// Package kademlia is a Kademlia node that can be embedded in other programs. A node is created with New, started
+// with Start, joins a network with Join, stores and retrieves immutable data with Put and Get, and is stopped
+// with Stop. The routing table, the local storage and the transports it is built from live in the routing,
+// storage and transport packages.
+package kademlia
+
+import (
+	"context"
+	"crypto/ed25519"
+	"d7024e/clock"
+	"d7024e/codec"
+	"d7024e/routing"
+	"d7024e/storage"
+	"d7024e/transport"
+	"encoding/hex"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Config is everything New needs to create a node. DefaultConfig returns the configuration the kademlia binary
+// runs with
+type Config struct {
+	// The IP address of the node. Its ID is the hash of the address
+	IP net.IP
+
+	// The transport for datagrams, and the one for streams (nil if this node doesn't accept streams)
+	Transport transport.Transport
+	Streams   transport.Transport
+
+	// Minimum number of replicas that must acknowledge a STORE for a put to count as successful
+	WriteQuorum int
+
+	// Longest time the network operations of a CLI command or HTTP request may take (see RequestContext)
+	RequestTimeout time.Duration
+
+	// The encoding of the requests the node sends
+	Encoding codec.Encoding
+
+	// Storage quota of the node. A value of 0 or less means unlimited
+	MaxStorageBytes int
+	MaxStorageItems int
+
+	// The clock that TTLs, refreshes and timeouts are measured with. Transports keep their own clock
+	Clock clock.Clock
+
+	// The file the key pair that signs the records of the node is kept in (see LoadIdentity). If it is empty the
+	// node signs with a new key pair every time it is created, so its records get a new key after a restart
+	IdentityFile string
+}
+
+// ErrNoData is returned by Get when no node in the network stores data at the hash
+var ErrNoData = errors.New("no data is stored at the hash in the network")
+
+// ErrContentTypeTooLong is returned by PutObject when the content type is longer than MAX_CONTENT_TYPE_LEN
+var ErrContentTypeTooLong = errors.New("content type is too long")
+
+// Object is a data object with the content type (MIME type) it was put with. The content type is metadata, the hash
+// of an object is the hash of its data
+type Object struct {
+	Data        []byte
+	ContentType string // Empty if the object was put without one
+}
+
+// QuorumError is returned by Put when fewer nodes than the write quorum stored the data
+type QuorumError struct {
+	Replicas int
+	Quorum   int
+}
+
+func (err *QuorumError) Error() string {
+	return "stored on " + strconv.Itoa(err.Replicas) + " nodes, write quorum is " + strconv.Itoa(err.Quorum)
+}
+
+// DefaultConfig returns the configuration of a node with some IP address that talks UDP on KAD_PORT and TCP on
+// STREAM_PORT
+func DefaultConfig(ip net.IP) Config {
+	return Config{
+		IP:              ip,
+		Transport:       transport.NewUDPTransport(KAD_PORT, TIMEOUT*time.Millisecond),
+		Streams:         transport.NewTCPTransport(STREAM_PORT, STREAM_TIMEOUT*time.Millisecond),
+		WriteQuorum:     DEFAULT_WRITE_QUORUM,
+		RequestTimeout:  DEFAULT_REQUEST_TIMEOUT * time.Millisecond,
+		Encoding:        codec.TLV,
+		MaxStorageBytes: storage.DEFAULT_MAX_STORAGE_BYTES,
+		MaxStorageItems: storage.DEFAULT_MAX_STORAGE_ITEMS,
+		Clock:           clock.Real,
+	}
+}
+
+// New creates a node from a configuration. The node does nothing until it is started (see Start)
+func New(config Config) (*Network, error) {
+	if config.IP == nil {
+		return nil, errors.New("the config has no IP address")
+	}
+	if config.Transport == nil {
+		return nil, errors.New("the config has no transport")
+	}
+	if config.WriteQuorum < 1 {
+		return nil, errors.New("the write quorum must be at least 1")
+	}
+	network := NewNetwork(&config.IP, config.Transport, config.Streams)
+	network.writeQuorum = config.WriteQuorum
+	if config.RequestTimeout > 0 {
+		network.requestTimeout = config.RequestTimeout
+	}
+	if config.Encoding != nil {
+		network.encoding = config.Encoding
+	}
+	network.localNode.SetQuota(config.MaxStorageBytes, config.MaxStorageItems)
+	if config.Clock != nil {
+		network.SetClock(config.Clock)
+	}
+	if config.IdentityFile != "" {
+		identity, err := LoadIdentity(config.IdentityFile)
+		if err != nil {
+			return nil, err
+		}
+		network.identity = identity
+	}
+	return &network, nil
+}
+
+// LoadIdentity reads the key pair of a node from a file, which holds the hex encoded seed of the private key.
+// If the file doesn't exist a new key pair is generated and written to it, readable only by the user
+func LoadIdentity(path string) (ed25519.PrivateKey, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		_, identity, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			return nil, err
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, err
+		}
+		_, err = file.WriteString(hex.EncodeToString(identity.Seed()) + "\n")
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		return identity, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	seed, err := hex.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, errors.New(path + " does not hold the seed of an ed25519 key")
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// data returns the data of an object, nil if there is no object
+func (object *Object) data() []byte {
+	if object == nil {
+		return nil
+	}
+	return object.Data
+}
+
+// Put stores data in the k closest nodes to its hash and keeps refreshing it until it is forgotten (see Forget).
+// Returns the hash of the data, and a *QuorumError if fewer nodes than the write quorum stored it
+func (network *Network) Put(ctx context.Context, data []byte) (*routing.KademliaID, error) {
+	hash, _, err := network.PutObject(ctx, &Object{Data: data})
+	return hash, err
+}
+
+// PutObject is Put for data with a content type, which is stored with the data. Also returns the number of nodes
+// that stored the object, including this one
+func (network *Network) PutObject(ctx context.Context, object *Object) (*routing.KademliaID, int, error) {
+	hash := routing.NewKademliaIDFromData(string(object.Data))
+	if len(object.ContentType) > MAX_CONTENT_TYPE_LEN {
+		return hash, 0, ErrContentTypeTooLong
+	}
+	replicas, err := network.storeObject(ctx, object, hash)
+	if replicas >= network.writeQuorum {
+		return hash, replicas, nil
+	}
+	if err != nil {
+		return hash, replicas, err
+	}
+	return hash, replicas, &QuorumError{replicas, network.writeQuorum}
+}
+
+// Get looks up the data stored at some hash. Returns ErrNoData if no node stores it, or the error of the context
+// if it is done before the data is found
+func (network *Network) Get(ctx context.Context, hash *routing.KademliaID) ([]byte, error) {
+	object, _, err := network.ObjectLookup(ctx, hash)
+	if object != nil {
+		return object.Data, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, ErrNoData
+}
+
+// Forget stops refreshing the data stored at some hash, which means that it will eventually be deleted by
+// the nodes that store it
+func (network *Network) Forget(hash *routing.KademliaID) {
+	network.localNode.Forget(hash)
+}
+
+// ID returns the kademlia ID of the node
+func (network *Network) ID() *routing.KademliaID {
+	return network.routingTable.Me().ID
+}
+
+// WriteQuorum returns the minimum number of nodes that must store some data for a put to be successful
+func (network *Network) WriteQuorum() int {
+	return network.writeQuorum
+}
+
+// RecordSequence returns the sequence number of the last record this node published (see Publish)
+func (network *Network) RecordSequence() uint64 {
+	return atomic.LoadUint64(&network.recordSequence)
+}
+
+// SetWriteQuorum sets the minimum number of nodes that must store some data for a put to be successful
+func (network *Network) SetWriteQuorum(writeQuorum int) {
+	network.writeQuorum = writeQuorum
+}
+
+// SetRequestTimeout sets the longest time the network operations of a CLI command or HTTP request may take
+func (network *Network) SetRequestTimeout(timeout time.Duration) {
+	network.requestTimeout = timeout
+}
@@ -0,0 +1,314 @@
+package kademlia
+
+import (
+	"context"
+	"d7024e/routing"
+	"d7024e/transport"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newConfig returns the configuration of a node on an in-memory network
+func newConfig(fake *transport.MemoryNetwork, ip net.IP) Config {
+	config := DefaultConfig(ip)
+	config.Transport = fake.NewTransport(ip.String(), KAD_PORT, MAX_PACKET_SIZE, TIMEOUT*time.Millisecond)
+	config.Streams = fake.NewTransport(ip.String(), STREAM_PORT, transport.MAX_STREAM_MESSAGE_SIZE,
+		STREAM_TIMEOUT*time.Millisecond)
+	return config
+}
+
+// New rejects configurations that a node can't run with
+func TestNew(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	noIP := newConfig(fake, net.ParseIP("0.0.0.0"))
+	noIP.IP = nil
+	noTransport := newConfig(fake, net.ParseIP("0.0.0.0"))
+	noTransport.Transport = nil
+	noQuorum := newConfig(fake, net.ParseIP("0.0.0.0"))
+	noQuorum.WriteQuorum = 0
+
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{"Default", newConfig(fake, net.ParseIP("0.0.0.0")), false},
+		{"No IP", noIP, true},
+		{"No transport", noTransport, true},
+		{"No write quorum", noQuorum, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			network, err := New(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && !network.ID().Equals(routing.NewKademliaIDFromIP(&tt.config.IP)) {
+				t.Errorf("ID() = %v, want %v", network.ID(), routing.NewKademliaIDFromIP(&tt.config.IP))
+			}
+		})
+	}
+}
+
+// A node signs its records with the same key pair after it is restarted with the same identity file
+func TestNew_IdentityFile(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	config := newConfig(fake, net.ParseIP("0.0.0.0"))
+	config.IdentityFile = filepath.Join(t.TempDir(), "identity")
+
+	first, err := New(config)
+	if err != nil {
+		t.Fatalf("New() = %v, want %v", err, nil)
+	}
+	second, err := New(config)
+	if err != nil {
+		t.Fatalf("New() = %v, want %v", err, nil)
+	}
+	if !first.identity.Equal(second.identity) {
+		t.Errorf("New() = %v, want %v", second.identity, first.identity)
+	}
+	if info, err := os.Stat(config.IdentityFile); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Stat() = %v, %v, want mode %v", info, err, os.FileMode(0600))
+	}
+
+	os.WriteFile(config.IdentityFile, []byte("not a key\n"), 0600)
+	if _, err := New(config); err == nil {
+		t.Errorf("New() = %v, want an error", err)
+	}
+}
+
+// Data that is put on one node can be read from another one, and a put fails if the write quorum isn't reached
+func TestNetwork_PutGet(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	ip1 := net.ParseIP("0.0.0.0")
+	ip2 := net.ParseIP("0.0.0.1")
+	config := newConfig(fake, ip2)
+	config.WriteQuorum = 3
+	net1, _ := New(newConfig(fake, ip1))
+	net2, _ := New(config)
+	net1.Start()
+	net2.Start()
+	defer net1.Stop()
+	defer net2.Stop()
+	time.Sleep(10 * time.Millisecond)
+	if err := net2.Join(net1.ID(), ip1.String()); err != nil {
+		t.Fatalf("Join() = %v, want %v", err, nil)
+	}
+
+	ctx := context.Background()
+	hash, err := net1.Put(ctx, []byte("Hello world!"))
+	if err != nil || !hash.Equals(routing.NewKademliaIDFromData("Hello world!")) {
+		t.Errorf("Put() = %v, %v, want %v, %v", hash, err, routing.NewKademliaIDFromData("Hello world!"), nil)
+	}
+	if data, err := net2.Get(ctx, hash); err != nil || string(data) != "Hello world!" {
+		t.Errorf("Get() = %v, %v, want %v, %v", string(data), err, "Hello world!", nil)
+	}
+	if _, err := net2.Get(ctx, routing.NewKademliaIDFromData("missing")); err != ErrNoData {
+		t.Errorf("Get() = %v, want %v", err, ErrNoData)
+	}
+
+	// Only two nodes can store the data
+	_, err = net2.Put(ctx, []byte("quorum"))
+	if quorum, ok := err.(*QuorumError); !ok || quorum.Replicas != 2 || quorum.Quorum != 3 {
+		t.Errorf("Put() = %v, want %v", err, &QuorumError{2, 3})
+	}
+}
+
+// The content type of an object is stored with it, and found with it by nodes that don't store it
+func TestNetwork_PutObject(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	ip1 := net.ParseIP("0.0.0.0")
+	ip2 := net.ParseIP("0.0.0.1")
+	ip3 := net.ParseIP("0.0.0.2")
+	net1, _ := New(newConfig(fake, ip1))
+	net2, _ := New(newConfig(fake, ip2))
+	net3, _ := New(newConfig(fake, ip3))
+	for _, network := range []*Network{net1, net2, net3} {
+		network.Start()
+		defer network.Stop()
+	}
+	time.Sleep(10 * time.Millisecond)
+	net2.Join(net1.ID(), ip1.String())
+
+	ctx := context.Background()
+	small := &Object{[]byte("<p>Hello world!</p>"), "text/html"}
+	// The data alone fits in a datagram, with the content type it doesn't
+	large := &Object{make([]byte, MAX_DATAGRAM_DATA_LEN-4), "application/octet-stream"}
+	for _, object := range []*Object{small, large} {
+		hash, replicas, err := net1.PutObject(ctx, object)
+		if err != nil || replicas != 2 || !hash.Equals(routing.NewKademliaIDFromData(string(object.Data))) {
+			t.Errorf("PutObject() = %v, %v, %v, want %v, %v, %v", hash, replicas, err,
+				routing.NewKademliaIDFromData(string(object.Data)), 2, nil)
+		}
+	}
+
+	// The third node only joins once the objects are stored, so it has to find them on the others
+	net3.Join(net1.ID(), ip1.String())
+	for _, object := range []*Object{small, large} {
+		hash := routing.NewKademliaIDFromData(string(object.Data))
+		found, _, err := net3.ObjectLookup(ctx, hash)
+		if err != nil || found == nil || found.ContentType != object.ContentType || len(found.Data) != len(object.Data) {
+			t.Errorf("ObjectLookup() = %v, %v, want content type %v", found, err, object.ContentType)
+		}
+		// The cached copy keeps the content type
+		if contentType := net3.localNode.ContentType(hash); contentType != object.ContentType {
+			t.Errorf("ContentType() = %v, want %v", contentType, object.ContentType)
+		}
+	}
+
+	// A put without a content type keeps the content type the data already has
+	if _, _, err := net1.PutObject(ctx, &Object{Data: small.Data}); err != nil {
+		t.Errorf("PutObject() = %v, want %v", err, nil)
+	}
+	if found, _, _ := net2.ObjectLookup(ctx, routing.NewKademliaIDFromData(string(small.Data))); found.ContentType != "text/html" {
+		t.Errorf("ObjectLookup() = %v, want content type %v", found, "text/html")
+	}
+	tooLong := &Object{[]byte("data"), strings.Repeat("a", MAX_CONTENT_TYPE_LEN+1)}
+	if _, _, err := net1.PutObject(ctx, tooLong); err != ErrContentTypeTooLong {
+		t.Errorf("PutObject() = %v, want %v", err, ErrContentTypeTooLong)
+	}
+}
+
+// The metrics count the RPCs and lookups of a node, and the contents of its routing table and storage
+func TestNetwork_MetricsHandler(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	ip1 := net.ParseIP("0.0.0.0")
+	ip2 := net.ParseIP("0.0.0.1")
+	net1, _ := New(newConfig(fake, ip1))
+	net2, _ := New(newConfig(fake, ip2))
+	net1.Start()
+	net2.Start()
+	defer net1.Stop()
+	defer net2.Stop()
+	time.Sleep(10 * time.Millisecond)
+	net2.Join(net1.ID(), ip1.String())
+	net2.Put(context.Background(), []byte("Hello world!"))
+	fake.NewTransport("0.0.0.2", KAD_PORT, MAX_PACKET_SIZE, time.Second).SendRequest(context.Background(),
+		ip1.String(), []byte("garbage"), false)
+	time.Sleep(10 * time.Millisecond)
+
+	recorder := httptest.NewRecorder()
+	net2.MetricsHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	for _, want := range []string{
+		`kademlia_rpcs_sent_total{type="PING"} 1`,
+		`kademlia_rpcs_sent_total{type="STORE"} 1`,
+		`kademlia_rpc_duration_seconds_count{type="PING"} 1`,
+		`kademlia_lookup_rounds_count{lookup="node"} 2`,
+		"kademlia_routing_table_size 1\n",
+		"kademlia_storage_items 1\n",
+		"kademlia_storage_bytes 12\n",
+	} {
+		if !strings.Contains(recorder.Body.String(), want) {
+			t.Errorf("MetricsHandler() = %v, want %v", recorder.Body.String(), want)
+		}
+	}
+
+	var text strings.Builder
+	net1.WriteMetrics(&text)
+	for _, want := range []string{
+		`kademlia_rpcs_received_total{type="STORE"} 1`,
+		`kademlia_messages_dropped_total{reason="malformed"} 1`,
+	} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("WriteMetrics() = %v, want %v", text.String(), want)
+		}
+	}
+}
+
+// The trace of a lookup has every round, the contacts that were queried and whether they answered
+func TestNetwork_NodeLookupTrace(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	ip1 := net.ParseIP("0.0.0.0")
+	ip2 := net.ParseIP("0.0.0.1")
+	ip3 := net.ParseIP("0.0.0.2")
+	net1, _ := New(newConfig(fake, ip1))
+	net2, _ := New(newConfig(fake, ip2))
+	net3, _ := New(newConfig(fake, ip3))
+	for _, network := range []*Network{net1, net2, net3} {
+		network.Start()
+		defer network.Stop()
+	}
+	time.Sleep(10 * time.Millisecond)
+	net2.Join(net1.ID(), ip1.String())
+	net3.Join(net1.ID(), ip1.String())
+
+	contacts, trace, err := net3.NodeLookupTrace(context.Background(), net2.ID())
+	if err != nil || len(contacts) != 2 {
+		t.Errorf("NodeLookupTrace() = %v, %v, want 2 contacts", contacts, err)
+	}
+	if trace.Kind != "node" || !trace.Target.Equals(net2.ID()) || len(trace.Rounds) == 0 || trace.RPCs() != 2 {
+		t.Fatalf("NodeLookupTrace() trace = %+v, want a node lookup of %v with 2 RPCs", trace, net2.ID())
+	}
+	for _, round := range trace.Rounds {
+		for _, query := range round.Queries {
+			if !query.Answered || query.Contact.Distance == nil {
+				t.Errorf("NodeLookupTrace() query = %+v, want an answered query with a distance", query)
+			}
+		}
+	}
+
+	// A node that stopped doesn't answer. net1 still returns it, so it may be queried more than once
+	net2.Stop()
+	_, trace, _ = net3.NodeLookupTrace(context.Background(), net2.ID())
+	for _, round := range trace.Rounds {
+		for _, query := range round.Queries {
+			if query.Answered != query.Contact.ID.Equals(net1.ID()) {
+				t.Errorf("NodeLookupTrace() query = %+v, want only %v to answer", query, net1.ID())
+			}
+		}
+	}
+}
+
+// The trace of a data lookup shows where the data was found, and which contacts timed out
+func TestNetwork_DataLookupTrace(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	ip1 := net.ParseIP("0.0.0.0")
+	ip2 := net.ParseIP("0.0.0.1")
+	net1, _ := New(newConfig(fake, ip1))
+	net2, _ := New(newConfig(fake, ip2))
+	net1.Start()
+	net2.Start()
+	defer net1.Stop()
+	defer net2.Stop()
+	time.Sleep(10 * time.Millisecond)
+	net2.Join(net1.ID(), ip1.String())
+	hash := routing.NewKademliaIDFromData("traced")
+	net1.localNode.Store([]byte("traced"), hash)
+
+	// A contact that never answers, and is asked first because it is the closest to the hash
+	silent := fake.NewTransport("0.0.0.2", KAD_PORT, MAX_PACKET_SIZE, time.Second)
+	go silent.Listen(func(ctx context.Context, request []byte, from string, reply func([]byte) error) {})
+	defer silent.Close()
+	time.Sleep(10 * time.Millisecond)
+	net2.routingTable.AddContact(routing.NewContact(hash, "0.0.0.2"))
+
+	data, _, trace, err := net2.DataLookupTrace(context.Background(), hash)
+	if string(data) != "traced" || err != nil {
+		t.Fatalf("DataLookupTrace() = %v, %v, want %v, %v", string(data), err, "traced", nil)
+	}
+	if trace.Kind != "data" || trace.Local || len(trace.Rounds) != 1 {
+		t.Fatalf("DataLookupTrace() trace = %+v, want a data lookup with 1 round", trace)
+	}
+	if len(trace.Rounds[0].Queries) != 2 {
+		t.Fatalf("DataLookupTrace() queries = %+v, want 2 queries", trace.Rounds[0].Queries)
+	}
+	for _, query := range trace.Rounds[0].Queries {
+		if query.Contact.ID.Equals(net1.ID()) && (!query.Answered || !query.FoundData) {
+			t.Errorf("DataLookupTrace() query = %+v, want the data from %v", query, net1.ID())
+		}
+		if !query.Contact.ID.Equals(net1.ID()) && (query.Answered || !query.TimedOut()) {
+			t.Errorf("DataLookupTrace() query = %+v, want a timeout", query)
+		}
+	}
+
+	// The data is cached by the lookup, so the next one is local
+	_, _, trace, _ = net2.DataLookupTrace(context.Background(), hash)
+	if !trace.Local || len(trace.Rounds) != 0 {
+		t.Errorf("DataLookupTrace() trace = %+v, want a local lookup", trace)
+	}
+}
@@ -0,0 +1,181 @@
+package kademlia
+
+import (
+	"context"
+	"d7024e/logging"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The workers of a started node: the listeners of both transports, the loops that refresh and expire data, and
+// the HTTP server of a frontend (see Serve). They all stop when the node is stopped, and the node stops itself
+// when one of them fails.
+type lifecycle struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+
+	mutex   sync.Mutex
+	server  *http.Server // nil if the node doesn't serve HTTP
+	address string
+
+	errOnce sync.Once
+	err     error // The first error of a worker
+
+	log *logging.Logger
+}
+
+const HTTP_SHUTDOWN_TIMEOUT = 5000 // Time the HTTP server waits for running requests when the node is stopped in milliseconds
+
+var ErrNodeStarted = errors.New("the node was already started")
+var ErrNodeNotStarted = errors.New("the node is not started")
+var ErrHTTPStarted = errors.New("the node already serves HTTP")
+
+// Start starts the workers of the node: it listens for requests on its transports, refreshes the data it has
+// stored and deletes expired data. A node can only be started once
+func (network *Network) Start() error {
+	if network.lifecycle != nil {
+		return ErrNodeStarted
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	life := &lifecycle{ctx: ctx, cancel: cancel, log: network.log}
+	network.lifecycle = life
+
+	life.run(network.listenDatagrams)
+	if network.streams != nil {
+		life.run(network.listenStreams)
+	}
+	life.run(func() error {
+		network.Remember(ctx)
+		return nil
+	})
+	life.run(func() error {
+		network.localNode.UpdateTTL(ctx)
+		return nil
+	})
+	// Running HTTP requests still need the transports, so they are closed after the HTTP server
+	life.run(func() error {
+		<-ctx.Done()
+		life.mutex.Lock()
+		server := life.server
+		life.mutex.Unlock()
+		var err error
+		if server != nil {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), HTTP_SHUTDOWN_TIMEOUT*time.Millisecond)
+			defer cancel()
+			if err = server.Shutdown(shutdownCtx); err != nil {
+				server.Close()
+			}
+		}
+		network.shutdown()
+		return err
+	})
+	return nil
+}
+
+// Serve serves the HTTP API of a frontend (handler) on address as one more worker of a started node. The server
+// is shut down gracefully when the node is stopped, before the transports that running requests still need
+// are closed. Returns an error if the address can't be listened on
+func (network *Network) Serve(address string, handler http.Handler) error {
+	life := network.lifecycle
+	if life == nil {
+		return ErrNodeNotStarted
+	}
+	// The teardown worker reads the server under the same lock once the node is stopped
+	life.mutex.Lock()
+	defer life.mutex.Unlock()
+	if life.ctx.Err() != nil {
+		return ErrNodeNotStarted
+	}
+	if life.server != nil {
+		return ErrHTTPStarted
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: handler}
+	life.server, life.address = server, listener.Addr().String()
+	life.run(func() error {
+		if err := server.Serve(listener); err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	})
+	return nil
+}
+
+// ServeListener runs serve on a listener of a frontend (e.g. a control socket) as one more worker of a started
+// node. The listener is closed when the node is stopped, which must make serve return. Errors of serve after that
+// are ignored
+func (network *Network) ServeListener(listener net.Listener, serve func(net.Listener) error) error {
+	life := network.lifecycle
+	if life == nil {
+		return ErrNodeNotStarted
+	}
+	life.mutex.Lock()
+	defer life.mutex.Unlock()
+	if life.ctx.Err() != nil {
+		return ErrNodeNotStarted
+	}
+	life.run(func() error {
+		if err := serve(listener); err != nil && life.ctx.Err() == nil {
+			return err
+		}
+		return nil
+	})
+	life.run(func() error {
+		<-life.ctx.Done()
+		listener.Close()
+		return nil
+	})
+	return nil
+}
+
+// Stop stops all workers of a started node, and waits for them to finish. Returns the first error of a worker,
+// nil if they all stopped cleanly
+func (network *Network) Stop() error {
+	if network.lifecycle == nil {
+		return nil
+	}
+	network.lifecycle.cancel()
+	return network.Wait()
+}
+
+// Wait blocks until a started node is stopped, by Stop or because one of its workers failed. Returns the first
+// error of a worker
+func (network *Network) Wait() error {
+	if network.lifecycle == nil {
+		return nil
+	}
+	network.lifecycle.workers.Wait()
+	return network.lifecycle.err
+}
+
+// HTTPAddress returns the address the HTTP API of a started node listens on, "" if it doesn't serve HTTP
+func (network *Network) HTTPAddress() string {
+	if network.lifecycle == nil {
+		return ""
+	}
+	network.lifecycle.mutex.Lock()
+	defer network.lifecycle.mutex.Unlock()
+	return network.lifecycle.address
+}
+
+// run runs a worker. If it fails, its error is kept and all other workers are stopped
+func (life *lifecycle) run(worker func() error) {
+	life.workers.Add(1)
+	go func() {
+		defer life.workers.Done()
+		if err := worker(); err != nil {
+			life.log.Error("Stopping the node", "error", err)
+			life.errOnce.Do(func() {
+				life.err = err
+			})
+			life.cancel()
+		}
+	}()
+}
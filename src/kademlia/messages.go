@@ -0,0 +1,113 @@
+package kademlia
+
+import (
+	"crypto/ed25519"
+	"d7024e/codec"
+	"d7024e/routing"
+)
+
+// The bodies of the messages sent between nodes. The numbers in the kad tags identify the fields on the wire
+// (see the codec package) and must never change, new fields get new numbers.
+
+// emptyMessage is the body of PING and PING_ACK
+type emptyMessage struct{}
+
+// targetRequest is the body of FIND_NODE, FIND_DATA and REFRESH_DATA_TTL
+type targetRequest struct {
+	Target routing.KademliaID `kad:"1"`
+}
+
+type storeRequest struct {
+	Target      routing.KademliaID          `kad:"1"`
+	Data        []byte                      `kad:"2"`
+	Owner       [ed25519.PublicKeySize]byte `kad:"4"`
+	Signature   [ed25519.SignatureSize]byte `kad:"5"` // By the owner, over the target and the data
+	ContentType []byte                      `kad:"8,optional"` // Empty if the data has none
+}
+
+type storeRecordRequest struct {
+	Target routing.KademliaID `kad:"1"`
+	Record []byte             `kad:"6"`
+}
+
+type deleteRequest struct {
+	Target    routing.KademliaID          `kad:"1"`
+	Owner     [ed25519.PublicKeySize]byte `kad:"4"`
+	Signature [ed25519.SignatureSize]byte `kad:"5"`
+	Timestamp [8]byte                     `kad:"9"` // When the owner signed the request, in Unix nanoseconds
+}
+
+// contactsReply is the body of FIND_NODE_ACK and FIND_DATA_ACK_FAIL
+type contactsReply struct {
+	Contacts []wireContact `kad:"3,optional"`
+}
+
+// wireContact is a contact in a contactsReply
+type wireContact struct {
+	ID    routing.KademliaID `kad:"1"`
+	IP    [IP_LEN]byte       `kad:"2"`
+	Flags byte               `kad:"3,optional"` // FLAG_STREAMS if the contact accepts streams
+}
+
+// dataReply is the body of FIND_DATA_ACK_SUCCESS
+type dataReply struct {
+	Data        []byte `kad:"2"`
+	ContentType []byte `kad:"8,optional"`
+}
+
+// reasonReply is the body of STORE_ACK, STORE_NACK and DELETE_ACK
+type reasonReply struct {
+	Reason byte `kad:"7"`
+}
+
+// optionalString converts a string to an optional field, which is left out of the message if the string is empty
+func optionalString(value string) []byte {
+	if value == "" {
+		return nil
+	}
+	return []byte(value)
+}
+
+// message is a decoded message
+type message struct {
+	codec.Header
+	Body interface{}
+	// The encoding that the message was received in. Replies are sent in the same encoding
+	encoding codec.Encoding
+	// If the message is sent or was received over a stream instead of in a datagram
+	stream bool
+}
+
+// newRequestBody returns an empty body for a type of request, or nil if it isn't a request this node knows about
+func newRequestBody(msgType byte) interface{} {
+	switch msgType {
+	case PING:
+		return &emptyMessage{}
+	case FIND_NODE, FIND_DATA, REFRESH_DATA_TTL:
+		return &targetRequest{}
+	case STORE:
+		return &storeRequest{}
+	case STORE_RECORD:
+		return &storeRecordRequest{}
+	case DELETE:
+		return &deleteRequest{}
+	}
+	return nil
+}
+
+// newReplyBody returns an empty body for a type of reply, or nil if it isn't a reply this node knows about
+func newReplyBody(msgType byte) interface{} {
+	switch msgType {
+	case PING_ACK:
+		return &emptyMessage{}
+	case FIND_NODE_ACK, FIND_DATA_ACK_FAIL:
+		return &contactsReply{}
+	case FIND_DATA_ACK_SUCCESS:
+		return &dataReply{}
+	case FIND_DATA_ACK_STREAM:
+		return &emptyMessage{}
+	case STORE_ACK, STORE_NACK, DELETE_ACK:
+		return &reasonReply{}
+	}
+	return nil
+}
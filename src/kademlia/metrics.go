@@ -0,0 +1,128 @@
+package kademlia
+
+import (
+	"d7024e/metrics"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Every node keeps metrics of the RPCs it sends and receives, its lookups, its routing table and its storage, and
+// serves them in the Prometheus text format (see MetricsHandler). Counters are updated as things happen, while
+// the sizes of the routing table and the storage are read when the metrics are collected.
+
+// Upper bounds of the buckets of the histogram of lookup rounds
+var LOOKUP_ROUND_BUCKETS = []float64{1, 2, 3, 4, 5, 6, 8, 10, 15, 20}
+
+type networkMetrics struct {
+	registry *metrics.Registry
+
+	rpcsSent        *metrics.Counter
+	rpcsReceived    *metrics.Counter
+	rpcTimeouts     *metrics.Counter
+	rpcDuration     *metrics.Histogram
+	droppedMessages *metrics.Counter
+
+	lookupRounds   *metrics.Histogram
+	lookupDuration *metrics.Histogram
+
+	routingTableSize     *metrics.Gauge
+	routingTableContacts *metrics.Gauge
+
+	storageItems       *metrics.Gauge
+	storageCachedItems *metrics.Gauge
+	storageBytes       *metrics.Gauge
+	expirations        *metrics.Counter
+	refreshes          *metrics.Counter
+	evictions          *metrics.Counter
+}
+
+func newNetworkMetrics() *networkMetrics {
+	registry := metrics.NewRegistry()
+	return &networkMetrics{
+		registry: registry,
+
+		rpcsSent: registry.NewCounter("kademlia_rpcs_sent_total",
+			"Number of RPCs sent to other nodes.", "type"),
+		rpcsReceived: registry.NewCounter("kademlia_rpcs_received_total",
+			"Number of RPCs received from other nodes.", "type"),
+		rpcTimeouts: registry.NewCounter("kademlia_rpc_timeouts_total",
+			"Number of RPCs that were not answered in time.", "type"),
+		rpcDuration: registry.NewHistogram("kademlia_rpc_duration_seconds",
+			"Time from sending an RPC to receiving its reply.", metrics.DEFAULT_BUCKETS, "type"),
+		droppedMessages: registry.NewCounter("kademlia_messages_dropped_total",
+			"Number of received messages that were dropped.", "reason"),
+
+		lookupRounds: registry.NewHistogram("kademlia_lookup_rounds",
+			"Number of rounds of RPCs of a lookup.", LOOKUP_ROUND_BUCKETS, "lookup"),
+		lookupDuration: registry.NewHistogram("kademlia_lookup_duration_seconds",
+			"Time a lookup took.", metrics.DEFAULT_BUCKETS, "lookup"),
+
+		routingTableSize: registry.NewGauge("kademlia_routing_table_size",
+			"Number of contacts in the routing table."),
+		routingTableContacts: registry.NewGauge("kademlia_routing_table_bucket_contacts",
+			"Number of contacts in each non-empty bucket of the routing table.", "bucket"),
+
+		storageItems: registry.NewGauge("kademlia_storage_items",
+			"Number of stored data objects and records, including cached copies."),
+		storageCachedItems: registry.NewGauge("kademlia_storage_cached_items",
+			"Number of stored cached copies of data."),
+		storageBytes: registry.NewGauge("kademlia_storage_bytes",
+			"Number of bytes of stored data."),
+		expirations: registry.NewCounter("kademlia_storage_expirations_total",
+			"Number of data objects that were deleted because their TTL ran out."),
+		refreshes: registry.NewCounter("kademlia_storage_refreshes_total",
+			"Number of times the TTL of a stored data object was refreshed."),
+		evictions: registry.NewCounter("kademlia_storage_evictions_total",
+			"Number of data objects that were evicted because the storage quota was reached."),
+	}
+}
+
+// MetricsHandler returns the handler that serves the metrics of the node in the Prometheus text format
+func (network *Network) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		network.collectMetrics()
+		network.metrics.registry.ServeHTTP(w, r)
+	})
+}
+
+// WriteMetrics writes the metrics of the node in the Prometheus text format
+func (network *Network) WriteMetrics(w io.Writer) error {
+	network.collectMetrics()
+	_, err := network.metrics.registry.WriteTo(w)
+	return err
+}
+
+// collectMetrics reads the sizes of the routing table and the storage, and the counters that are kept elsewhere
+func (network *Network) collectMetrics() {
+	m := network.metrics
+	m.droppedMessages.Set(float64(atomic.LoadUint64(&network.unknownMessages)), "unknown_type")
+	m.droppedMessages.Set(float64(atomic.LoadUint64(&network.malformedMessages)), "malformed")
+
+	size := 0
+	m.routingTableContacts.Reset()
+	for bucket, contacts := range network.routingTable.BucketSizes() {
+		if contacts > 0 {
+			m.routingTableContacts.Set(float64(contacts), strconv.Itoa(bucket))
+			size += contacts
+		}
+	}
+	m.routingTableSize.Set(float64(size))
+
+	stats := network.localNode.Stats()
+	m.storageItems.Set(float64(stats.Items))
+	m.storageCachedItems.Set(float64(stats.CachedItems))
+	m.storageBytes.Set(float64(stats.Bytes))
+	m.expirations.Set(float64(stats.Expirations))
+	m.refreshes.Set(float64(stats.Refreshes))
+	m.evictions.Set(float64(stats.Evictions))
+}
+
+// observeLookup records the number of rounds and the duration of a lookup that started at some time. Defer it at
+// the start of the lookup with a pointer to its round counter
+func (network *Network) observeLookup(lookup string, start time.Time, rounds *int) {
+	network.metrics.lookupRounds.Observe(float64(*rounds), lookup)
+	network.metrics.lookupDuration.Observe(network.clock.Now().Sub(start).Seconds(), lookup)
+}
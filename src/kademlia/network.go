@@ -0,0 +1,1409 @@
+package kademlia
+
+import (
+	"context"
+	"crypto/ed25519"
+	"d7024e/clock"
+	"d7024e/codec"
+	"d7024e/logging"
+	"d7024e/routing"
+	"d7024e/storage"
+	"d7024e/transport"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Network messages are encoded by the codec package, in the TLV or the CBOR encoding of the node. Every message has
+// a header with the protocol version, the message type, a request ID and the ID of the sender, and a body with
+// the fields of its type (see messages.go). A reply has the same request ID and encoding as its request.
+// Fields that a node doesn't know about are ignored so that new fields can be added without breaking older nodes.
+
+// Requests:
+// PING: nothing
+// FIND_NODE: TARGET (the ID to find the closest nodes to)
+// FIND_DATA: TARGET (the hash of the data)
+// STORE: TARGET (the hash of the data), OWNER, DATA
+// STORE_RECORD: TARGET (the key of the record), RECORD
+// DELETE: TARGET (the hash of the data), OWNER, SIGNATURE
+// REFRESH_DATA_TTL: TARGET (the hash of the data). There is no reply
+
+// Protocol for returning information:
+// PING_ACK: Contains nothing
+
+// STORE_ACK: REASON why the value was accepted.
+// 		0: The value is stored (or was already stored)
+
+// DELETE_ACK: REASON, the result of the DELETE request.
+// 		0: The data is deleted (or the requester is no longer one of its owners)
+// 		4: The signature of the request is invalid
+// 		5: The requester is not an owner of the data
+// 		6: There is no data stored at the hash
+
+// STORE_NACK: REASON why the value was rejected.
+// 		1: The storage quota of the node is exceeded
+// 		2: The record has an invalid signature (STORE_RECORD only)
+// 		3: A newer version of the record is already stored (STORE_RECORD only)
+
+// FIND_NODE_ACK: CONTACTS, a list of <NODE_ID, IP> pairs with the <=K closest nodes
+
+// FIND_DATA_ACK_FAIL: Found no data. CONTACTS with the <=K closest nodes, like FIND_NODE_ACK
+// FIND_DATA_ACK_SUCCESS: Found data. DATA with the full byte array
+// FIND_DATA_ACK_STREAM: Found data, but it is too large for a datagram. Contains nothing, the requester should send
+// the FIND_DATA again over a stream (see stream.go)
+
+// Golang doesn't have enums, this the closest alternative I could find
+const (
+	PING byte = 0
+	PING_ACK byte = 1
+
+	STORE byte = 2
+	STORE_NACK byte = 3
+
+	FIND_NODE byte = 4
+	FIND_NODE_ACK byte = 5
+
+	REFRESH_DATA_TTL = 6
+	STORE_ACK byte = 7
+
+	FIND_DATA byte = 8
+	FIND_DATA_ACK_SUCCESS byte = 9
+	FIND_DATA_ACK_FAIL byte = 10
+
+	STORE_RECORD byte = 11
+
+	DELETE byte = 12
+	DELETE_ACK byte = 13
+
+	FIND_DATA_ACK_STREAM byte = 14
+)
+
+// Flags in the header of a message
+const (
+	FLAG_STREAMS byte = 1 // The sender accepts streams for large messages
+)
+
+// Reasons for accepting or rejecting a STORE or DELETE request, sent in a STORE_ACK, STORE_NACK or DELETE_ACK
+const (
+	ACCEPT_STORED byte = 0
+	REJECT_QUOTA byte = 1
+	REJECT_INVALID_RECORD byte = 2
+	REJECT_STALE_RECORD byte = 3
+	REJECT_INVALID_SIGNATURE byte = 4
+	REJECT_NOT_OWNER byte = 5
+	REJECT_NOT_FOUND byte = 6
+	REJECT_HASH_MISMATCH byte = 7
+	REJECT_STALE_DELETE byte = 8
+
+	ACCEPT_DELETED byte = 0
+)
+
+// Message communication constants
+const MAX_PACKET_SIZE = transport.MAX_DATAGRAM_SIZE // Maximum size of a byte array
+const MAX_DATAGRAM_DATA_LEN = MAX_PACKET_SIZE - codec.MaxHeaderLen - codec.MaxFieldHeaderLen // Largest data in a FIND_DATA_ACK_SUCCESS datagram
+const IP_LEN = 4 // Length of IP address in bytes
+const TIMEOUT = 50 // Amount of time before a i/o timeout is issued in milliseconds
+const KAD_PORT = "5001" // Port number used for communication between nodes
+const STORE_TIMEOUT = 2000 // Amount of time Store waits for STORE_ACKs in milliseconds
+const DEFAULT_WRITE_QUORUM = 1 // Number of nodes that must store some data for a put to be successful
+const DEFAULT_REQUEST_TIMEOUT = 30000 // Longest time a CLI command or HTTP request may take in milliseconds
+
+// Maximum size of the value of a record. It has to fit in a single STORE_RECORD message
+const MAX_RECORD_VALUE_LEN = MAX_PACKET_SIZE - codec.MaxHeaderLen - 2*codec.MaxFieldHeaderLen - routing.ID_LEN - storage.RECORD_HEADER_LEN
+
+const MAX_CONTENT_TYPE_LEN = 255 // Longest content type of a data object (see PutObject)
+
+// Shorthands for the kademlia parameters of the routing package
+const (
+	k     = routing.K
+	alpha = routing.ALPHA
+)
+
+var ErrUnknownMessage = errors.New("received unknown request")
+var ErrMalformedMessage = errors.New("received malformed message")
+var ErrUnexpectedReply = errors.New("received an unexpected reply")
+var ErrInvalidData = errors.New("received data that is neither the data of the hash nor a record stored under it")
+
+type Network struct {
+	localNode storage.Node
+	routingTable *routing.RoutingTable
+
+	// The transport for datagrams, and the one for streams (nil if this node doesn't accept streams)
+	transport transport.Transport
+	streams transport.Transport
+
+	// Minimum number of replicas that must acknowledge a STORE for a put to count as successful
+	writeQuorum int
+
+	// The key pair used to sign the records this node publishes, and the sequence number of the last one.
+	// Use atomic operations for the sequence number, records can be published by several requests at once
+	identity ed25519.PrivateKey
+	recordSequence uint64
+
+	// Number of received messages that were dropped because of an unknown type or because they were malformed
+	// Use atomic operations, these are updated by the listening thread
+	unknownMessages uint64
+	malformedMessages uint64
+
+	// ID of the last request this node sent. Use atomic operations
+	requestID uint32
+
+	// The encoding of the requests this node sends
+	encoding codec.Encoding
+
+	// Longest time the network operations of a CLI command or HTTP request may take
+	requestTimeout time.Duration
+
+	// The workers started by Start, nil if the node wasn't started (see lifecycle.go)
+	lifecycle *lifecycle
+
+	// The clock that refreshes and timeouts are measured with (see SetClock)
+	clock clock.Clock
+
+	// The loggers of the network and of the refreshing of data (see ttl.go), with the ID of the node in every entry
+	log    *logging.Logger
+	ttlLog *logging.Logger
+
+	// The metrics of the node (see metrics.go)
+	metrics *networkMetrics
+
+	// The lookups that are running (see introspection.go)
+	lookups *lookupTracker
+}
+
+// NewNetwork creates the network of a node with some IP address that communicates over transport, and over
+// streams for messages that are too large for a datagram. streams may be nil
+func NewNetwork(ip *net.IP, transport transport.Transport, streams transport.Transport) Network {
+	_, identity, _ := ed25519.GenerateKey(nil)
+	me := routing.NewContact(routing.NewKademliaIDFromIP(ip),ip.String())
+	return Network{storage.NewNode(me), routing.NewRoutingTable(me), transport, streams,
+		DEFAULT_WRITE_QUORUM, identity, 0, 0, 0, rand.Uint32(), codec.TLV, DEFAULT_REQUEST_TIMEOUT * time.Millisecond, nil,
+		clock.Real, logging.For("network").With("node", me.ID), logging.For("ttl").With("node", me.ID),
+		newNetworkMetrics(), newLookupTracker()}
+}
+
+// RequestContext returns the context for the network operations of a CLI command or HTTP request. It is done
+// when parent is done or after the request timeout of the node
+func (network *Network) RequestContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, network.requestTimeout)
+}
+
+// checkRequest decodes a request and validates its type and fields before it is handled, so that a hostile or
+// broken message can't crash the node. Dropped messages are logged and counted.
+func (network *Network) checkRequest(msg []byte) (*message, error) {
+	header, body, encoding, err := codec.Decode(msg, newRequestBody)
+	if err == codec.ErrUnknownType {
+		atomic.AddUint64(&network.unknownMessages, 1)
+		network.log.Warn("Dropped a message of unknown type", "type", header.Type)
+		return nil, ErrUnknownMessage
+	}
+	if err != nil {
+		atomic.AddUint64(&network.malformedMessages, 1)
+		network.log.Warn("Dropped a malformed message", "length", len(msg), "error", err)
+		return nil, ErrMalformedMessage
+	}
+	return &message{header, body, encoding, false}, nil
+}
+
+// newHeader creates the header of a message from the local node
+func (network *Network) newHeader(msgType byte, requestID uint32) codec.Header {
+	header := codec.NewHeader(msgType, requestID, *network.routingTable.Me().ID)
+	if network.streams != nil {
+		header.Flags |= FLAG_STREAMS
+	}
+	return header
+}
+
+// newRequest creates a request from the local node with a new request ID
+func (network *Network) newRequest(msgType byte, body interface{}) *message {
+	return &message{network.newHeader(msgType, atomic.AddUint32(&network.requestID, 1)), body, network.encoding, false}
+}
+
+// reply sends a reply to a request with respond, in the encoding of the request
+func (network *Network) reply(respond func([]byte) error, request *message, msgType byte, body interface{}) error {
+	msg, err := request.encoding.Encode(network.newHeader(msgType, request.RequestID), body)
+	if err != nil {
+		return err
+	}
+	return respond(msg)
+}
+
+// participate returns a context for an operation that sends requests, and the function to call when it is done.
+// The transport is told about the operation if it tracks the operations of the node (see transport.Tracker)
+func (network *Network) participate(ctx context.Context) (context.Context, func()) {
+	if tracker, ok := network.transport.(transport.Tracker); ok {
+		return tracker.Participate(ctx)
+	}
+	return ctx, func() {}
+}
+
+// spawn runs f, which sends requests with the context it is given, in a new goroutine. It is started through the
+// transport if the transport tracks the operations of the node (see transport.Tracker)
+func (network *Network) spawn(ctx context.Context, f func(ctx context.Context)) {
+	if tracker, ok := network.transport.(transport.Tracker); ok {
+		tracker.Go(ctx, f)
+	} else {
+		go f(ctx)
+	}
+}
+
+// block runs wait, which waits for goroutines that the operation of ctx started with spawn
+func (network *Network) block(ctx context.Context, wait func()) {
+	if tracker, ok := network.transport.(transport.Tracker); ok {
+		tracker.Block(ctx, wait)
+	} else {
+		wait()
+	}
+}
+
+// pinger returns the function that KickTheBucket pings old contacts with, as part of the operation of ctx. The
+// pings aren't stopped when ctx is done, since a contact that doesn't reply to them is evicted
+func (network *Network) pinger(ctx context.Context) func(*routing.Contact) bool {
+	ctx = detachedContext{ctx}
+	return func(contact *routing.Contact) bool {
+		return network.PingContext(ctx, contact)
+	}
+}
+
+// detachedContext keeps the values of a context, but is never done
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// sendRequest sends a request to some contact. If expectReply is set it waits (at most TIMEOUT, or until the
+// context is done) for the reply and checks that it answers this request. Returns nil if no reply is expected.
+// Requests that are too large for a datagram, or that are marked as stream requests, are sent over a stream.
+// The contact is updated with whether it accepts streams or not when it replies
+func (network *Network) sendRequest(ctx context.Context, contact *routing.Contact, request *message, expectReply bool) (*message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	msg, err := request.encoding.Encode(request.Header, request.Body)
+	if err != nil {
+		return nil, err
+	}
+	name := messageName(request.Type)
+	start := network.clock.Now()
+	if request.stream || len(msg) > MAX_PACKET_SIZE {
+		if !contact.Streams || network.streams == nil {
+			return nil, ErrStreamsUnsupported
+		}
+		network.metrics.rpcsSent.Inc(name)
+		msg, err = network.streams.SendRequest(ctx, contact.Address, msg, expectReply)
+	} else {
+		network.metrics.rpcsSent.Inc(name)
+		msg, err = network.transport.SendRequest(ctx, contact.Address, msg, expectReply)
+	}
+	if transport.IsTimeout(err) {
+		network.metrics.rpcTimeouts.Inc(name)
+	}
+	if err != nil || !expectReply {
+		return nil, err
+	}
+	network.metrics.rpcDuration.Observe(network.clock.Now().Sub(start).Seconds(), name)
+
+	header, body, encoding, err := codec.Decode(msg, newReplyBody)
+	if err != nil {
+		return nil, err
+	}
+	if header.RequestID != request.RequestID {
+		return nil, ErrUnexpectedReply
+	}
+	contact.Streams = header.Flags&FLAG_STREAMS != 0
+	return &message{header, body, encoding, false}, nil
+}
+
+// Handles FIND_NODE  requests (initiated by findNodeRPC) from other nodes by sending back a bucket of the k closest
+// nodes to some kademlia ID.
+// msgType is the type of message (message description) that will be sent back to the requester.
+func (network *Network) sendFindNodeAck(request *message, respond func([]byte) error, msgType byte) error {
+	// Message format:
+	// REC: [FIND_NODE or FIND_DATA, TARGET]
+	// SEND: [msgType, CONTACTS:[ID, IP]...]
+
+	requesterID := routing.KademliaID(request.Sender)
+	targetID := &request.Body.(*targetRequest).Target
+	bucket := network.routingTable.FindClosestContacts(targetID, k + 1)
+	bucket = removeSelfOrTail(&requesterID, bucket, len(bucket) == k + 1)
+
+	network.log.Debug("Received request", "rpc", "FIND_NODE", "peer", requesterID, "target", targetID)
+
+	// Send the actual bucket (put the ID and IP address of the contacts in the message)
+	reply := contactsReply{make([]wireContact, len(bucket))}
+	for i ,data := range bucket {
+		reply.Contacts[i].ID = *data.ID
+		if data.Streams {
+			reply.Contacts[i].Flags = FLAG_STREAMS
+		}
+
+		// convert ip string to byte array
+		copy(reply.Contacts[i].IP[:], net.ParseIP(strings.Split(data.Address,":")[0]).To4())
+	}
+	return network.reply(respond, request, msgType, &reply)
+}
+
+// unpackMessage decodes and handles a kademlia request from another node, and replies with respond.
+// Returns ErrUnknownMessage or ErrMalformedMessage if the request can't be handled (see checkRequest)
+func (network *Network) unpackMessage(msg []byte, respond func([]byte) error) error {
+	request, err := network.checkRequest(msg)
+	if err != nil {
+		return err
+	}
+	return network.handleRequest(request, respond)
+}
+
+// handleRequest handles all kademlia requests from other nodes and sends the replies with respond.
+// The request must have been decoded by checkRequest
+func (network *Network) handleRequest(request *message, respond func([]byte) error) error {
+	network.metrics.rpcsReceived.Inc(messageName(request.Type))
+	switch request.Type {
+	case PING:
+		network.log.Debug("Received request", "rpc", "PING", "peer", routing.KademliaID(request.Sender))
+		err := network.reply(respond, request, PING_ACK, &emptyMessage{})
+		if err != nil {
+			network.log.Warn("Could not reply to request", "rpc", "PING", "error", err)
+		}
+		return err
+	case FIND_NODE:
+		return network.sendFindNodeAck(request, respond, FIND_NODE_ACK)
+	case FIND_DATA:
+		// Message format:
+		// REC:  [FIND_DATA, TARGET]
+		// SEND: [FIND_DATA_ACK_FAIL, CONTACTS:[ID, IP]...]
+		//   OR  [FIND_DATA_ACK_SUCCESS, DATA, CONTENT_TYPE]
+		target := &request.Body.(*targetRequest).Target
+		network.log.Debug("Received request", "rpc", "FIND_DATA", "peer", routing.KademliaID(request.Sender),
+			"hash", target)
+		data := network.localNode.LookupData(target)
+		contentType := network.localNode.ContentType(target)
+		size := len(data)
+		if contentType != "" {
+			size += codec.MaxFieldHeaderLen + len(contentType) // The content type is one more field
+		}
+		if data != nil && !request.stream && size > MAX_DATAGRAM_DATA_LEN && request.Flags&FLAG_STREAMS != 0 {
+			// The requester has to ask again over a stream. A requester that doesn't accept streams gets the
+			// datagram, which the transport refuses to send if it is too large
+			return network.reply(respond, request, FIND_DATA_ACK_STREAM, &emptyMessage{})
+		}
+		if data != nil {
+			err := network.reply(respond, request, FIND_DATA_ACK_SUCCESS, &dataReply{data, optionalString(contentType)})
+			if err != nil {
+				network.log.Warn("Could not reply to request", "rpc", "FIND_DATA", "error", err)
+			}
+			return err
+		}
+		return network.sendFindNodeAck(request, respond, FIND_DATA_ACK_FAIL)
+	case STORE:
+		// Message format:
+		// REC: [STORE, TARGET, DATA, OWNER, SIGNATURE, CONTENT_TYPE]
+		// SEND: [STORE_ACK or STORE_NACK, REASON]
+		body := request.Body.(*storeRequest)
+		network.log.Debug("Received request", "rpc", "STORE", "peer", routing.KademliaID(request.Sender), "hash", body.Target)
+
+		msgType, reply := STORE_ACK, reasonReply{ACCEPT_STORED}
+		switch network.localNode.StoreOwned(body.Data, &body.Target, body.Owner[:], body.Signature[:]) {
+		case nil:
+			if len(body.ContentType) > 0 && len(body.ContentType) <= MAX_CONTENT_TYPE_LEN {
+				network.localNode.SetContentType(&body.Target, string(body.ContentType))
+			}
+		case storage.ErrInvalidSignature:
+			msgType, reply = STORE_NACK, reasonReply{REJECT_INVALID_SIGNATURE}
+		case storage.ErrHashMismatch:
+			msgType, reply = STORE_NACK, reasonReply{REJECT_HASH_MISMATCH}
+		case storage.ErrNotOwner:
+			msgType, reply = STORE_NACK, reasonReply{REJECT_NOT_OWNER}
+		default:
+			msgType, reply = STORE_NACK, reasonReply{REJECT_QUOTA}
+		}
+		if msgType == STORE_NACK {
+			network.log.Warn("Rejected request", "rpc", "STORE", "hash", body.Target, "reason", rejectReason(reply.Reason))
+		}
+		err := network.reply(respond, request, msgType, &reply)
+		if err != nil {
+			network.log.Warn("Could not reply to request", "rpc", "STORE", "error", err)
+		}
+		return err
+	case STORE_RECORD:
+		// Message format:
+		// REC: [STORE_RECORD, TARGET, RECORD]
+		// SEND: [STORE_ACK or STORE_NACK, REASON]
+		body := request.Body.(*storeRecordRequest)
+		msgType, reply := STORE_ACK, reasonReply{ACCEPT_STORED}
+		record, err := storage.DeserializeRecord(body.Record)
+		if err != nil || !record.Key().Equals(&body.Target) {
+			msgType, reply = STORE_NACK, reasonReply{REJECT_INVALID_RECORD}
+		} else {
+			switch network.localNode.StoreRecord(record) {
+			case storage.ErrInvalidRecord:
+				msgType, reply = STORE_NACK, reasonReply{REJECT_INVALID_RECORD}
+			case storage.ErrStaleRecord:
+				msgType, reply = STORE_NACK, reasonReply{REJECT_STALE_RECORD}
+			case storage.ErrStorageFull:
+				msgType, reply = STORE_NACK, reasonReply{REJECT_QUOTA}
+			}
+		}
+		if msgType == STORE_NACK {
+			network.log.Warn("Rejected request", "rpc", "STORE_RECORD", "hash", body.Target, "reason", rejectReason(reply.Reason))
+		}
+		err = network.reply(respond, request, msgType, &reply)
+		if err != nil {
+			network.log.Warn("Could not reply to request", "rpc", "STORE_RECORD", "error", err)
+		}
+		return err
+	case DELETE:
+		// Message format:
+		// REC: [DELETE, TARGET, OWNER, SIGNATURE, TIMESTAMP]
+		// SEND: [DELETE_ACK, REASON]
+		body := request.Body.(*deleteRequest)
+
+		reply := reasonReply{ACCEPT_DELETED}
+		timestamp := time.Unix(0, int64(binary.BigEndian.Uint64(body.Timestamp[:])))
+		switch network.localNode.Unpublish(&body.Target, body.Owner[:], timestamp, body.Signature[:]) {
+		case storage.ErrInvalidSignature:
+			reply.Reason = REJECT_INVALID_SIGNATURE
+		case storage.ErrStaleDelete:
+			reply.Reason = REJECT_STALE_DELETE
+		case storage.ErrNotOwner:
+			reply.Reason = REJECT_NOT_OWNER
+		case storage.ErrNotFound:
+			reply.Reason = REJECT_NOT_FOUND
+		}
+		if reply.Reason != ACCEPT_DELETED {
+			network.log.Warn("Rejected request", "rpc", "DELETE", "hash", body.Target, "reason", rejectReason(reply.Reason))
+		}
+		err := network.reply(respond, request, DELETE_ACK, &reply)
+		if err != nil {
+			network.log.Warn("Could not reply to request", "rpc", "DELETE", "error", err)
+		}
+		return err
+	case REFRESH_DATA_TTL:
+		// Message format:
+		// REC: [REFRESH_DATA_TTL, TARGET]
+		// SEND: nothing
+		network.log.Debug("Received request", "rpc", "REFRESH_DATA_TTL", "peer", routing.KademliaID(request.Sender),
+			"hash", request.Body.(*targetRequest).Target)
+		network.localNode.Refresh(&request.Body.(*targetRequest).Target)
+		return nil
+	}
+	return ErrUnknownMessage
+}
+
+// Listen listens for incoming requests until shutdown is called. Once a message is received it is directed to
+// handleRequest. Also checks if the requesting node should be added to the routing table of the local node
+// (see kickTheBucket). Incoming streams are accepted for as long as Listen runs.
+// Start runs Listen together with the other workers of the node
+func (network *Network) Listen() error {
+	if network.streams != nil {
+		go network.listenStreams()
+	}
+	return network.listenDatagrams()
+}
+
+// listenStreams accepts incoming streams until shutdown is called
+func (network *Network) listenStreams() error {
+	err := network.streams.Listen(network.handleStream)
+	if err != nil {
+		network.log.Error("Could not listen for incoming streams", "error", err)
+	}
+	return err
+}
+
+// listenDatagrams listens for incoming datagrams until shutdown is called, see Listen
+func (network *Network) listenDatagrams() error {
+	err := network.transport.Listen(func(ctx context.Context, msg []byte, from string, respond func([]byte) error) {
+		request, err := network.checkRequest(msg)
+		if err != nil {
+			// Don't add the sender to the routing table if it sends garbage
+			return
+		}
+		ID := routing.KademliaID(request.Sender)
+
+		contact := routing.NewContact(&ID, from)
+		contact.Streams = request.Flags&FLAG_STREAMS != 0
+		network.routingTable.KickTheBucket(&contact, network.pinger(ctx))
+
+		network.handleRequest(request, respond)
+	})
+	if err != nil {
+		network.log.Error("Could not listen for incoming requests", "error", err)
+	}
+	network.log.Debug("Stopped listening")
+	return err
+}
+
+// SetClock sets the clock that TTLs, refreshes and timeouts of the node are measured with. Transports keep their
+// own clock
+func (network *Network) SetClock(clock clock.Clock) {
+	network.clock = clock
+	network.routingTable.SetClock(clock)
+	network.localNode.SetClock(clock)
+}
+
+// shutdown stops Listen and closes the transports
+func (network *Network) shutdown() {
+	network.transport.Close()
+	if network.streams != nil {
+		network.streams.Close()
+	}
+}
+
+// Join a kademlia network via a known nodes IP and ID. The ID is probably the SHA-1 hash of its IP.
+func (network *Network) Join(id *routing.KademliaID, address string) error {
+	return network.JoinContext(context.Background(), id, address)
+}
+
+// JoinContext is Join with a context, which stops the PING and the lookup like in NodeLookupContext. Returns the
+// error of the context if it is done before the node joined
+func (network *Network) JoinContext(ctx context.Context, id *routing.KademliaID, address string) error {
+	ctx, done := network.participate(ctx)
+	defer done()
+	knownNode := routing.NewContact(id, address)
+
+	if network.PingContext(ctx, &knownNode) { // If Ping is successful
+		network.log.Info("Joined network", "peer", knownNode.Address)
+		_, err := network.NodeLookupContext(ctx, network.routingTable.Me().ID) // Start lookup algorithm on yourself
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return errors.New("could not join network node")
+}
+
+// Ping some node directly with the given contact.address.
+// Returns true if the node responded successfully, and false if it did not
+func (network *Network) Ping(contact *routing.Contact) bool {
+	return network.PingContext(context.Background(), contact)
+}
+
+// PingContext is Ping with a context. Returns false if the context is done before the node responded
+func (network *Network) PingContext(ctx context.Context, contact *routing.Contact) bool {
+	ctx, done := network.participate(ctx)
+	defer done()
+	start := network.clock.Now()
+	reply, err := network.sendRequest(ctx, contact, network.newRequest(PING, &emptyMessage{}), true)
+	if err != nil {
+		network.log.Warn("Could not read reply", "rpc", "PING", "peer", contact.ID, "error", err)
+		return false
+	}
+
+	duration := network.clock.Now().Sub(start)
+
+	// Update routing table with the contact that we pinged
+	network.routingTable.KickTheBucket(contact, network.pinger(ctx))
+
+	if reply.Type == PING_ACK {
+		network.log.Debug("Successful ping", "peer", contact.ID, "duration", duration)
+		return true
+	} else {
+		network.log.Warn("Received an invalid reply", "rpc", "PING", "peer", contact.ID, "type", messageName(reply.Type))
+		return false
+	}
+}
+
+// NodeLookup is the central kademlia node lookup algorithm that can be used to find nodes (or data, see DataLookup)
+// depending on the lookup ID. It will always try to locate the K closest nodes in the network and starts by sending
+// udp messages and recursively locates nodes that are closer until no more nodes can be found. Each node
+// will then receive these messages and search through their own routing table
+func (network *Network) NodeLookup(lookupID *routing.KademliaID) []routing.Contact {
+	contacts, _ := network.NodeLookupContext(context.Background(), lookupID)
+	return contacts
+}
+
+// NodeLookupContext is NodeLookup with a context. Once the context is done the outstanding RPCs are stopped, and
+// the closest nodes that were visited so far are returned together with the error of the context
+func (network *Network) NodeLookupContext(ctx context.Context, lookupID *routing.KademliaID) ([]routing.Contact, error) {
+	return network.nodeLookup(ctx, lookupID, nil)
+}
+
+// nodeLookup is NodeLookupContext, recording the lookup in trace if it isn't nil (see trace.go)
+func (network *Network) nodeLookup(ctx context.Context, lookupID *routing.KademliaID, trace *LookupTrace) ([]routing.Contact, error) {
+	ctx, done := network.participate(ctx)
+	defer done()
+	// Get the initial k closest nodes from the current node
+	initNodes := network.routingTable.FindClosestContacts(lookupID, k)
+	if len(initNodes) == 0 {
+		return []routing.Contact{}, ctx.Err()
+	}
+
+	rounds := 0
+	defer network.observeLookup("node", network.clock.Now(), &rounds)
+	lookup := network.lookups.start("node", lookupID, network.clock.Now())
+	defer network.lookups.finish(lookup)
+
+	var visited routing.ContactCandidates
+	var unvisited routing.ContactCandidates
+	unvisited.Append(initNodes)
+
+	wideSearch := false
+	var searchRange = alpha
+	for !visitedKClosest(&unvisited, &visited, k) { // Keep sending RPCs until k closest nodes has been visited
+		searchRange = setSearchSize(wideSearch, &unvisited)
+		rounds++
+		network.lookups.update(lookup, rounds, visited.Len(), unvisited.Len())
+		trace.startRound()
+
+		var newRoundNodes []routing.Contact
+		// Actually visit <=alpha of k-closest nodes grabbed in the prev step
+		for currentNode := 0; currentNode < searchRange; {
+			start := network.clock.Now()
+			newBucket, err := network.findNodeRPC(ctx, &unvisited.Contacts[currentNode], lookupID) // Send RPC
+			trace.addQuery(unvisited.Contacts[currentNode], err, network.clock.Now().Sub(start), newBucket, false)
+			if ctx.Err() != nil {
+				return closestVisited(&visited), ctx.Err()
+			}
+			if err == nil {
+				newRoundNodes = append(newRoundNodes, newBucket...)
+				currentNode ++
+			} else {
+				unvisited.Contacts = append(unvisited.Contacts[:currentNode],
+					unvisited.Contacts[currentNode+1:]...)
+				searchRange--
+			}
+		}
+		postIterationProcessing(&visited, &unvisited, &newRoundNodes, searchRange)
+	}
+
+	return closestVisited(&visited), nil
+}
+
+// DataLookup works exactly like NodeLookup, except that we return data instead of a bucket if we find it from
+// any of the findDataRPCs (which replaces findNodeRPC from NodeLookup)
+func (network *Network) DataLookup(hash *routing.KademliaID) ([]byte, []routing.Contact) {
+	data, contacts, _ := network.DataLookupContext(context.Background(), hash)
+	return data, contacts
+}
+
+// DataLookupContext is DataLookup with a context, which stops the lookup like in NodeLookupContext
+func (network *Network) DataLookupContext(ctx context.Context, hash *routing.KademliaID) ([]byte, []routing.Contact, error) {
+	object, contacts, err := network.dataLookup(ctx, hash, nil)
+	return object.data(), contacts, err
+}
+
+// ObjectLookup is DataLookupContext, returning the data with its content type
+func (network *Network) ObjectLookup(ctx context.Context, hash *routing.KademliaID) (*Object, []routing.Contact, error) {
+	return network.dataLookup(ctx, hash, nil)
+}
+
+// dataLookup is ObjectLookup, recording the lookup in trace if it isn't nil (see trace.go)
+func (network *Network) dataLookup(ctx context.Context, hash *routing.KademliaID, trace *LookupTrace) (*Object, []routing.Contact, error) {
+	ctx, done := network.participate(ctx)
+	defer done()
+	localData := network.localNode.LookupData(hash)
+	if localData != nil {
+		network.log.Debug("Found data on local node", "hash", hash)
+		if trace != nil {
+			trace.Local = true
+		}
+		return &Object{localData, network.localNode.ContentType(hash)}, []routing.Contact{network.routingTable.Me()}, nil
+	}
+
+	initNodes := network.routingTable.FindClosestContacts(hash, k)
+	if len(initNodes) == 0 {
+		return nil, []routing.Contact{}, ctx.Err()
+	}
+
+	rounds := 0
+	defer network.observeLookup("data", network.clock.Now(), &rounds)
+	lookup := network.lookups.start("data", hash, network.clock.Now())
+	defer network.lookups.finish(lookup)
+
+	var visited routing.ContactCandidates
+	var unvisited routing.ContactCandidates
+	unvisited.Append(initNodes)
+
+	wideSearch := false
+	var searchRange = alpha
+	for !visitedKClosest(&unvisited, &visited, k) {
+		searchRange = setSearchSize(wideSearch, &unvisited)
+		rounds++
+		network.lookups.update(lookup, rounds, visited.Len(), unvisited.Len())
+		trace.startRound()
+
+		var newRoundNodes []routing.Contact
+		// Actually visit <=alpha of k-closest nodes grabbed in the prev step
+		for currentNode := 0; currentNode < searchRange; {
+			start := network.clock.Now()
+			object, newBucket, err := network.findDataRPC(ctx, &unvisited.Contacts[currentNode], hash) // Send RPC
+			trace.addQuery(unvisited.Contacts[currentNode], err, network.clock.Now().Sub(start), newBucket, object != nil)
+			if ctx.Err() != nil {
+				return nil, closestVisited(&visited), ctx.Err()
+			}
+			if err == nil {
+				if object != nil {
+					// Keep a cached copy so that the next lookup doesn't have to go through the network.
+					// It is the first thing to be evicted if the storage quota is reached.
+					if network.localNode.Cache(object.Data, hash) == nil && object.ContentType != "" {
+						network.localNode.SetContentType(hash, object.ContentType)
+					}
+					return object, unvisited.Contacts[currentNode:currentNode+1], nil
+				}
+				newRoundNodes = append(newRoundNodes, newBucket...)
+				currentNode ++
+			} else {
+				unvisited.Contacts = append(unvisited.Contacts[:currentNode],
+					unvisited.Contacts[currentNode+1:]...)
+				searchRange--
+			}
+		}
+		postIterationProcessing(&visited, &unvisited, &newRoundNodes, searchRange)
+	}
+
+	return nil, closestVisited(&visited), nil
+}
+
+// closestVisited returns the <=k closest nodes that a lookup has visited
+func closestVisited(visited *routing.ContactCandidates) []routing.Contact {
+	if visited.Len() < k {
+		return visited.GetContacts(visited.Len())
+	}
+	return visited.GetContacts(k)
+}
+
+// Store sends a store msg to the 20th closest nodes a bucket and waits (at most STORE_TIMEOUT) for them to
+// acknowledge it. Returns the number of nodes that stored the data, including the local node.
+// Only the nodes that stored the data are remembered for refreshing.
+func (network *Network) Store(data []byte, hash *routing.KademliaID) int {
+	replicas, _ := network.StoreContext(context.Background(), data, hash)
+	return replicas
+}
+
+// StoreContext is Store with a context. If the context is done before the closest nodes are found nothing is
+// stored, if it is done while waiting for the STORE_ACKs the nodes that acknowledged so far are counted
+func (network *Network) StoreContext(ctx context.Context, data []byte, hash *routing.KademliaID) (int, error) {
+	return network.storeObject(ctx, &Object{Data: data}, hash)
+}
+
+// storeObject is StoreContext for data with a content type. An empty content type doesn't replace the content type
+// that the data was stored with before
+func (network *Network) storeObject(ctx context.Context, object *Object, hash *routing.KademliaID) (int, error) {
+	owner := network.identity.Public().(ed25519.PublicKey)
+	signature := ed25519.Sign(network.identity, storage.StoreMessage(hash, object.Data))
+	return network.replicate(ctx, hash,
+		func() error {
+			if err := network.localNode.StoreOwned(object.Data, hash, owner, signature); err != nil {
+				return err
+			}
+			if object.ContentType != "" {
+				network.localNode.SetContentType(hash, object.ContentType)
+			}
+			return nil
+		},
+		func(ctx context.Context, contact routing.Contact) bool {
+			return network.storeDataRPC(ctx, contact, hash, object, signature)
+		})
+}
+
+// Publish signs a new version of the mutable record of this node and stores it in the k closest nodes
+// to its key, just like Store. Returns the key of the record, its sequence number and the number of nodes that
+// stored it.
+func (network *Network) Publish(value []byte) (*routing.KademliaID, uint64, int, error) {
+	return network.PublishContext(context.Background(), value)
+}
+
+// PublishContext is Publish with a context, which stops the replication like in StoreContext
+func (network *Network) PublishContext(ctx context.Context, value []byte) (*routing.KademliaID, uint64, int, error) {
+	if len(value) > MAX_RECORD_VALUE_LEN {
+		return nil, 0, 0, errors.New("record value is too large")
+	}
+	sequence := network.nextRecordSequence()
+	record := storage.NewRecord(network.identity, sequence, value)
+	key := record.Key()
+	// The replicas of the previous version are not necessarily the ones that store this version
+	network.localNode.Forget(key)
+	replicas, err := network.replicate(ctx, key,
+		func() error {
+			return network.localNode.StoreRecord(record)
+		},
+		func(ctx context.Context, contact routing.Contact) bool {
+			return network.storeRecordRPC(ctx, contact, record)
+		})
+	return key, sequence, replicas, err
+}
+
+// nextRecordSequence returns the sequence number of a new record, which is higher than the one of every record this
+// node published before. It is based on the time so that it keeps growing if the node restarts
+func (network *Network) nextRecordSequence() uint64 {
+	for {
+		last := atomic.LoadUint64(&network.recordSequence)
+		sequence := uint64(network.clock.Now().UnixNano())
+		if sequence <= last {
+			sequence = last + 1
+		}
+		if atomic.CompareAndSwapUint64(&network.recordSequence, last, sequence) {
+			return sequence
+		}
+	}
+}
+
+// Resolve looks up the mutable record stored under some key. Every one of the k closest nodes to the key is asked
+// for the record, and the valid record with the highest sequence number wins, so that a node that still stores an
+// older version can't hide the newest one. Returns nil if no node has a record with a valid signature by the
+// owner of the key. The contacts are the nodes that returned the record, or the closest nodes if none did
+func (network *Network) Resolve(key *routing.KademliaID) (*storage.Record, []routing.Contact) {
+	record, nodes, _ := network.ResolveContext(context.Background(), key)
+	return record, nodes
+}
+
+// ResolveContext is Resolve with a context, which stops the lookup like in NodeLookupContext. Unlike DataLookup
+// the local copy of the record is only one of the candidates, and the records that are found are not cached
+func (network *Network) ResolveContext(ctx context.Context, key *routing.KademliaID) (*storage.Record, []routing.Contact, error) {
+	ctx, done := network.participate(ctx)
+	defer done()
+	nodes, err := network.NodeLookupContext(ctx, key)
+	if err != nil {
+		return nil, nodes, err
+	}
+
+	var newest *storage.Record
+	var holders []routing.Contact
+	consider := func(data []byte, contact routing.Contact) {
+		if data == nil {
+			return
+		}
+		record, err := storage.DeserializeRecord(data)
+		if err != nil || !record.Verify() || !record.Key().Equals(key) {
+			network.log.Warn("Found data that is not a valid record", "hash", key, "peer", contact.ID)
+			return
+		}
+		if newest == nil || record.Sequence > newest.Sequence {
+			newest, holders = record, []routing.Contact{contact}
+		} else if record.Sequence == newest.Sequence {
+			holders = append(holders, contact)
+		}
+	}
+	consider(network.localNode.LookupData(key), network.routingTable.Me())
+
+	// Every contact reports back exactly once, with nil data if it has no record
+	type found struct {
+		contact routing.Contact
+		data    []byte
+	}
+	results := make(chan found, len(nodes))
+	for _, contact := range nodes {
+		contact := contact
+		network.spawn(ctx, func(ctx context.Context) {
+			object, _, _ := network.findDataRPC(ctx, &contact, key)
+			results <- found{contact, object.data()}
+		})
+	}
+	for range nodes {
+		var result found
+		network.block(ctx, func() {
+			select {
+			case result = <-results:
+			case <-ctx.Done():
+			}
+		})
+		if ctx.Err() != nil {
+			return nil, nodes, ctx.Err()
+		}
+		consider(result.data, result.contact)
+	}
+	if newest == nil {
+		return nil, nodes, nil
+	}
+	network.log.Debug("Resolved record", "hash", key, "sequence", newest.Sequence, "holders", len(holders))
+	return newest, holders, nil
+}
+
+// Delete removes data that this node has stored from all of the k closest nodes to its hash, and stops
+// refreshing it. Only nodes that recorded this node as an owner of the data when it was stored will delete it.
+// Returns the number of nodes that deleted the data, including the local node
+func (network *Network) Delete(hash *routing.KademliaID) int {
+	deleted, _ := network.DeleteContext(context.Background(), hash)
+	return deleted
+}
+
+// DeleteContext is Delete with a context. The data is always forgotten and deleted locally, the nodes that
+// deleted it before the context was done are counted
+func (network *Network) DeleteContext(ctx context.Context, hash *routing.KademliaID) (int, error) {
+	ctx, done := network.participate(ctx)
+	defer done()
+	network.localNode.Forget(hash)
+	timestamp := network.clock.Now()
+	signature := ed25519.Sign(network.identity, storage.DeleteMessage(hash, timestamp))
+	owner := network.identity.Public().(ed25519.PublicKey)
+
+	deleted := 0
+	if network.localNode.Unpublish(hash, owner, timestamp, signature) == nil {
+		deleted++
+	}
+	nodes, err := network.NodeLookupContext(ctx, hash)
+	if err != nil {
+		return deleted, err
+	}
+	results := make(chan bool, len(nodes))
+	for _, contact := range nodes {
+		contact := contact
+		network.spawn(ctx, func(ctx context.Context) {
+			results <- network.deleteRPC(ctx, contact, hash, owner, timestamp, signature)
+		})
+	}
+	for range nodes {
+		var ok bool
+		network.block(ctx, func() {
+			select {
+			case ok = <-results:
+			case <-ctx.Done():
+			}
+		})
+		if ctx.Err() != nil {
+			network.log.Info("Stopped deleting data", "hash", hash, "deleted", deleted, "error", ctx.Err())
+			return deleted, ctx.Err()
+		}
+		if ok {
+			deleted++
+		}
+	}
+	network.log.Info("Deleted data", "hash", hash, "deleted", deleted)
+	return deleted, nil
+}
+
+// replicate stores something in the k closest nodes to a hash and waits (at most STORE_TIMEOUT) for them to
+// acknowledge it. storeLocal is used if the local node is one of the k closest, storeRemote for all other nodes.
+// Returns the number of nodes that stored the data and remembers them for refreshing.
+// Stops early with the error of the context when it is done, see StoreContext
+func (network *Network) replicate(ctx context.Context, hash *routing.KademliaID, storeLocal func() error,
+	storeRemote func(context.Context, routing.Contact) bool) (int, error) {
+	ctx, done := network.participate(ctx)
+	defer done()
+	nodes, err := network.NodeLookupContext(ctx, hash) // Get ALL nodes that are closest to the hash value
+	if err != nil {
+		return 0, err
+	}
+	me := network.routingTable.Me()
+	me.CalcDistance(hash)
+	if len(nodes) < k {
+		nodes = append(nodes, me)
+	} else if me.Distance.Less(nodes[len(nodes)-1].Distance) {
+		// If the locals node distance is less than the last node in the bucket,
+		// Im actually supposed to be in the bucket and not that node.
+		nodes[len(nodes)-1] = me
+	}
+	network.log.Debug("Storing data", "hash", hash, "nodes", len(nodes))
+
+	// Every contact reports back exactly once, nil if it did not store the data
+	// The channel is buffered so that late replies after the timeout don't block forever
+	stored := make(chan *routing.Contact, len(nodes))
+	for _,contact := range nodes { // What type of syntax is this??
+		contact := contact
+		if network.routingTable.Me().ID == contact.ID {
+			// No need to send a network request. Send the RPC directly to the local node thread.
+			if err := storeLocal(); err == nil {
+				stored <- &contact
+			} else {
+				network.log.Warn("Local node rejected the data", "hash", hash, "error", err)
+				stored <- nil
+			}
+		} else {
+			network.spawn(ctx, func(ctx context.Context) {
+				if storeRemote(ctx, contact) {
+					stored <- &contact
+				} else {
+					stored <- nil
+				}
+			})
+		}
+	}
+
+	var replicas []routing.Contact
+	timeout := network.clock.After(STORE_TIMEOUT * time.Millisecond)
+waitForAcks:
+	for i := 0; i < len(nodes); i++ {
+		var contact *routing.Contact
+		received, timedOut := false, false
+		network.block(ctx, func() {
+			select {
+			case contact = <-stored:
+				received = true
+			case <-timeout:
+				timedOut = true
+			case <-ctx.Done():
+			}
+		})
+		switch {
+		case received:
+			if contact != nil {
+				replicas = append(replicas, *contact)
+			}
+		case timedOut:
+			network.log.Warn("Timed out while waiting for STORE_ACKs", "hash", hash)
+			break waitForAcks
+		default:
+			err = ctx.Err()
+			network.log.Warn("Stopped waiting for STORE_ACKs", "hash", hash, "error", err)
+			break waitForAcks
+		}
+	}
+	network.log.Info("Stored data", "hash", hash, "replicas", len(replicas), "nodes", len(nodes))
+	if len(replicas) > 0 {
+		network.localNode.RememberContacts(hash, replicas)
+	}
+	return len(replicas), err
+}
+
+// findNodeRPC sends a FIND_NODE request to some contact with some targetID.
+// Returns the k closest nodes to the target ID, or why the contact didn't answer with them
+func (network *Network) findNodeRPC(ctx context.Context, contact *routing.Contact, targetID *routing.KademliaID) ([]routing.Contact, error) {
+	// Message format:
+	// SEND: [FIND_NODE, TARGET]
+	// REC:  [FIND_NODE_ACK, CONTACTS:[ID, IP]...]
+	reply, err := network.sendRequest(ctx, contact, network.newRequest(FIND_NODE, &targetRequest{*targetID}), true)
+	if err != nil {
+		network.log.Warn("Could not read reply", "rpc", "FIND_NODE", "peer", contact.ID, "error", err)
+		return nil, err
+	}
+
+	if reply.Type != FIND_NODE_ACK {
+		network.log.Warn("Received an invalid reply", "rpc", "FIND_NODE", "peer", contact.ID, "type", messageName(reply.Type))
+		return nil, ErrUnexpectedReply
+	}
+	kClosestReply, err := handleBucketReply(reply.Body.(*contactsReply).Contacts)
+	if err != nil {
+		network.log.Warn("Received an invalid reply", "rpc", "FIND_NODE", "peer", contact.ID, "error", err)
+		return nil, err
+	}
+
+	network.routingTable.KickTheBucket(contact, network.pinger(ctx))
+	return kClosestReply.GetContactsAndCalcDistances(targetID), nil
+}
+
+// findNodeRPC sends a FIND_DATA request to some contact with some targetID.
+// Returns the k closest nodes to the hash OR the object that matches the hash (in the hash, data pair)
+// or why the contact didn't answer with either. If it didn't, both the object and k closest contacts are nil.
+func (network *Network) findDataRPC(ctx context.Context, contact *routing.Contact, hash *routing.KademliaID) (*Object, []routing.Contact, error) {
+	network.log.Debug("Sending request", "rpc", "FIND_DATA", "peer", contact.ID, "hash", hash)
+	reply, err := network.sendRequest(ctx, contact, network.newRequest(FIND_DATA, &targetRequest{*hash}), true)
+	if err != nil {
+		network.log.Warn("Could not read reply", "rpc", "FIND_DATA", "peer", contact.ID, "error", err)
+		return nil, nil, err
+	}
+
+	if reply.Type == FIND_DATA_ACK_FAIL {
+		// Message format:
+		// REC: [FIND_DATA_ACK_FAIL, CONTACTS:[ID, IP]...]
+		// (This has the same format as findNodeAck)
+		kClosestReply, err := handleBucketReply(reply.Body.(*contactsReply).Contacts)
+		if err != nil {
+			network.log.Warn("Received an invalid reply", "rpc", "FIND_DATA", "peer", contact.ID, "error", err)
+			return nil, nil, err
+		}
+		network.routingTable.KickTheBucket(contact, network.pinger(ctx))
+		return nil, kClosestReply.GetContactsAndCalcDistances(hash), nil
+
+	} else if reply.Type == FIND_DATA_ACK_STREAM {
+		// The data is too large for a datagram, ask again over a stream
+		request := network.newRequest(FIND_DATA, &targetRequest{*hash})
+		request.stream = true
+		reply, err := network.sendRequest(ctx, contact, request, true)
+		if err == nil && reply.Type != FIND_DATA_ACK_SUCCESS {
+			err = ErrUnexpectedReply
+		}
+		if err != nil {
+			network.log.Warn("Could not read reply over a stream", "rpc", "FIND_DATA", "peer", contact.ID, "error", err)
+			return nil, nil, err
+		}
+		network.routingTable.KickTheBucket(contact, network.pinger(ctx))
+		return network.checkObject(contact, hash, newObject(reply.Body.(*dataReply)))
+	} else if reply.Type == FIND_DATA_ACK_SUCCESS {
+		// Message format:
+		// REC: [FIND_DATA_ACK_SUCCESS, DATA, CONTENT_TYPE]
+		network.routingTable.KickTheBucket(contact, network.pinger(ctx))
+		return network.checkObject(contact, hash, newObject(reply.Body.(*dataReply)))
+	} else {
+		network.log.Warn("Received an invalid reply", "rpc", "FIND_DATA", "peer", contact.ID, "type", messageName(reply.Type))
+		return nil, nil, ErrUnexpectedReply
+	}
+}
+
+// newObject returns the object in a FIND_DATA_ACK_SUCCESS
+func newObject(reply *dataReply) *Object {
+	return &Object{reply.Data, string(reply.ContentType)}
+}
+
+// checkObject returns an object that a contact sent for some hash, or ErrInvalidData if it is neither the data
+// of the hash nor a valid record stored under it, so that a peer can't make this node cache and serve anything else
+func (network *Network) checkObject(contact *routing.Contact, hash *routing.KademliaID, object *Object) (*Object, []routing.Contact, error) {
+	if routing.NewKademliaIDFromData(string(object.Data)).Equals(hash) {
+		return object, nil, nil
+	}
+	if record, err := storage.DeserializeRecord(object.Data); err == nil && record.Verify() && record.Key().Equals(hash) {
+		return object, nil, nil
+	}
+	network.log.Warn("Received an invalid reply", "rpc", "FIND_DATA", "peer", contact.ID, "hash", hash, "error", ErrInvalidData)
+	return nil, nil, ErrInvalidData
+}
+
+// storeDataRPC sends a STORE request to some contact with a hash value and an object, signed by this node
+// (see storage.StoreMessage). Returns true if the contact acknowledged that the data is stored (STORE_ACK), and
+// false if it rejected the data (STORE_NACK) or did not answer at all
+func (network *Network) storeDataRPC(ctx context.Context, contact routing.Contact, hash *routing.KademliaID, object *Object,
+	signature []byte) bool {
+	// Message format:
+	// SEND: [STORE, TARGET, DATA, OWNER, SIGNATURE, CONTENT_TYPE]
+	// REC: [STORE_ACK or STORE_NACK, REASON]
+	body := storeRequest{Target: *hash, Data: object.Data, ContentType: optionalString(object.ContentType)}
+	copy(body.Owner[:], network.identity.Public().(ed25519.PublicKey))
+	copy(body.Signature[:], signature)
+	return network.sendStoreRPC(ctx, contact, network.newRequest(STORE, &body), hash)
+}
+
+// storeRecordRPC sends a STORE_RECORD request to some contact with a signed record.
+// Returns true if the contact verified and stored the record, like storeDataRPC
+func (network *Network) storeRecordRPC(ctx context.Context, contact routing.Contact, record *storage.Record) bool {
+	// Message format:
+	// SEND: [STORE_RECORD, TARGET, RECORD]
+	// REC: [STORE_ACK or STORE_NACK, REASON]
+	key := record.Key()
+	request := network.newRequest(STORE_RECORD, &storeRecordRequest{*key, record.Serialize()})
+	return network.sendStoreRPC(ctx, contact, request, key)
+}
+
+// deleteRPC sends a DELETE request for some hash, signed by the owner of the data at some time (see
+// storage.DeleteMessage). Returns true if the contact deleted the data
+func (network *Network) deleteRPC(ctx context.Context, contact routing.Contact, hash *routing.KademliaID, owner ed25519.PublicKey,
+	timestamp time.Time, signature []byte) bool {
+	// Message format:
+	// SEND: [DELETE, TARGET, OWNER, SIGNATURE, TIMESTAMP]
+	// REC: [DELETE_ACK, REASON]
+	body := deleteRequest{Target: *hash}
+	copy(body.Owner[:], owner)
+	copy(body.Signature[:], signature)
+	binary.BigEndian.PutUint64(body.Timestamp[:], uint64(timestamp.UnixNano()))
+	reply, err := network.sendRequest(ctx, &contact, network.newRequest(DELETE, &body), true)
+	if err != nil {
+		network.log.Warn("Could not read reply", "rpc", "DELETE", "peer", contact.ID, "error", err)
+		return false
+	}
+	if reply.Type != DELETE_ACK {
+		network.log.Warn("Received an invalid reply", "rpc", "DELETE", "peer", contact.ID, "type", messageName(reply.Type))
+		return false
+	}
+	return reply.Body.(*reasonReply).Reason == ACCEPT_DELETED
+}
+
+// sendStoreRPC sends a STORE or STORE_RECORD request and waits for the STORE_ACK
+func (network *Network) sendStoreRPC(ctx context.Context, contact routing.Contact, request *message, hash *routing.KademliaID) bool {
+	reply, err := network.sendRequest(ctx, &contact, request, true)
+	if err != nil {
+		network.log.Warn("Could not read reply", "rpc", messageName(request.Type), "peer", contact.ID, "error", err)
+		return false
+	}
+	if reply.Type == STORE_NACK {
+		network.log.Warn("Request was rejected", "rpc", messageName(request.Type), "peer", contact.ID, "hash", hash,
+			"reason", rejectReason(reply.Body.(*reasonReply).Reason))
+	}
+	return reply.Type == STORE_ACK
+}
+
+// messageName returns the name of a message type, as used in the logs
+func messageName(msgType byte) string {
+	switch msgType {
+	case PING:
+		return "PING"
+	case PING_ACK:
+		return "PING_ACK"
+	case STORE:
+		return "STORE"
+	case STORE_NACK:
+		return "STORE_NACK"
+	case FIND_NODE:
+		return "FIND_NODE"
+	case FIND_NODE_ACK:
+		return "FIND_NODE_ACK"
+	case REFRESH_DATA_TTL:
+		return "REFRESH_DATA_TTL"
+	case STORE_ACK:
+		return "STORE_ACK"
+	case FIND_DATA:
+		return "FIND_DATA"
+	case FIND_DATA_ACK_SUCCESS:
+		return "FIND_DATA_ACK_SUCCESS"
+	case FIND_DATA_ACK_FAIL:
+		return "FIND_DATA_ACK_FAIL"
+	case STORE_RECORD:
+		return "STORE_RECORD"
+	case DELETE:
+		return "DELETE"
+	case DELETE_ACK:
+		return "DELETE_ACK"
+	case FIND_DATA_ACK_STREAM:
+		return "FIND_DATA_ACK_STREAM"
+	default:
+		return "UNKNOWN(" + strconv.Itoa(int(msgType)) + ")"
+	}
+}
+
+// rejectReason returns a human readable description of the reason sent in a STORE_NACK or DELETE_ACK
+func rejectReason(reason byte) string {
+	switch reason {
+	case REJECT_QUOTA:
+		return "storage quota exceeded"
+	case REJECT_INVALID_RECORD:
+		return "invalid record"
+	case REJECT_STALE_RECORD:
+		return "a newer record is already stored"
+	case REJECT_INVALID_SIGNATURE:
+		return "invalid signature"
+	case REJECT_NOT_OWNER:
+		return "not an owner of the data"
+	case REJECT_NOT_FOUND:
+		return "no data is stored at the hash"
+	case REJECT_HASH_MISMATCH:
+		return "the hash is not the hash of the data"
+	case REJECT_STALE_DELETE:
+		return "the delete request is too old or was replayed"
+	default:
+		return "unknown reason"
+	}
+}
+
+// We don't want to send back the requester its own ID so that it has itself in its own bucket.
+// removeSelfOrTail therefore grabs a bucket (of size k+1) and either remove the requesterID if it exists,
+// or the tail (the furthest one away of the nodes) if it doesn't.
+// Removing tail is optional (you don't want to do this if bucket is already less than k)
+func removeSelfOrTail(requesterID *routing.KademliaID, bucket []routing.Contact, removeTail bool) []routing.Contact {
+	for index, contact := range bucket {
+		if *requesterID == *contact.ID {
+			bucket = append(bucket[:index], bucket[index + 1:]...)
+			return bucket
+		} else {
+		}
+	}
+	if removeTail {
+		return bucket[:len(bucket)-1]
+	}
+	return bucket
+}
+
+// addNewNodes adds new nodes from the current iteration to the unvisited collection
+// It avoids duplicates, which means that all nodes in unvisited + visited will be unique (ID vise)
+func addNewNodes(visited *routing.ContactCandidates, unvisited *routing.ContactCandidates,
+	newNodes []routing.Contact) {
+	allOld := *visited // All nodes from the previous rounds that we have seen, visited and unvisited
+	allOld.Append(unvisited.Contacts)
+	var toBeAdded routing.ContactCandidates
+	for i := 0; i < len(newNodes); i++ {
+		// Check for duplicates among the nodes from prev rounds (visited and unvisited)
+		// Check for duplicates among newNodes
+		if !allOld.Contains(&newNodes[i]) && !toBeAdded.Contains(&newNodes[i]) {
+			toBeAdded.AppendContact(newNodes[i])
+		}
+	}
+	unvisited.Append(toBeAdded.Contacts)
+}
+
+// visitedKClosest checks if the NodeLookup (and DataLookup) algorithm is finished by
+// comparing the known k closest nodes to the visited nodes
+// returns either true (finished) or false (not finished). See implementation comments for more detail
+func visitedKClosest(unvisited *routing.ContactCandidates, visited *routing.ContactCandidates, k int) bool {
+	visited.Sort()
+	unvisited.Sort()
+
+	// There are no new contacts to visit! We must be done, regardless of how many nodes
+	// we have already visited (name of function should change?)
+	if unvisited.Len() == 0 {
+		return true
+	}
+
+	// If we have visited k or more nodes, we could POTENTIALLY be done. Let's dive deeper ...
+	if visited.Len() >= k {
+		// If the last contact in visited is closer than the first contact in unvisited,
+		// all visited contacts are the closest. We are done.
+		if visited.Contacts[k-1].Less(&unvisited.Contacts[0]) {
+			return true
+		}
+		// Otherwise, there is a contact that is part of the k closest collection that
+		// we have not yet visited.
+		return false
+	} else {
+		// If we have nodes to visit (unvisited.Len() > 0) but we have not yet visited k nodes,
+		// we are not done
+		return false
+	}
+}
+
+// doWideSearch checks if the next iteration in the NodeLookup (or DataLookup) algorithm should be a
+// "wide search" or not by comparing the distance of some new contacts (this iteration)
+// to some already known contacts (previous iterations)
+// Definition of wide search: "If a round of FIND_NODEs fails to return a node any closer than the closest already seen,
+// the initiator resends the FIND_NODE to all of the closest k nodes it has not already queried"
+func doWideSearch(newContacts *[]routing.Contact, closest routing.Contact) bool {
+	for _, contact := range *newContacts {
+		if contact.Less(&closest) {
+			return false
+		}
+	}
+	return true
+}
+
+// handleBucketReply takes the contacts of a FIND_NODE_ACK or FIND_DATA_ACK_FAIL and converts them into a bucket
+// (collection of contacts)
+// Returns an error if there are more than k contacts
+func handleBucketReply(contacts []wireContact) (routing.Bucket, error) {
+	if len(contacts) > k {
+		return routing.Bucket{}, errors.New("bucket reply contains more than k contacts")
+	}
+	result := *routing.NewBucket()
+	for _, received := range contacts {
+		id := received.ID
+		IP := net.IP(received.IP[:])
+		contact := routing.NewContact(&id, IP.String())
+		contact.Streams = received.Flags&FLAG_STREAMS != 0
+		result.AddContact(contact)
+	}
+	return result, nil
+}
+
+// setSearchSize returns the number of nodes to visit this iteration.
+// The size is dependent on the boolean wideSearch (if wide search is enabled or not)
+// and how many unvisited nodes there are
+func setSearchSize(wideSearch bool, unvisitedNodes *routing.ContactCandidates) int {
+	var result int
+	if wideSearch {
+		// Grab <=k nodes to visit
+		wideSearch = false
+		result = k
+	} else {
+		// Grab <=alpha nodes to visit
+		result = alpha
+	}
+	if result > unvisitedNodes.Len() {
+		result = unvisitedNodes.Len()
+	}
+	return result
+}
+
+// postIterationProcessing does all the things that is required after an iteration of contacting
+// nodes is completed in NodeLookup (or DataLookup). This includes:
+// 		moving visited nodes from the unvisited collection to the visited collection
+// 		calling addNewNodes
+//      calling and returning the result of doWideSearch
+func postIterationProcessing(visited *routing.ContactCandidates, unvisited *routing.ContactCandidates,
+	newRoundNodes *[]routing.Contact, searchRange int) bool {
+	if unvisited.Len() > 0 {
+		visited.Append(unvisited.Contacts[:searchRange])
+		visited.Sort()
+
+		// Nothing is visited yet if every contact of the first round failed
+		wideSearch := false
+		if visited.Len() > 0 {
+			wideSearch = doWideSearch(newRoundNodes, visited.Contacts[0])
+		}
+
+		unvisited.Contacts = unvisited.Contacts[searchRange:]
+		addNewNodes(visited, unvisited, *newRoundNodes)
+		return wideSearch
+	}
+	return false
+}
\ No newline at end of file
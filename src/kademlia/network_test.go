@@ -0,0 +1,1373 @@
+package kademlia
+
+import (
+	"context"
+	"crypto/ed25519"
+	"d7024e/codec"
+	"d7024e/routing"
+	"d7024e/storage"
+	"d7024e/transport"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newMemoryNetwork creates the network of a node on an in-memory network, with the same limits as UDP and TCP
+func newMemoryNetwork(fake *transport.MemoryNetwork, ip *net.IP) Network {
+	return NewNetwork(ip, fake.NewTransport(ip.String(), KAD_PORT, MAX_PACKET_SIZE, TIMEOUT*time.Millisecond),
+		fake.NewTransport(ip.String(), STREAM_PORT, transport.MAX_STREAM_MESSAGE_SIZE, STREAM_TIMEOUT*time.Millisecond))
+}
+
+func TestRemoveSelfOrTail(t *testing.T) {
+	// We need to test these cases:
+	// Case 1. Remove self from slice correctly
+	//    a) with removeTail
+	//    b) without removeTail
+	// Case 2. Not remove self from slice ...
+	//    a) and remove tail
+	//    b) and NOT remove tail
+
+	mockReqIP := net.IP{192, 0, 0, 1}
+	requesterID := routing.NewKademliaIDFromIP(&mockReqIP)
+	var b routing.ContactCandidates
+
+	// Case 1. Adding a bunch of ID's and one that matches requesterID
+	b.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000001"), "0"))
+	b.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000002"), "0"))
+	b.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000003"), "0"))
+	b.AppendContact(routing.NewContact(requesterID, "0"))
+	oldSize := b.Len()
+	result := removeSelfOrTail(requesterID, b.Contacts, true)
+	newSize := len(result)
+	// requesterID should NOT be in the collection and collection should be size-1 from before
+	if newSize != oldSize - 1 {
+		t.Errorf("removeSelfOrTail did not remove an element when it should. Case 1a")
+	}
+	for _, e := range result {
+		if *e.ID == *requesterID {
+			t.Errorf("removeSelfOrTail did not remove requester ID properly. Case 1a")
+		}
+	}
+
+	b = routing.ContactCandidates{}
+	b.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000001"), "0"))
+	b.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000002"), "0"))
+	b.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000003"), "0"))
+	b.AppendContact(routing.NewContact(requesterID, ""))
+	oldSize = b.Len()
+	result = removeSelfOrTail(requesterID, b.Contacts, false)
+	newSize = len(result)
+	// requesterID should NOT be in the collection and collection should be size-1 from before
+	if newSize != oldSize - 1 {
+		t.Errorf("removeSelfOrTail removed an element when it shouldn't. Case 1b")
+	}
+	for _, e := range result {
+		if *e.ID == *requesterID {
+			t.Errorf("removeSelfOrTail did not remove requester ID properly. Case 1b")
+		}
+	}
+
+	// Case 2
+	b = routing.ContactCandidates{}
+	b.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000001"), "0"))
+	b.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000002"), "0"))
+	b.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000003"), "0"))
+	b.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000004"), "0"))
+	oldSize = b.Len()
+	result = removeSelfOrTail(requesterID, b.Contacts, true)
+	newSize = len(result)
+	// last element should be gone and collection should be size-1 from before
+	if newSize != oldSize - 1 {
+		t.Errorf("removeSelfOrTail did not remove an element when it should. Case 2a.")
+	}
+	for _, e := range result {
+		if *e.ID == *requesterID {
+			t.Errorf("removeSelfOrTail did not remove requester ID properly. Case 2a")
+		}
+	}
+
+	// Case 2
+	b = routing.ContactCandidates{}
+	b.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000001"), "0"))
+	b.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000002"), "0"))
+	b.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000003"), "0"))
+	b.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000004"), "0"))
+	oldSize = b.Len()
+	result = removeSelfOrTail(requesterID, b.Contacts, false)
+	newSize = len(result)
+	// last element should be gone and collection should be same size from before
+	if newSize != oldSize {
+		t.Errorf("removeSelfOrTail removed an element when it shouldn't. Case 2b.")
+	}
+	for _, e := range result {
+		if *e.ID == *requesterID {
+			t.Errorf("removeSelfOrTail did not remove requester ID properly. Case 2b")
+		}
+	}
+}
+
+func TestAddNewNodes(t *testing.T) {
+	var v, u, n routing.ContactCandidates
+	v.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000001"), "0"))
+	v.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000002"), "0"))
+	v.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000003"), "0"))
+
+	u.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000004"), "0"))
+	u.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000005"), "0"))
+	u.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000006"), "0"))
+
+	// Check for visited duplicates
+	n.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000001"), "0"))
+	// Check for unvisited duplicates
+	n.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000004"), "0"))
+	// Check for unique new one
+	n.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000007"), "0"))
+
+	addNewNodes(&v, &u, n.GetContacts(3))
+
+	foundCount := 0
+	for _, e := range u.Contacts {
+		if *e.ID == *routing.NewKademliaID("0000000000000000000000000000000000000001") {
+			foundCount++
+		}
+	}
+	if foundCount > 1 {
+		t.Errorf("addNewNodes added a node that already exists in visited.")
+	}
+
+	foundCount = 0
+	for _, e := range u.Contacts {
+		if *e.ID == *routing.NewKademliaID("0000000000000000000000000000000000000004") {
+			foundCount++
+		}
+	}
+	if foundCount > 1 {
+		t.Errorf("addNewNodes added a node that already exists in unvisited.")
+	}
+
+	foundCount = 0
+	for _, e := range u.Contacts {
+		if *e.ID == *routing.NewKademliaID("0000000000000000000000000000000000000007") {
+			foundCount++
+		}
+	}
+	if foundCount < 1 {
+		t.Errorf("addNewNodes did not add a unique node for some reason.")
+	}
+}
+
+func TestVisitedKClosest(t *testing.T) {
+	k := 2
+	var v, u routing.ContactCandidates
+
+	// Case 1: Have visited at least k contacts and all k contacts are the closest.
+	// Should return true.
+	v.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000000"), "0"))
+	v.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000000"), "0"))
+	v.Contacts[0].Distance = routing.NewKademliaID("0000000000000000000000000000000000000000")
+	v.Contacts[1].Distance = routing.NewKademliaID("0000000000000000000000000000000000000001")
+
+	u.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000000"), "0"))
+	u.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000000"), "0"))
+	u.Contacts[0].Distance = routing.NewKademliaID("0000000000000000000000000000000000000002")
+	u.Contacts[1].Distance = routing.NewKademliaID("0000000000000000000000000000000000000003")
+
+	if !visitedKClosest(&u, &v, k) {
+		t.Errorf("TestVisitedKClosest error case 1")
+	}
+
+	v = routing.ContactCandidates{}
+	u = routing.ContactCandidates{}
+	// Case 2: Have visited at least k contacts and all k EXCEPT 1 contact is the closest.
+	// Should return false
+	v.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000000"), "0"))
+	v.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000000"), "0"))
+	v.Contacts[0].Distance = routing.NewKademliaID("0000000000000000000000000000000000000000")
+	v.Contacts[1].Distance = routing.NewKademliaID("0000000000000000000000000000000000000002")
+
+	u.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000000"), "0"))
+	u.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000000"), "0"))
+	u.Contacts[0].Distance = routing.NewKademliaID("0000000000000000000000000000000000000001")
+	u.Contacts[1].Distance = routing.NewKademliaID("0000000000000000000000000000000000000003")
+
+	if visitedKClosest(&u, &v, k) {
+		t.Errorf("TestVisitedKClosest error case 2")
+	}
+
+	v = routing.ContactCandidates{}
+	u = routing.ContactCandidates{}
+	// Case 3: We have not visited k closest but no more unvisited to visit. Should return true
+	v.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000000"), "0"))
+	v.Contacts[0].Distance = routing.NewKademliaID("0000000000000000000000000000000000000000")
+
+	if !visitedKClosest(&u, &v, k) {
+		t.Errorf("TestVisitedKClosest error case 3")
+	}
+
+	v = routing.ContactCandidates{}
+	u = routing.ContactCandidates{}
+	// Case 4: We have not visited k nodes yet, but there are more to visit. Should return false
+	v.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000000"), "0"))
+	v.Contacts[0].Distance = routing.NewKademliaID("0000000000000000000000000000000000000000")
+
+	u.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000001"), "0"))
+	u.Contacts[0].Distance = routing.NewKademliaID("0000000000000000000000000000000000000000")
+
+	if visitedKClosest(&u, &v, k) {
+		t.Errorf("TestVisitedKClosest error case 3")
+	}
+}
+
+func TestDoWideSearch(t *testing.T) {
+	closest := routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000000"), "0")
+
+	var c []routing.Contact
+	c = append(c, routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000000"), "0"))
+	c = append(c, routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000000"), "0"))
+
+	// Case 1, closest is actually closest.
+	// Should be WideSearch (round failed to return any node closer)
+	closest.Distance = routing.NewKademliaID("0000000000000000000000000000000000000000")
+	c[0].Distance = routing.NewKademliaID("0000000000000000000000000000000000000001")
+	c[1].Distance = routing.NewKademliaID("0000000000000000000000000000000000000002")
+	if !doWideSearch(&c, closest) {
+		t.Errorf("TestDoWideSearch error case 1")
+	}
+
+	// Case 2, closest is actually NOT the closest.
+	// Should not be WideSearch (round found a new closer node)
+	closest.Distance = routing.NewKademliaID("0000000000000000000000000000000000000001")
+	c[0].Distance = routing.NewKademliaID("0000000000000000000000000000000000000000")
+	c[1].Distance = routing.NewKademliaID("0000000000000000000000000000000000000002")
+	if doWideSearch(&c, closest) {
+		t.Errorf("TestDoWideSearch error case 2")
+	}
+}
+
+func TestHandleBucketReply(t *testing.T) {
+	testNrContacts := 4
+	b := make([]wireContact, testNrContacts)
+	IPs := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"}
+	IDs := []routing.KademliaID{*routing.NewKademliaID("0000000000000000000000000000000000000000"),
+		                *routing.NewKademliaID("0000000000000000000000000000000000000001"),
+		                *routing.NewKademliaID("0000000000000000000000000000000000000002"),
+		                *routing.NewKademliaID("0000000000000000000000000000000000000003")}
+
+	if len(IPs) != len(IDs) {
+		fmt.Println("TestHandleBucketReply ERROR: IP and ID array are different sizes")
+	}
+
+	for i := 0; i < testNrContacts; i++ {
+		b[i].ID = IDs[i]
+		copy(b[i].IP[:], net.ParseIP(IPs[i]).To4())
+	}
+
+	temp, err := handleBucketReply(b)
+	if err != nil || temp.Len() != 4 {
+		t.Errorf("handleBucketReply returned a bucket with incorrect size")
+	}
+
+	result := temp.GetContactsAndCalcDistances(routing.NewKademliaID("0000000000000000000000000000000000000000"))
+	// if each contact formed by IDs and IPs aren't in result, error
+	for i := 0; i < testNrContacts; i++ {
+		for j, c := range result {
+			if IDs[i] == *c.ID && IPs[i] == c.Address {
+				// found! Continue
+				break
+			} else {
+				// not found ...
+				if j == testNrContacts - 1 {
+					// and we are at the last contact in result. Means the contact doesn't exist
+					// and handleBucketReply doesn't extract it correctly. Error
+					t.Errorf("handleBucketReply did not extract test element %d correctly", i)
+				}
+			}
+		}
+	}
+}
+
+// A hostile reply should be an error, not a panic or a read past the end of the message
+func TestHandleBucketReply_Invalid(t *testing.T) {
+	// More than k contacts
+	if _, err := handleBucketReply(make([]wireContact, k+1)); err == nil {
+		t.Errorf("handleBucketReply accepted a bucket larger than k")
+	}
+
+	// No contacts at all is a valid (empty) bucket
+	if result, err := handleBucketReply(nil); err != nil || result.Len() != 0 {
+		t.Errorf("handleBucketReply() = %v, want %v", err, nil)
+	}
+}
+
+// Decodes fuzzed replies like findNodeRPC does
+func FuzzHandleBucketReply(f *testing.F) {
+	for _, encoding := range []codec.Encoding{codec.TLV, codec.CBOR} {
+		for _, contacts := range [][]wireContact{nil, make([]wireContact, 2), make([]wireContact, k+1)} {
+			msg, _ := encoding.Encode(codec.NewHeader(FIND_NODE_ACK, 1, [routing.ID_LEN]byte{}), &contactsReply{contacts})
+			f.Add(msg)
+		}
+	}
+	f.Fuzz(func(t *testing.T, msg []byte) {
+		header, body, _, err := codec.Decode(msg, newReplyBody)
+		if err != nil || header.Type != FIND_NODE_ACK {
+			return
+		}
+		result, err := handleBucketReply(body.(*contactsReply).Contacts)
+		if err == nil && result.Len() > k {
+			t.Errorf("handleBucketReply returned a bucket with %d contacts", result.Len())
+		}
+	})
+}
+
+func TestSetSearchSize(t *testing.T) {
+	// case 1: we should visit k nodes if wideSearch and c contains at least k nodes
+	var c routing.ContactCandidates
+	for i := 0; i < k; i++ {
+		c.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000000"), "0"))
+	}
+	if setSearchSize(true, &c) != k {
+		t.Errorf("TestSetSearchSize case 1")
+	}
+
+	// case 2: we should visit alpha nodes if NOT wideSearch and c contains at least alpha nodes
+	c = routing.ContactCandidates{}
+	for i := 0; i < alpha; i++ {
+		c.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000000"), "0"))
+	}
+	if setSearchSize(false, &c) != alpha {
+		t.Errorf("TestSetSearchSize case 2")
+	}
+
+	// case 3a: we should visit c.Len() nodes if c.Len() < alpha <= k ...
+	// (regardless of wideSearch)
+	c = routing.ContactCandidates{}
+	for i := 0; i < alpha-1; i++ {
+		c.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000000"), "0"))
+	}
+	c3a := setSearchSize(false, &c)
+	c3b := setSearchSize(true, &c)
+	if c3a != c.Len() && c3b != c.Len() {
+		t.Errorf("TestSetSearchSize case 3a")
+	}
+
+	// case 3b: ... OR alpha <= c.len <= k
+	c = routing.ContactCandidates{}
+	for i := 0; i < k-1; i++ {
+		c.AppendContact(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000000"), "0"))
+	}
+	c3c := setSearchSize(false, &c)
+	c3d := setSearchSize(true, &c)
+	if c3c != c.Len() && c3d != c.Len() {
+		t.Errorf("TestSetSearchSize case 3b")
+	}
+}
+
+func TestPostIterationProcessing(t *testing.T) {
+	// We only have to test that postIterationProcessing moves contacts from unvisited to visited correctly.
+	// Testing addNewNodes and doWideSearch is done separately (see this file)
+	// We assume that postIterationProcessing will return the same value as doWideSearch
+	// because it is IMPOSSIBLE TO MESS UP (1 LINE ASSIGNMENT)
+
+	// We are checking that movement works for a
+	// searchRange = min, searchRange = max and min < searchRange < max
+	var v, u routing.ContactCandidates
+	var n []routing.Contact
+	c1 := routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000001"), "0")
+	c2 := routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000002"), "0")
+	c3 := routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000003"), "0")
+	c4 := routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000004"), "0")
+	c1.Distance = routing.NewKademliaID("0000000000000000000000000000000000000000")
+	c2.Distance = routing.NewKademliaID("0000000000000000000000000000000000000000")
+	c3.Distance = routing.NewKademliaID("0000000000000000000000000000000000000000")
+	c4.Distance = routing.NewKademliaID("0000000000000000000000000000000000000000")
+	u.AppendContact(c1)
+	u.AppendContact(c2)
+	u.AppendContact(c3)
+
+	s := 2
+	// ID 1 and 2 should be moved
+	postIterationProcessing(&v, &u, &n, s)
+	if !v.Contains(&c1) || !v.Contains(&c2) || v.Contains(&c3) || u.Contains(&c1) || u.Contains(&c2) || !u.Contains(&c3){
+		t.Errorf("postIterationProcessing did not move contacts properly from " +
+			"unvisited to visited when searchRange = %d", s)
+	}
+
+	v = routing.ContactCandidates{}
+	u = routing.ContactCandidates{}
+	v.AppendContact(c4) // has to have at least 1 contact
+	u.AppendContact(c1)
+	u.AppendContact(c2)
+	u.AppendContact(c3)
+	s = 0
+	// none should be moved
+	postIterationProcessing(&v, &u, &n, s)
+	if v.Len() > 1 {
+		t.Errorf("postIterationProcessing did not move contacts properly from " +
+			"unvisited to visited when searchRange = %d", s)
+	}
+
+	v = routing.ContactCandidates{}
+	// every contact of the first round failed, so there is nothing in visited
+	postIterationProcessing(&v, &u, &n, s)
+	if v.Len() > 0 || u.Len() != 3 {
+		t.Errorf("postIterationProcessing did not move contacts properly from " +
+			"unvisited to visited when searchRange = %d and nothing is visited", s)
+	}
+
+	v = routing.ContactCandidates{}
+	u = routing.ContactCandidates{}
+	u.AppendContact(c1)
+	u.AppendContact(c2)
+	u.AppendContact(c3)
+	s = 3
+	// all should be moved
+	postIterationProcessing(&v, &u, &n, s)
+	if !v.Contains(&c1) || !v.Contains(&c2) || !v.Contains(&c3) || u.Contains(&c1) || u.Contains(&c2) || u.Contains(&c3){
+		t.Errorf("postIterationProcessing did not move contacts properly from " +
+			"unvisited to visited when searchRange = %d", s)
+	}
+
+	v = routing.ContactCandidates{}
+	u = routing.ContactCandidates{}
+	s = 100
+	// all should be moved
+	postIterationProcessing(&v, &u, &n, s)
+	if v.Len() > 0 || u.Len() > 0 {
+		t.Errorf("postIterationProcessing did not move contacts properly from " +
+			"unvisited to visited when searchRange = %d", s)
+	}
+}
+
+// Check if the listen function can be terminated from another thread.
+func TestNetwork_Listen(t *testing.T) {
+	// The home IP and network message simulator
+	type fields struct {
+		ip *net.IP
+		transport transport.Transport
+	}
+	tests := []struct {
+		name   string
+		fields fields
+	}{
+		{"", fields{&net.IP{},transport.NewMemoryNetwork().NewTransport("", KAD_PORT, MAX_PACKET_SIZE, TIMEOUT*time.Millisecond)}},
+		{"UDP", fields{&net.IP{},transport.NewUDPTransport("0", TIMEOUT*time.Millisecond)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			network := NewNetwork(tt.fields.ip,tt.fields.transport,nil)
+			wait := make(chan bool)
+			go func() {
+				network.Listen()
+				wait <- true
+			}()
+			time.Sleep(500*time.Millisecond)	// Give the network some time to boot up
+			network.shutdown()
+			select {
+			case <- wait:
+				return
+			case <-time.After(1*time.Second):
+				t.Errorf("Listen() = %v, want %v", "running", "stopped")
+			}
+		})
+	}
+}
+
+// Start two nodes with all of their workers, serve HTTP from one of them and stop both of them again
+func TestNetwork_StartStop(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	ip1 := net.ParseIP("0.0.0.0")
+	ip2 := net.ParseIP("0.0.0.1")
+	net1 := newMemoryNetwork(fake, &ip1)
+	net2 := newMemoryNetwork(fake, &ip2)
+	hash := routing.NewKademliaIDFromData("Hello world!")
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := net2.Get(r.Context(), hash)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	})
+
+	if err := net2.Serve("127.0.0.1:0", handler); err != ErrNodeNotStarted {
+		t.Errorf("Serve() = %v, want %v", err, ErrNodeNotStarted)
+	}
+	if err := net1.Start(); err != nil {
+		t.Errorf("Start() = %v, want %v", err, nil)
+	}
+	if err := net2.Start(); err != nil {
+		t.Errorf("Start() = %v, want %v", err, nil)
+	}
+	if err := net2.Start(); err != ErrNodeStarted {
+		t.Errorf("Start() = %v, want %v", err, ErrNodeStarted)
+	}
+	if err := net2.Serve("127.0.0.1:0", handler); err != nil {
+		t.Errorf("Serve() = %v, want %v", err, nil)
+	}
+	if err := net2.Serve("127.0.0.1:0", handler); err != ErrHTTPStarted {
+		t.Errorf("Serve() = %v, want %v", err, ErrHTTPStarted)
+	}
+	time.Sleep(10*time.Millisecond)
+	if err := net2.Join(routing.NewKademliaIDFromIP(&ip1), "0.0.0.0"); err != nil {
+		t.Errorf("Join() = %v, want %v", err, nil)
+	}
+
+	net1.Store([]byte("Hello world!"), hash)
+	response, err := http.Get("http://" + net2.HTTPAddress() + "/")
+	if err != nil || response.StatusCode != http.StatusOK {
+		t.Errorf("GET = %v, %v, want %v", response, err, http.StatusOK)
+	} else {
+		response.Body.Close()
+	}
+
+	for _, network := range []*Network{&net1, &net2} {
+		stopped := make(chan error)
+		go func() {
+			stopped <- network.Stop()
+		}()
+		select {
+		case err := <-stopped:
+			if err != nil {
+				t.Errorf("Stop() = %v, want %v", err, nil)
+			}
+		case <-time.After(HTTP_SHUTDOWN_TIMEOUT * time.Millisecond):
+			t.Errorf("Stop() = %v, want %v", "running", "stopped")
+		}
+	}
+	if _, err := http.Get("http://" + net2.HTTPAddress() + "/"); err == nil {
+		t.Errorf("GET = %v, want an error", err)
+	}
+}
+
+// A listener served by a node is closed when the node is stopped
+func TestNetwork_ServeListener(t *testing.T) {
+	ip := net.ParseIP("0.0.0.0")
+	network := newMemoryNetwork(transport.NewMemoryNetwork(), &ip)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve := func(listener net.Listener) error {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return err
+			}
+			conn.Close()
+		}
+	}
+
+	if err := network.ServeListener(listener, serve); err != ErrNodeNotStarted {
+		t.Errorf("ServeListener() = %v, want %v", err, ErrNodeNotStarted)
+	}
+	network.Start()
+	if err := network.ServeListener(listener, serve); err != nil {
+		t.Errorf("ServeListener() = %v, want %v", err, nil)
+	}
+	if err := network.Stop(); err != nil {
+		t.Errorf("Stop() = %v, want %v", err, nil)
+	}
+	if conn, err := net.Dial("tcp", listener.Addr().String()); err == nil {
+		conn.Close()
+		t.Errorf("Dial() = %v, want an error", err)
+	}
+}
+
+// A node stops itself if one of its workers fails
+func TestNetwork_StartFails(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	ip := net.ParseIP("0.0.0.0")
+	net1 := newMemoryNetwork(fake, &ip)
+	net2 := newMemoryNetwork(fake, &ip)
+
+	if err := net1.Start(); err != nil {
+		t.Errorf("Start() = %v, want %v", err, nil)
+	}
+	time.Sleep(10*time.Millisecond)
+	// The address of the transports is already in use
+	if err := net2.Start(); err != nil {
+		t.Errorf("Start() = %v, want %v", err, nil)
+	}
+	if err := net2.Wait(); err == nil {
+		t.Errorf("Wait() = %v, want an error", err)
+	}
+	if err := net1.Stop(); err != nil {
+		t.Errorf("Stop() = %v, want %v", err, nil)
+	}
+}
+
+// Try to join another node with both valid and invalid information
+func TestNetwork_Join(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	// Set up IP addresses
+	ip1 := net.ParseIP("0.0.0.0")
+	ip2 := net.ParseIP("0.0.0.1")
+
+	// create two networks
+	net1 := newMemoryNetwork(fake, &ip1)
+	net2 := newMemoryNetwork(fake, &ip2)
+
+	// Start to listen on one network
+	net1_chan := make(chan bool)
+	go func() {
+		net1.Listen()
+		net1_chan <- true
+	}()
+	time.Sleep(50*time.Millisecond)
+	error := net2.Join(routing.NewKademliaIDFromIP(&ip1),"0.0.0.0")
+
+	// Finally join the network.
+	if error != nil {
+		t.Errorf("Join() = %v, want %v", "Failed to join", "Succesful join")
+	}
+	net1.shutdown()
+	<-net1_chan
+
+	net2 = newMemoryNetwork(fake, &ip2)
+
+	// This should not work. The network has already shut down.
+	error = net2.Join(routing.NewKademliaIDFromIP(&ip1),"0.0.0.0")
+	if error == nil {
+		t.Errorf("Join() = %v, want %v", "Succesful join","Failed to join")
+	}
+}
+
+// Store some data in a network
+func TestNetwork_Store(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+
+	// Set up IP addresses
+	ip1 := net.ParseIP("0.0.0.0")
+	ip2 := net.ParseIP("0.0.0.1")
+
+	// Set up two networks
+	net1 := newMemoryNetwork(fake, &ip1)
+	net2 := newMemoryNetwork(fake, &ip2)
+
+	// Store some valid data
+	data := []byte("Hello world!")
+	net1.Store(data, routing.NewKademliaIDFromData(string(data)))
+	net1_chan := make(chan bool)
+	go func() {
+		net1.Listen()
+		net1_chan <- true
+	}()
+	time.Sleep(50*time.Millisecond)
+	error := net2.Join(routing.NewKademliaIDFromIP(&ip1),"0.0.0.0")
+	if error != nil {
+		t.Errorf("Store() failed to create a connection. Check if join passed testing")
+	}
+
+	// Verify that the data has been stored on one node but not the other.
+	result,_ := net2.DataLookup(routing.NewKademliaIDFromData(string(data)))
+
+	if string(result[:12]) != string(data) {
+		t.Errorf("Store() = %v, want %v", string(data),string(result))
+	}
+
+	// Now test with both nodes.
+	data = []byte("Another text")
+	replicas := net2.Store(data, routing.NewKademliaIDFromData(string(data)))
+	if replicas != 2 {
+		t.Errorf("Store() = %v, want %v", replicas, 2)
+	}
+	result,_ = net2.DataLookup(routing.NewKademliaIDFromData(string(data)))
+
+	if string(result[:12]) != string(data) {
+		t.Errorf("Store() = %v, want %v", string(data),string(result))
+	}
+
+	// Finally shut down
+	net1.shutdown()
+	<-net1_chan
+
+}
+
+// Nodes that send CBOR and nodes that send TLV can work together
+func TestNetwork_MixedEncodings(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+
+	ip1 := net.ParseIP("0.0.0.0")
+	ip2 := net.ParseIP("0.0.0.1")
+	net1 := newMemoryNetwork(fake, &ip1)
+	net2 := newMemoryNetwork(fake, &ip2)
+	net2.encoding = codec.CBOR
+
+	net1_chan := make(chan bool)
+	go func() {
+		net1.Listen()
+		net1_chan <- true
+	}()
+	net2_chan := make(chan bool)
+	go func() {
+		net2.Listen()
+		net2_chan <- true
+	}()
+	time.Sleep(50*time.Millisecond)
+	if err := net2.Join(routing.NewKademliaIDFromIP(&ip1), "0.0.0.0"); err != nil {
+		t.Errorf("Join() = %v, want %v", err, nil)
+	}
+
+	data := []byte("Hello CBOR!")
+	hash := routing.NewKademliaIDFromData(string(data))
+	if replicas := net2.Store(data, hash); replicas != 2 {
+		t.Errorf("Store() = %v, want %v", replicas, 2)
+	}
+	if string(net1.localNode.LookupData(hash)) != string(data) {
+		t.Errorf("LookupData() = %v, want %v", string(net1.localNode.LookupData(hash)), string(data))
+	}
+	// The TLV node replies in CBOR
+	net2.localNode.Delete(hash)
+	if result, _ := net2.DataLookup(hash); string(result) != string(data) {
+		t.Errorf("DataLookup() = %v, want %v", string(result), string(data))
+	}
+
+	net2.shutdown()
+	<-net2_chan
+	net1.shutdown()
+	<-net1_chan
+
+}
+
+func TestNetwork_LargeData(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+
+	ip1 := net.ParseIP("0.0.0.0")
+	ip2 := net.ParseIP("0.0.0.1")
+	net1 := newMemoryNetwork(fake, &ip1)
+	net2 := newMemoryNetwork(fake, &ip2)
+
+	net1_chan := make(chan bool)
+	go func() {
+		net1.Listen()
+		net1_chan <- true
+	}()
+	net2_chan := make(chan bool)
+	go func() {
+		net2.Listen()
+		net2_chan <- true
+	}()
+	time.Sleep(50*time.Millisecond)
+	if err := net2.Join(routing.NewKademliaIDFromIP(&ip1), "0.0.0.0"); err != nil {
+		t.Errorf("Join() = %v, want %v", err, nil)
+	}
+
+	// Too large for a datagram, so the STORE and the FIND_DATA reply are sent over streams
+	data := make([]byte, 5*MAX_PACKET_SIZE)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	hash := routing.NewKademliaIDFromData(string(data))
+	if replicas := net2.Store(data, hash); replicas != 2 {
+		t.Errorf("Store() = %v, want %v", replicas, 2)
+	}
+	if string(net1.localNode.LookupData(hash)) != string(data) {
+		t.Errorf("LookupData() = %v bytes, want %v bytes", len(net1.localNode.LookupData(hash)), len(data))
+	}
+	net2.localNode.Delete(hash)
+	if result, _ := net2.DataLookup(hash); string(result) != string(data) {
+		t.Errorf("DataLookup() = %v bytes, want %v bytes", len(result), len(data))
+	}
+
+	// A contact that doesn't accept streams can't be sent large messages
+	contact := routing.NewContact(routing.NewKademliaIDFromIP(&ip1), "0.0.0.0")
+	request := net2.newRequest(STORE, &storeRequest{Target: *hash, Data: data})
+	if _, err := net2.sendRequest(context.Background(), &contact, request, true); err != ErrStreamsUnsupported {
+		t.Errorf("sendRequest() = %v, want %v", err, ErrStreamsUnsupported)
+	}
+	// And isn't told to ask again over a stream
+	for _, flags := range []byte{FLAG_STREAMS, 0} {
+		request = net2.newRequest(FIND_DATA, &targetRequest{*hash})
+		request.Flags = flags
+		var replyType byte
+		net1.handleRequest(request, func(reply []byte) error {
+			header, _, _, err := codec.Decode(reply, newReplyBody)
+			replyType = header.Type
+			return err
+		})
+		want := FIND_DATA_ACK_SUCCESS
+		if flags&FLAG_STREAMS != 0 {
+			want = FIND_DATA_ACK_STREAM
+		}
+		if replyType != want {
+			t.Errorf("handleRequest() with flags %d replied %v, want %v", flags, messageName(replyType), messageName(want))
+		}
+	}
+
+	net2.shutdown()
+	<-net2_chan
+	net1.shutdown()
+	<-net1_chan
+
+}
+
+func TestNetwork_NodeLookup(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+
+	// Set up IP addresses.
+	ip1 := net.ParseIP("0.0.0.0")
+
+	ip2 := net.ParseIP("0.0.0.1")
+
+	ip3 := net.ParseIP("0.0.0.2")
+
+	// Set up networks
+	net1 := newMemoryNetwork(fake, &ip1)
+	net2 := newMemoryNetwork(fake, &ip2)
+	net3 := newMemoryNetwork(fake, &ip3)
+
+	// Start to listen on all 3 networks
+	net1_chan := make(chan bool)
+	go func() {
+		net1.Listen()
+		net1_chan <- true
+	}()
+	net2_chan := make(chan bool)
+	go func() {
+		net2.Listen()
+		net2_chan <- true
+	}()
+	net3_chan := make(chan bool)
+	go func() {
+		net3.Listen()
+		net3_chan <- true
+	}()
+
+	// Now join
+	time.Sleep(50*time.Millisecond)
+	error := net2.Join(routing.NewKademliaIDFromIP(&ip1),"0.0.0.0")
+	if error != nil {
+		t.Errorf("NodeLookup() failed to create a connection. Check if join passed testing")
+	}
+	error = net3.Join(routing.NewKademliaIDFromIP(&ip1),"0.0.0.0")
+	if error != nil {
+		t.Errorf("NodeLookup() failed to create a connection. Check if join passed testing")
+	}
+
+	// Try to find a node.
+	contacts := net3.NodeLookup(routing.NewKademliaIDFromIP(&ip1))
+	if len(contacts) != 2 {
+		t.Errorf("NodeLookup() = %v, want %v", len(contacts), 2)
+	}
+
+	// Finally shut down
+	net1.shutdown()
+	net2.shutdown()
+	net3.shutdown()
+	<- net1_chan
+	<- net2_chan
+	<- net3_chan
+
+}
+
+func TestNetwork_DataLookup(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+
+	// Set up IP addresses
+	ip1 := net.ParseIP("0.0.0.0")
+
+	ip2 := net.ParseIP("0.0.0.1")
+
+	ip3 := net.ParseIP("0.0.0.2")
+
+	// Set up networks
+	net1 := newMemoryNetwork(fake, &ip1)
+	net2 := newMemoryNetwork(fake, &ip2)
+	net3 := newMemoryNetwork(fake, &ip3)
+
+	// Start to listen for connections
+	net1_chan := make(chan bool)
+	go func() {
+		net1.Listen()
+		net1_chan <- true
+	}()
+	net2_chan := make(chan bool)
+	go func() {
+		net2.Listen()
+		net2_chan <- true
+	}()
+	net3_chan := make(chan bool)
+	go func() {
+		net3.Listen()
+		net3_chan <- true
+	}()
+	time.Sleep(50*time.Millisecond)
+	error := net2.Join(routing.NewKademliaIDFromIP(&ip1),"0.0.0.0")
+	if error != nil {
+		t.Errorf("DataLookup() failed to create a connection. Check if join passed testing")
+	}
+
+	// Now store some data on two nodes
+	data := []byte("Hello world!")
+	net1.Store(data, routing.NewKademliaIDFromData(string(data)))
+
+	error = net3.Join(routing.NewKademliaIDFromIP(&ip1),"0.0.0.0")
+	if error != nil {
+		t.Errorf("DataLookup() failed to create a connection. Check if join passed testing")
+	}
+
+	// Try to find the data on the third node.
+	result,contacts := net3.DataLookup(routing.NewKademliaIDFromData(string(data)))
+	if len(contacts) != 1 {
+		t.Errorf("DataLookup() = %v, want %v", len(contacts), 1)
+	} else if string(result[:12]) != string(data){
+		t.Errorf("DataLookup() = %v, want %v", string(result), string(data))
+	}
+
+	// Now store on all 3 nodes and try to find the data.
+	data = []byte("Another hello!")
+	result,contacts = net3.DataLookup(routing.NewKademliaIDFromData(string(data)))
+	if len(contacts) != 2 {
+		t.Errorf("DataLookup() = %v, want %v", len(contacts), 2)
+	}
+	if result != nil{
+		t.Errorf("DataLookup() = %v, want %v", string(result), string(data))
+	}
+
+	// Data that doesn't match the hash is neither returned nor cached
+	poisoned := routing.NewKademliaIDFromData("poisoned")
+	net1.localNode.Store([]byte("something else"), poisoned)
+	if result, _ = net3.DataLookup(poisoned); result != nil {
+		t.Errorf("DataLookup() = %v, want %v", string(result), nil)
+	}
+	if net3.localNode.LookupData(poisoned) != nil {
+		t.Errorf("DataLookup() cached data that doesn't match the hash")
+	}
+
+	// Finally shut down
+	net1.shutdown()
+	net2.shutdown()
+	net3.shutdown()
+	<- net1_chan
+	<- net2_chan
+	<- net3_chan
+
+}
+
+// newSilentNetwork creates a network whose only contact (0.0.0.1) receives requests but never answers them,
+// and which waits up to 10 seconds for replies. Call the returned function to stop the silent contact
+func newSilentNetwork(fake *transport.MemoryNetwork) (*Network, func()) {
+	ip1 := net.ParseIP("0.0.0.0")
+	ip2 := net.ParseIP("0.0.0.1")
+	network := NewNetwork(&ip1, fake.NewTransport("0.0.0.0", KAD_PORT, MAX_PACKET_SIZE, 10*time.Second), nil)
+	network.routingTable.AddContact(routing.NewContact(routing.NewKademliaIDFromIP(&ip2), "0.0.0.1"))
+
+	silent := fake.NewTransport("0.0.0.1", KAD_PORT, MAX_PACKET_SIZE, TIMEOUT*time.Millisecond)
+	go silent.Listen(func(ctx context.Context, request []byte, from string, reply func([]byte) error) {})
+	time.Sleep(10 * time.Millisecond)
+	return &network, func() { silent.Close() }
+}
+
+// Lookups and stores stop waiting for replies as soon as their context is done
+func TestNetwork_LookupContext(t *testing.T) {
+	network, stop := newSilentNetwork(transport.NewMemoryNetwork())
+	defer stop()
+	hash := routing.NewKademliaIDFromData("Hello world!")
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := network.DataLookupContext(ctx, hash); err != context.DeadlineExceeded {
+		t.Errorf("DataLookupContext() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("DataLookupContext() took %v, want less than %v", elapsed, time.Second)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := network.NodeLookupContext(ctx, hash); err != context.Canceled {
+		t.Errorf("NodeLookupContext() = %v, want %v", err, context.Canceled)
+	}
+	// Nothing is stored if the closest nodes could not be found
+	if replicas, err := network.StoreContext(ctx, []byte("Hello world!"), hash); replicas != 0 || err != context.Canceled {
+		t.Errorf("StoreContext() = %v, %v, want %v, %v", replicas, err, 0, context.Canceled)
+	}
+	if network.localNode.LookupData(hash) != nil {
+		t.Errorf("StoreContext() stored the data after the context was done")
+	}
+
+	// Bootstrapping can be given a deadline too
+	silentIP := net.ParseIP("0.0.0.1")
+	silent := routing.NewKademliaIDFromIP(&silentIP)
+	start = time.Now()
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := network.JoinContext(ctx, silent, "0.0.0.1"); err != context.DeadlineExceeded {
+		t.Errorf("JoinContext() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	contact := routing.NewContact(silent, "0.0.0.1")
+	if network.PingContext(ctx, &contact) {
+		t.Errorf("PingContext() = %v, want %v", true, false)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("JoinContext() took %v, want less than %v", elapsed, time.Second)
+	}
+}
+
+// Publish a record and a newer version of it, and resolve it from another node
+func TestNetwork_PublishResolve(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+
+	ip1 := net.ParseIP("0.0.0.0")
+	ip2 := net.ParseIP("0.0.0.1")
+	ip3 := net.ParseIP("0.0.0.2")
+
+	net1 := newMemoryNetwork(fake, &ip1)
+	net2 := newMemoryNetwork(fake, &ip2)
+	net3 := newMemoryNetwork(fake, &ip3)
+
+	net1_chan := make(chan bool)
+	go func() {
+		net1.Listen()
+		net1_chan <- true
+	}()
+	time.Sleep(50*time.Millisecond)
+	error := net2.Join(routing.NewKademliaIDFromIP(&ip1),"0.0.0.0")
+	if error != nil {
+		t.Errorf("Publish() failed to create a connection. Check if join passed testing")
+	}
+
+	key, _, replicas, _ := net2.Publish([]byte("first"))
+	if replicas != 2 {
+		t.Errorf("Publish() = %v, want %v", replicas, 2)
+	}
+	// net3 is not one of the replicas, and must not keep serving the first version once the second is published
+	net3.Join(routing.NewKademliaIDFromIP(&ip1), "0.0.0.0")
+	if record, _ := net3.Resolve(key); record == nil || string(record.Value) != "first" {
+		t.Errorf("Resolve() = %v, want %v", record, "first")
+	}
+	newKey, sequence, replicas, _ := net2.Publish([]byte("second"))
+	if replicas != 2 {
+		t.Errorf("Publish() = %v, want %v", replicas, 2)
+	}
+	if !key.Equals(newKey) {
+		t.Errorf("Publish() = %v, want %v", newKey.String(), key.String())
+	}
+
+	// net1 stores a replica of the record
+	record, _ := net1.Resolve(key)
+	if record == nil || string(record.Value) != "second" || record.Sequence != sequence {
+		t.Errorf("Resolve() = %v, want %v with sequence %v", record, "second", sequence)
+	}
+	if net3.localNode.LookupData(key) != nil {
+		t.Errorf("Resolve() cached the record")
+	}
+	if record, _ := net3.Resolve(key); record == nil || string(record.Value) != "second" {
+		t.Errorf("Resolve() = %v, want %v", record, "second")
+	}
+
+	net1.shutdown()
+	<-net1_chan
+
+}
+
+// Records that are published at the same time get different sequence numbers, the last of which is RecordSequence
+func TestNetwork_PublishConcurrently(t *testing.T) {
+	ip := net.ParseIP("0.0.0.0")
+	network := newMemoryNetwork(transport.NewMemoryNetwork(), &ip)
+
+	sequences := make([]uint64, 10)
+	var wg sync.WaitGroup
+	for i := range sequences {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, sequences[i], _, _ = network.Publish([]byte("value " + strconv.Itoa(i)))
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[uint64]bool)
+	var highest uint64
+	for _, sequence := range sequences {
+		if seen[sequence] {
+			t.Errorf("Publish() = %v twice", sequence)
+		}
+		seen[sequence] = true
+		if sequence > highest {
+			highest = sequence
+		}
+	}
+	if network.RecordSequence() != highest {
+		t.Errorf("RecordSequence() = %v, want %v", network.RecordSequence(), highest)
+	}
+}
+
+// Only the node that stored some data is allowed to delete it from the network
+func TestNetwork_Delete(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+
+	ip1 := net.ParseIP("0.0.0.0")
+	ip2 := net.ParseIP("0.0.0.1")
+
+	net1 := newMemoryNetwork(fake, &ip1)
+	net2 := newMemoryNetwork(fake, &ip2)
+
+	net1_chan := make(chan bool)
+	go func() {
+		net1.Listen()
+		net1_chan <- true
+	}()
+	time.Sleep(50*time.Millisecond)
+	error := net2.Join(routing.NewKademliaIDFromIP(&ip1),"0.0.0.0")
+	if error != nil {
+		t.Errorf("Delete() failed to create a connection. Check if join passed testing")
+	}
+
+	data := []byte("Hello world!")
+	hash := routing.NewKademliaIDFromData(string(data))
+	net2.Store(data, hash)
+
+	// net1 stores a replica but is not the owner
+	if deleted := net1.Delete(hash); deleted != 0 {
+		t.Errorf("Delete() = %v, want %v", deleted, 0)
+	}
+	if deleted := net2.Delete(hash); deleted != 2 {
+		t.Errorf("Delete() = %v, want %v", deleted, 2)
+	}
+	if net1.localNode.LookupData(hash) != nil || net2.localNode.LookupData(hash) != nil {
+		t.Errorf("Delete() did not remove the data from all nodes")
+	}
+	if len(net2.localNode.RefreshContacts()[*hash]) != 0 {
+		t.Errorf("Delete() did not stop refreshing the data")
+	}
+
+	// A DELETE request that was already accepted can't delete the data again once it is stored again
+	contact := routing.NewContact(routing.NewKademliaIDFromIP(&ip1), "0.0.0.0")
+	owner := net2.identity.Public().(ed25519.PublicKey)
+	timestamp := time.Now()
+	signature := ed25519.Sign(net2.identity, storage.DeleteMessage(hash, timestamp))
+	net2.Store(data, hash)
+	if !net2.deleteRPC(context.Background(), contact, hash, owner, timestamp, signature) {
+		t.Errorf("deleteRPC() = %v, want %v", false, true)
+	}
+	net2.Store(data, hash)
+	if net2.deleteRPC(context.Background(), contact, hash, owner, timestamp, signature) {
+		t.Errorf("deleteRPC() = %v, want %v", true, false)
+	}
+	if net1.localNode.LookupData(hash) == nil {
+		t.Errorf("deleteRPC() deleted the data with a replayed request")
+	}
+	old := timestamp.Add(-storage.DELETE_MAX_AGE*time.Millisecond - time.Second)
+	if net2.deleteRPC(context.Background(), contact, hash, owner, old, ed25519.Sign(net2.identity, storage.DeleteMessage(hash, old))) {
+		t.Errorf("deleteRPC() = %v, want %v", true, false)
+	}
+
+	// A STORE must be signed by its owner, and can't make net2 an owner of the record of net1
+	key, _, _, _ := net1.Publish([]byte("record"))
+	publicKey := net1.identity.Public().(ed25519.PublicKey)
+	tests := []struct {
+		name       string
+		data       []byte
+		hash       *routing.KademliaID
+		signature  []byte
+		wantReason byte
+	}{
+		{"Unsigned", data, hash, nil, REJECT_INVALID_SIGNATURE},
+		{"Not the hash of the data", data, key, ed25519.Sign(net2.identity, storage.StoreMessage(key, data)),
+			REJECT_HASH_MISMATCH},
+		{"Key of a record", publicKey, key, ed25519.Sign(net2.identity, storage.StoreMessage(key, publicKey)),
+			REJECT_NOT_OWNER},
+	}
+	for _, tt := range tests {
+		body := storeRequest{Target: *tt.hash, Data: tt.data}
+		copy(body.Owner[:], net2.identity.Public().(ed25519.PublicKey))
+		copy(body.Signature[:], tt.signature)
+		reply, err := net2.sendRequest(context.Background(), &contact, net2.newRequest(STORE, &body), true)
+		if err != nil || reply.Type != STORE_NACK || reply.Body.(*reasonReply).Reason != tt.wantReason {
+			t.Errorf("%s: sendRequest() = %v, %v, want reason %v", tt.name, reply, err, rejectReason(tt.wantReason))
+		}
+	}
+	if deleted := net2.Delete(key); deleted != 0 {
+		t.Errorf("Delete() = %v, want %v", deleted, 0)
+	}
+	if record, _ := net2.Resolve(key); record == nil {
+		t.Errorf("Resolve() = %v, want the record of net1", record)
+	}
+
+	net1.shutdown()
+	<-net1_chan
+
+}
+
+// Data that is stored on one side of a partition can't be found on the other side until it heals
+func TestNetwork_SimulatedPartition(t *testing.T) {
+	cluster := newTestCluster(t, 2, 40, transport.LinkConfig{Latency: time.Millisecond})
+	defer cluster.stop()
+	nodes := cluster.nodes
+
+	var a, b []string
+	for i, node := range nodes {
+		if i < len(nodes)/2 {
+			a = append(a, node.routingTable.Me().Address)
+		} else {
+			b = append(b, node.routingTable.Me().Address)
+		}
+	}
+	cluster.sim.Partition("a", a...)
+	cluster.sim.Partition("b", b...)
+
+	data := []byte("split brain")
+	hash := routing.NewKademliaIDFromData(string(data))
+	if replicas, _ := nodes[0].StoreContext(cluster.ctx, data, hash); replicas == 0 || replicas > len(a) {
+		t.Errorf("Store() = %v, want between %v and %v", replicas, 1, len(a))
+	}
+	if result, _, _ := nodes[len(nodes)-1].DataLookupContext(cluster.ctx, hash); result != nil {
+		t.Errorf("DataLookup() = %v, want %v", string(result), nil)
+	}
+
+	cluster.sim.Heal()
+	if result, _, _ := nodes[len(nodes)-1].DataLookupContext(cluster.ctx, hash); string(result) != string(data) {
+		t.Errorf("DataLookup() = %v, want %v", string(result), string(data))
+	}
+}
+
+// Data survives when nodes leave a lossy network
+func TestNetwork_SimulatedChurn(t *testing.T) {
+	cluster := newTestCluster(t, 3, 100, transport.LinkConfig{Latency: 2 * time.Millisecond, Jitter: 5 * time.Millisecond,
+		Loss: 0.05, Duplication: 0.05})
+	defer cluster.stop()
+	rng := rand.New(rand.NewSource(3))
+
+	data := make(map[routing.KademliaID]string)
+	for i := 0; i < 10; i++ {
+		value := "churn " + strconv.Itoa(i)
+		data[*cluster.store(rng.Intn(len(cluster.nodes)), value)] = value
+	}
+
+	// A fifth of the nodes leave without a word
+	for len(cluster.running()) > len(cluster.nodes)*4/5 {
+		cluster.leave(rng.Intn(len(cluster.nodes)))
+	}
+	running := cluster.running()
+	for hash, value := range data {
+		hash := hash
+		node := running[rng.Intn(len(running))]
+		if result, _, _ := cluster.nodes[node].DataLookupContext(cluster.ctx, &hash); string(result) != value {
+			t.Errorf("DataLookup() = %v, want %v", string(result), value)
+		}
+	}
+}
+
+// A thousand nodes fit in one test
+func TestNetwork_SimulatedLargeCluster(t *testing.T) {
+	cluster := newTestCluster(t, 4, 1000, transport.LinkConfig{Latency: time.Millisecond})
+	defer cluster.stop()
+
+	cluster.assertLookupHops(999, cluster.nodes[500].routingTable.Me().ID, maxLookupHops(len(cluster.nodes)))
+}
+
+// Malformed and unknown messages should be counted and dropped
+func TestNetwork_checkRequest(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	ip := net.ParseIP("0.0.0.0")
+	network := newMemoryNetwork(fake, &ip)
+
+	header := func(msgType byte) codec.Header {
+		return codec.NewHeader(msgType, 1, *network.routingTable.Me().ID)
+	}
+
+	if _, err := network.checkRequest([]byte{}); err != ErrMalformedMessage {
+		t.Errorf("checkRequest() = %v, want %v", err, ErrMalformedMessage)
+	}
+	// A STORE without data and owner
+	msg, _ := codec.TLV.Encode(header(STORE), &targetRequest{})
+	if _, err := network.checkRequest(msg); err != ErrMalformedMessage {
+		t.Errorf("checkRequest() = %v, want %v", err, ErrMalformedMessage)
+	}
+	// A FIND_NODE with a target that is too short
+	msg, _ = codec.CBOR.Encode(header(FIND_NODE), &struct{ Target [4]byte `kad:"1"` }{})
+	if _, err := network.checkRequest(msg); err != ErrMalformedMessage {
+		t.Errorf("checkRequest() = %v, want %v", err, ErrMalformedMessage)
+	}
+	// A reply is not a request
+	msg, _ = codec.TLV.Encode(header(PING_ACK), &emptyMessage{})
+	if _, err := network.checkRequest(msg); err != ErrUnknownMessage {
+		t.Errorf("checkRequest() = %v, want %v", err, ErrUnknownMessage)
+	}
+	// Fields that the node doesn't know about are ignored
+	msg, _ = codec.CBOR.Encode(header(PING), &struct{ New []byte `kad:"200"` }{[]byte("from the future")})
+	if request, err := network.checkRequest(msg); err != nil || request.encoding != codec.CBOR {
+		t.Errorf("checkRequest() = %v, want %v", err, nil)
+	}
+	if network.malformedMessages != 3 || network.unknownMessages != 1 {
+		t.Errorf("checkRequest() counted %d malformed and %d unknown messages, want 3 and 1",
+			network.malformedMessages, network.unknownMessages)
+	}
+}
+
+// A hostile datagram must never crash a node
+func FuzzUnpackMessage(f *testing.F) {
+	fake := transport.NewMemoryNetwork()
+	ip := net.ParseIP("0.0.0.0")
+	network := newMemoryNetwork(fake, &ip)
+	// Replies are dropped
+	respond := func([]byte) error { return nil }
+
+	for _, encoding := range []codec.Encoding{codec.TLV, codec.CBOR} {
+		for _, request := range []*message{
+			network.newRequest(PING, &emptyMessage{}),
+			network.newRequest(FIND_NODE, &targetRequest{}),
+			network.newRequest(FIND_DATA, &targetRequest{}),
+			network.newRequest(STORE, &storeRequest{Data: []byte{1}}),
+			network.newRequest(STORE_RECORD, &storeRecordRequest{Record: make([]byte, storage.RECORD_HEADER_LEN+5)}),
+			network.newRequest(DELETE, &deleteRequest{}),
+			network.newRequest(REFRESH_DATA_TTL, &targetRequest{}),
+		} {
+			msg, _ := encoding.Encode(request.Header, request.Body)
+			f.Add(msg)
+		}
+	}
+	f.Add([]byte{255, 1, 2})
+	f.Fuzz(func(t *testing.T, msg []byte) {
+		network.unpackMessage(msg, respond)
+	})
+}
+
+// This just checks an invalid message type. Nothing fancy going on here.
+func TestNetwork_unpackMessage(t *testing.T) {
+	fake := transport.NewMemoryNetwork()
+	ip1 := net.ParseIP("0.0.0.0")
+	ip2 := net.ParseIP("0.0.0.1")
+
+	net1 := newMemoryNetwork(fake, &ip1)
+	net2 := newMemoryNetwork(fake, &ip2)
+
+	net1_chan := make(chan bool)
+	go func() {
+		net1.Listen()
+		net1_chan <- true
+	}()
+	net2_chan := make(chan bool)
+	go func() {
+		net2.Listen()
+		net2_chan <- true
+	}()
+	time.Sleep(50*time.Millisecond)
+	error := net2.Join(routing.NewKademliaIDFromIP(&ip1),"0.0.0.0")
+
+	if error != nil {
+		t.Errorf("unpackMessage() = %v, want %v", "Failed to join", "Succesful join")
+	}
+	net2.shutdown()
+	<-net2_chan
+
+	net1.shutdown()
+	<-net1_chan
+
+	{
+		if err := net1.unpackMessage([]byte{255},nil); err == nil {
+			t.Errorf("unpackMessage() = %v, want %v", err.Error(), "received unknown request")
+		}
+	}
+}
\ No newline at end of file
@@ -0,0 +1,29 @@
+package kademlia
+
+import (
+	"context"
+	"errors"
+)
+
+// Messages that don't fit in a datagram (MAX_PACKET_SIZE) are sent over a stream (TCP) instead. Routing traffic
+// stays on UDP, only large STOREs (and STORE_RECORDs) and the replies to FIND_DATA requests for large data use
+// streams. A node advertises that it accepts streams with FLAG_STREAMS in the header of its messages and in the
+// contacts it sends, and messages are never sent over a stream to a contact that hasn't advertised it.
+
+// A stream carries a single request and its reply (see the TCP transport of the transport package)
+
+const STREAM_PORT = "5002"  // Port number used for streams between nodes
+const STREAM_TIMEOUT = 2000 // Amount of time a request and its reply over a stream may take in milliseconds
+
+var ErrStreamsUnsupported = errors.New("message is too large for a datagram and the contact does not accept streams")
+
+// handleStream handles a request that was received over a stream and sends the reply back over the same stream.
+// Stream requests don't update the routing table, the UDP requests that come before them do that
+func (network *Network) handleStream(ctx context.Context, msg []byte, from string, respond func([]byte) error) {
+	request, err := network.checkRequest(msg)
+	if err != nil {
+		return
+	}
+	request.stream = true
+	network.handleRequest(request, respond)
+}
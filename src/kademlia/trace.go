@@ -0,0 +1,94 @@
+package kademlia
+
+import (
+	"context"
+	"d7024e/routing"
+	"d7024e/transport"
+	"time"
+)
+
+// A lookup can record a trace of its rounds: which contacts were queried, whether they answered (and why not),
+// which contacts they returned and how long they took. Lookups without a trace record nothing, the methods of
+// a nil trace do nothing.
+
+// LookupTrace is the trace of a single lookup
+type LookupTrace struct {
+	Kind     string // "node" or "data"
+	Target   *routing.KademliaID
+	Started  time.Time
+	Duration time.Duration
+	Rounds   []TraceRound
+	Local    bool // The data of a data lookup was found in the local storage, without sending any RPCs
+}
+
+// TraceRound is a round of RPCs of a lookup
+type TraceRound struct {
+	Queries []TraceQuery
+}
+
+// TraceQuery is a single RPC of a lookup
+type TraceQuery struct {
+	Contact   routing.Contact // With its distance to the target
+	Answered  bool
+	Err       error // Why the contact didn't answer, nil if it did
+	Duration  time.Duration
+	Returned  []routing.Contact // The contacts in the reply, with their distance to the target
+	FoundData bool              // The contact answered a FIND_DATA with the data
+}
+
+// TimedOut returns true if the contact didn't answer in time
+func (query *TraceQuery) TimedOut() bool {
+	return transport.IsTimeout(query.Err)
+}
+
+func newLookupTrace(kind string, target *routing.KademliaID, started time.Time) *LookupTrace {
+	return &LookupTrace{Kind: kind, Target: target, Started: started}
+}
+
+// startRound starts recording a new round
+func (trace *LookupTrace) startRound() {
+	if trace != nil {
+		trace.Rounds = append(trace.Rounds, TraceRound{})
+	}
+}
+
+// addQuery records an RPC of the current round, and the error of the RPC if it failed
+func (trace *LookupTrace) addQuery(contact routing.Contact, err error, duration time.Duration, returned []routing.Contact,
+	foundData bool) {
+	if trace != nil {
+		round := &trace.Rounds[len(trace.Rounds)-1]
+		round.Queries = append(round.Queries, TraceQuery{contact, err == nil, err, duration, returned, foundData})
+	}
+}
+
+// finish records when the lookup ended
+func (trace *LookupTrace) finish(now time.Time) {
+	if trace != nil {
+		trace.Duration = now.Sub(trace.Started)
+	}
+}
+
+// RPCs returns the number of RPCs the lookup sent
+func (trace *LookupTrace) RPCs() int {
+	rpcs := 0
+	for _, round := range trace.Rounds {
+		rpcs += len(round.Queries)
+	}
+	return rpcs
+}
+
+// NodeLookupTrace is NodeLookupContext, and also returns the trace of the lookup
+func (network *Network) NodeLookupTrace(ctx context.Context, lookupID *routing.KademliaID) ([]routing.Contact, *LookupTrace, error) {
+	trace := newLookupTrace("node", lookupID, network.clock.Now())
+	contacts, err := network.nodeLookup(ctx, lookupID, trace)
+	trace.finish(network.clock.Now())
+	return contacts, trace, err
+}
+
+// DataLookupTrace is DataLookupContext, and also returns the trace of the lookup
+func (network *Network) DataLookupTrace(ctx context.Context, hash *routing.KademliaID) ([]byte, []routing.Contact, *LookupTrace, error) {
+	trace := newLookupTrace("data", hash, network.clock.Now())
+	object, contacts, err := network.dataLookup(ctx, hash, trace)
+	trace.finish(network.clock.Now())
+	return object.data(), contacts, trace, err
+}
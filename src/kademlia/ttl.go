@@ -0,0 +1,61 @@
+package kademlia
+
+import (
+	"context"
+	"d7024e/routing"
+	"d7024e/storage"
+	"time"
+)
+
+// How often (ms) the data this node has stored is refreshed. Has to be lower than storage.TIME_TO_LIVE
+const REMEMBER_UPDATE_FREQ = 5 * 1000
+
+// Remember runs a loop that sends refreshRPCs to all contact that is associated with some data that has been
+// added with Put (or Store), until the context is done.
+// Runs local Refresh directly if one of the contacts are this node
+func (network *Network) Remember(ctx context.Context) {
+	if REMEMBER_UPDATE_FREQ >= storage.TIME_TO_LIVE {
+		network.ttlLog.Error("Update frequency of ttl refreshing is lower than the system wide TTL parameter. "+
+			"No stored data will live for long ...", "frequency", REMEMBER_UPDATE_FREQ, "ttl", storage.TIME_TO_LIVE)
+	}
+	for {
+		network.refreshAll()
+		select {
+		case <-network.clock.After(time.Duration(REMEMBER_UPDATE_FREQ) * time.Millisecond):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// refreshAll refreshes the ttl of the data of every Store that this local node has initiated and not forgotten
+func (network *Network) refreshAll() {
+	// For each contact list associated to some data hash
+	for dataHash, contacts := range network.localNode.RefreshContacts() {
+		dataHash := dataHash
+		for _, c := range contacts {
+			if c.ID.Equals(network.routingTable.Me().ID) {
+				// Invoke local refresh directly, no reason to send RPCs to self
+				network.ttlLog.Debug("Refreshing data", "hash", dataHash, "peer", "self")
+				network.localNode.Refresh(&dataHash)
+			} else {
+				network.ttlLog.Debug("Refreshing data", "hash", dataHash, "peer", c.ID)
+				network.refreshRPC(c, &dataHash)
+			}
+		}
+	}
+}
+
+// refreshRPC sends a REFRESH_DATA_TTL message to some kademlia node which will invoke the local
+// node.Refresh function, effectively resetting the ttl for some hashed data so that the data
+// won't be deleted
+func (network *Network) refreshRPC(contact routing.Contact, hash *routing.KademliaID) {
+	// Message format:
+	// SEND: [REFRESH_DATA_TTL, TARGET]
+	// REC: nothing
+	_, err := network.sendRequest(context.Background(), &contact, network.newRequest(REFRESH_DATA_TTL, &targetRequest{*hash}), false)
+	if err != nil {
+		network.ttlLog.Warn("Could not send request", "rpc", "REFRESH_DATA_TTL", "peer", contact.ID,
+			"address", contact.Address, "error", err)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,238 @@
+// Package logging is a small leveled logger with key/value fields, in the spirit of log/slog (which needs a newer
+// Go than the nodes are built with). Every entry is a single line in logfmt, so that the logs of many nodes can be
+// searched with grep:
+//
+//	time=2026-10-19T12:00:00.000Z level=INFO subsystem=network node=3f2a... msg="Joined network" peer=172.20.0.3
+//
+// Every subsystem of a node (network, ttl, storage, transport, http, cli, ...) logs with its own Logger, and the
+// verbosity of each subsystem can be configured at startup (see Configure). Entries are written to stderr unless
+// another output is set with SetOutput, so that they don't mix with the output of the interactive CLI.
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log entry. Entries below the level of their subsystem are dropped
+type Level int
+
+const (
+	DEBUG Level = iota
+	INFO
+	WARN
+	ERROR
+	OFF // Drops every entry
+)
+
+// The level of subsystems that have no level of their own
+const DEFAULT_LEVEL = INFO
+
+// Key that a value without a key is logged under
+const BAD_KEY = "!BADKEY"
+
+var levelNames = []string{"DEBUG", "INFO", "WARN", "ERROR", "OFF"}
+
+var ErrInvalidLevel = errors.New("invalid log level")
+
+func (level Level) String() string {
+	if level < DEBUG || level > OFF {
+		return "Level(" + strconv.Itoa(int(level)) + ")"
+	}
+	return levelNames[level]
+}
+
+// ParseLevel returns the level with some name, regardless of case
+func ParseLevel(name string) (Level, error) {
+	for level, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return Level(level), nil
+		}
+	}
+	return 0, ErrInvalidLevel
+}
+
+// sink is the output that the loggers write to, and the levels of the subsystems
+type sink struct {
+	mutex  sync.Mutex
+	writer io.Writer
+	level  Level            // The level of subsystems that aren't in levels
+	levels map[string]Level // Levels of single subsystems
+	now    func() time.Time
+}
+
+// The sink of every logger created by For
+var std = newSink(os.Stderr)
+
+func newSink(writer io.Writer) *sink {
+	return &sink{writer: writer, level: DEFAULT_LEVEL, levels: make(map[string]Level), now: time.Now}
+}
+
+// Logger writes the log entries of a subsystem. Loggers are safe for concurrent use
+type Logger struct {
+	sink      *sink
+	subsystem string
+	fields    []interface{} // Key/value pairs that are added to every entry (see With)
+}
+
+// For returns the logger of some subsystem
+func For(subsystem string) *Logger {
+	return &Logger{sink: std, subsystem: subsystem}
+}
+
+// SetOutput sets where all loggers write to, stderr by default
+func SetOutput(writer io.Writer) {
+	std.mutex.Lock()
+	defer std.mutex.Unlock()
+	std.writer = writer
+}
+
+// SetLevel sets the level of every subsystem, and forgets the levels set by SetSubsystemLevel
+func SetLevel(level Level) {
+	std.mutex.Lock()
+	defer std.mutex.Unlock()
+	std.level = level
+	std.levels = make(map[string]Level)
+}
+
+// SetSubsystemLevel sets the level of a single subsystem
+func SetSubsystemLevel(subsystem string, level Level) {
+	std.mutex.Lock()
+	defer std.mutex.Unlock()
+	std.levels[subsystem] = level
+}
+
+// Configure sets the levels of the subsystems from a comma separated list of levels, as in
+// "info,network=debug,http=warn". A level without a subsystem is the level of all other subsystems.
+// Nothing is changed if the list is invalid
+func Configure(spec string) error {
+	level := DEFAULT_LEVEL
+	levels := make(map[string]Level)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		subsystem, name := "", item
+		if i := strings.Index(item, "="); i >= 0 {
+			subsystem, name = strings.TrimSpace(item[:i]), strings.TrimSpace(item[i+1:])
+			if subsystem == "" {
+				return errors.New("missing subsystem in log level " + strconv.Quote(item))
+			}
+		}
+		parsed, err := ParseLevel(name)
+		if err != nil {
+			return errors.New("invalid log level " + strconv.Quote(name))
+		}
+		if subsystem == "" {
+			level = parsed
+		} else {
+			levels[subsystem] = parsed
+		}
+	}
+	std.mutex.Lock()
+	defer std.mutex.Unlock()
+	std.level, std.levels = level, levels
+	return nil
+}
+
+// With returns a logger that adds some key/value pairs to every entry, as in With("node", id)
+func (logger *Logger) With(keyvals ...interface{}) *Logger {
+	fields := make([]interface{}, 0, len(logger.fields)+len(keyvals))
+	fields = append(append(fields, logger.fields...), keyvals...)
+	return &Logger{sink: logger.sink, subsystem: logger.subsystem, fields: fields}
+}
+
+// Enabled returns true if entries of some level are written. Use it to skip expensive work for debug entries
+func (logger *Logger) Enabled(level Level) bool {
+	logger.sink.mutex.Lock()
+	defer logger.sink.mutex.Unlock()
+	return logger.enabled(level)
+}
+
+// enabled is Enabled for callers that hold the mutex of the sink
+func (logger *Logger) enabled(level Level) bool {
+	threshold, ok := logger.sink.levels[logger.subsystem]
+	if !ok {
+		threshold = logger.sink.level
+	}
+	return level < OFF && level >= threshold
+}
+
+// Debug logs a message with key/value pairs, as in Debug("Sent request", "peer", address, "rpc", "PING")
+func (logger *Logger) Debug(msg string, keyvals ...interface{}) {
+	logger.log(DEBUG, msg, keyvals)
+}
+
+// Info logs a message with key/value pairs, see Debug
+func (logger *Logger) Info(msg string, keyvals ...interface{}) {
+	logger.log(INFO, msg, keyvals)
+}
+
+// Warn logs a message with key/value pairs, see Debug
+func (logger *Logger) Warn(msg string, keyvals ...interface{}) {
+	logger.log(WARN, msg, keyvals)
+}
+
+// Error logs a message with key/value pairs, see Debug
+func (logger *Logger) Error(msg string, keyvals ...interface{}) {
+	logger.log(ERROR, msg, keyvals)
+}
+
+// log writes an entry as a single line if its level is enabled
+func (logger *Logger) log(level Level, msg string, keyvals []interface{}) {
+	logger.sink.mutex.Lock()
+	defer logger.sink.mutex.Unlock()
+	if !logger.enabled(level) {
+		return
+	}
+	var line strings.Builder
+	line.WriteString("time=" + logger.sink.now().UTC().Format("2006-01-02T15:04:05.000Z07:00"))
+	line.WriteString(" level=" + level.String())
+	line.WriteString(" subsystem=" + formatValue(logger.subsystem))
+	writeFields(&line, logger.fields)
+	line.WriteString(" msg=" + formatValue(msg))
+	writeFields(&line, keyvals)
+	line.WriteString("\n")
+	io.WriteString(logger.sink.writer, line.String())
+}
+
+// writeFields writes key/value pairs as key=value. A value without a key is written under BAD_KEY
+func writeFields(line *strings.Builder, keyvals []interface{}) {
+	for i := 0; i < len(keyvals); i += 2 {
+		key, ok := keyvals[i].(string)
+		if !ok || i+1 == len(keyvals) {
+			line.WriteString(" " + BAD_KEY + "=" + formatValue(keyvals[i]))
+			i--
+			continue
+		}
+		line.WriteString(" " + key + "=" + formatValue(keyvals[i+1]))
+	}
+}
+
+// formatValue formats a value of a field. Values that are empty or contain spaces, quotes or equal signs are quoted
+func formatValue(value interface{}) string {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		text = "nil"
+	case string:
+		text = v
+	case error:
+		text = v.Error()
+	case fmt.Stringer:
+		text = v.String()
+	default:
+		text = fmt.Sprint(v)
+	}
+	if text == "" || strings.ContainsAny(text, " \t\r\n\"=\\") || !strconv.CanBackquote(text) {
+		return strconv.Quote(text)
+	}
+	return text
+}
@@ -0,0 +1,113 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestLogger returns the logger of a subsystem that writes to a buffer, at a fixed time
+func newTestLogger(subsystem string) (*Logger, *bytes.Buffer) {
+	var buffer bytes.Buffer
+	sink := newSink(&buffer)
+	sink.now = func() time.Time { return time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC) }
+	return &Logger{sink: sink, subsystem: subsystem}, &buffer
+}
+
+func TestLogger_Format(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     string
+		keyvals []interface{}
+		want    string
+	}{
+		{"No fields", "Started", nil, "msg=Started"},
+		{"Quoted message", "Joined network", nil, "msg=\"Joined network\""},
+		{"Fields", "Sent", []interface{}{"peer", "0.0.0.1", "duration", 12 * time.Millisecond, "replicas", 3},
+			"msg=Sent peer=0.0.0.1 duration=12ms replicas=3"},
+		{"Quoted values", "Failed", []interface{}{"error", errors.New("i/o timeout"), "value", "", "quote", "a\"b"},
+			"msg=Failed error=\"i/o timeout\" value=\"\" quote=\"a\\\"b\""},
+		{"Value without key", "Odd", []interface{}{"peer"}, "msg=Odd " + BAD_KEY + "=peer"},
+		{"Key that isn't a string", "Odd", []interface{}{1, "peer", "0.0.0.1"}, "msg=Odd " + BAD_KEY + "=1 peer=0.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, buffer := newTestLogger("network")
+			logger.With("node", "abc").Info(tt.msg, tt.keyvals...)
+			want := "time=2026-10-19T12:00:00.000Z level=INFO subsystem=network node=abc " + tt.want + "\n"
+			if got := buffer.String(); got != want {
+				t.Errorf("Info() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// Entries below the level of their subsystem are dropped
+func TestLogger_Levels(t *testing.T) {
+	logger, buffer := newTestLogger("network")
+	logger.Debug("dropped")
+	logger.Warn("written")
+	if got := bytes.Count(buffer.Bytes(), []byte("\n")); got != 1 {
+		t.Errorf("Debug() and Warn() wrote %v entries, want %v", got, 1)
+	}
+
+	logger.sink.levels["network"] = DEBUG
+	if !logger.Enabled(DEBUG) {
+		t.Errorf("Enabled() = %v, want %v", false, true)
+	}
+	other := &Logger{sink: logger.sink, subsystem: "http"}
+	if other.Enabled(DEBUG) {
+		t.Errorf("Enabled() = %v, want %v", true, false)
+	}
+	logger.sink.levels["network"] = OFF
+	if logger.Enabled(ERROR) {
+		t.Errorf("Enabled() = %v, want %v", true, false)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	defer SetLevel(DEFAULT_LEVEL)
+	tests := []struct {
+		name    string
+		spec    string
+		level   Level
+		levels  map[string]Level
+		wantErr bool
+	}{
+		{"Empty", "", INFO, map[string]Level{}, false},
+		{"Default level", "warn", WARN, map[string]Level{}, false},
+		{"Subsystems", "error, network=debug,HTTP=Off", ERROR, map[string]Level{"network": DEBUG, "HTTP": OFF}, false},
+		{"Invalid level", "network=loud", ERROR, map[string]Level{"network": DEBUG, "HTTP": OFF}, true},
+		{"Missing subsystem", "=debug", ERROR, map[string]Level{"network": DEBUG, "HTTP": OFF}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Configure(tt.spec); (err != nil) != tt.wantErr {
+				t.Errorf("Configure() = %v, want error %v", err, tt.wantErr)
+			}
+			if std.level != tt.level {
+				t.Errorf("Configure() level = %v, want %v", std.level, tt.level)
+			}
+			if len(std.levels) != len(tt.levels) {
+				t.Errorf("Configure() levels = %v, want %v", std.levels, tt.levels)
+			}
+			for subsystem, level := range tt.levels {
+				if std.levels[subsystem] != level {
+					t.Errorf("Configure() levels = %v, want %v", std.levels, tt.levels)
+				}
+			}
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, level := range []Level{DEBUG, INFO, WARN, ERROR, OFF} {
+		if got, err := ParseLevel(level.String()); got != level || err != nil {
+			t.Errorf("ParseLevel() = %v, %v, want %v, %v", got, err, level, nil)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err != ErrInvalidLevel {
+		t.Errorf("ParseLevel() = %v, want %v", err, ErrInvalidLevel)
+	}
+}
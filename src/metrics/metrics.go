@@ -0,0 +1,247 @@
+// Package metrics keeps counters, gauges and histograms and writes them in the Prometheus text format, so that a
+// node can be scraped without pulling in the Prometheus client library:
+//
+//	# HELP kademlia_rpcs_sent_total Number of RPCs sent to other nodes.
+//	# TYPE kademlia_rpcs_sent_total counter
+//	kademlia_rpcs_sent_total{type="PING"} 3
+//
+// Every metric is created in a Registry, which writes all its metrics with WriteTo and serves them over HTTP.
+// A metric can have labels, and every combination of label values is a separate series.
+package metrics
+
+import (
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The content type of the Prometheus text format
+const CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8"
+
+// Upper bounds of the buckets of a histogram of durations in seconds, from 1ms to 10s
+var DEFAULT_BUCKETS = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry is a set of metrics. Registries are safe for concurrent use
+type Registry struct {
+	mutex   sync.Mutex
+	metrics []*metric
+}
+
+// metric is the state of a single metric with all its series
+type metric struct {
+	name    string
+	help    string
+	kind    string // counter, gauge or histogram
+	labels  []string
+	buckets []float64          // Upper bounds of the buckets of a histogram
+	series  map[string]*series // Series by their label values, joined by labelSeparator
+}
+
+// series is a single combination of label values of a metric
+type series struct {
+	labels []string
+	value  float64  // The value of a counter or gauge, the sum of the observations of a histogram
+	counts []uint64 // Number of observations in each bucket of a histogram (not cumulative)
+	count  uint64   // Number of observations of a histogram
+}
+
+// Label values can't contain this byte, so it separates them in the keys of series
+const labelSeparator = "\xff"
+
+// Counter is a value that only goes up, like the number of sent requests
+type Counter struct {
+	registry *Registry
+	metric   *metric
+}
+
+// Gauge is a value that goes up and down, like the number of stored items
+type Gauge struct {
+	registry *Registry
+	metric   *metric
+}
+
+// Histogram counts observations, like the durations of requests, in buckets
+type Histogram struct {
+	registry *Registry
+	metric   *metric
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// NewCounter creates a counter with some labels, as in NewCounter("rpcs_sent_total", "Number of RPCs.", "type")
+func (registry *Registry) NewCounter(name string, help string, labels ...string) *Counter {
+	return &Counter{registry, registry.add(name, help, "counter", labels, nil)}
+}
+
+// NewGauge creates a gauge with some labels, see NewCounter
+func (registry *Registry) NewGauge(name string, help string, labels ...string) *Gauge {
+	return &Gauge{registry, registry.add(name, help, "gauge", labels, nil)}
+}
+
+// NewHistogram creates a histogram with some labels, that counts observations in buckets with some upper bounds.
+// The buckets must be sorted, a bucket without upper bound (+Inf) is always added
+func (registry *Registry) NewHistogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	return &Histogram{registry, registry.add(name, help, "histogram", labels, buckets)}
+}
+
+func (registry *Registry) add(name string, help string, kind string, labels []string, buckets []float64) *metric {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	metric := &metric{name, help, kind, labels, buckets, make(map[string]*series)}
+	registry.metrics = append(registry.metrics, metric)
+	return metric
+}
+
+// get returns the series of a metric with some label values, and creates it if it doesn't exist.
+// The mutex of the registry must be held by the caller
+func (metric *metric) get(labels []string) *series {
+	if len(labels) != len(metric.labels) {
+		panic("metrics: " + metric.name + " has " + strconv.Itoa(len(metric.labels)) + " labels, got " +
+			strconv.Itoa(len(labels)) + " values")
+	}
+	key := strings.Join(labels, labelSeparator)
+	s := metric.series[key]
+	if s == nil {
+		s = &series{labels: append([]string(nil), labels...)}
+		if metric.kind == "histogram" {
+			s.counts = make([]uint64, len(metric.buckets)+1)
+		}
+		metric.series[key] = s
+	}
+	return s
+}
+
+// Inc adds 1 to the series with some label values
+func (counter *Counter) Inc(labels ...string) {
+	counter.Add(1, labels...)
+}
+
+// Add adds a value, which must not be negative, to the series with some label values
+func (counter *Counter) Add(value float64, labels ...string) {
+	counter.registry.mutex.Lock()
+	defer counter.registry.mutex.Unlock()
+	counter.metric.get(labels).value += value
+}
+
+// Set sets the series with some label values. Only use it for counters that are counted elsewhere, and copied to
+// the registry before the metrics are written
+func (counter *Counter) Set(value float64, labels ...string) {
+	counter.registry.mutex.Lock()
+	defer counter.registry.mutex.Unlock()
+	counter.metric.get(labels).value = value
+}
+
+// Set sets the series with some label values
+func (gauge *Gauge) Set(value float64, labels ...string) {
+	gauge.registry.mutex.Lock()
+	defer gauge.registry.mutex.Unlock()
+	gauge.metric.get(labels).value = value
+}
+
+// Reset removes all series of the gauge, so that series that are no longer set disappear
+func (gauge *Gauge) Reset() {
+	gauge.registry.mutex.Lock()
+	defer gauge.registry.mutex.Unlock()
+	gauge.metric.series = make(map[string]*series)
+}
+
+// Observe counts a value in the series with some label values
+func (histogram *Histogram) Observe(value float64, labels ...string) {
+	histogram.registry.mutex.Lock()
+	defer histogram.registry.mutex.Unlock()
+	s := histogram.metric.get(labels)
+	bucket := sort.SearchFloat64s(histogram.metric.buckets, value) // The first bucket with value <= upper bound
+	s.counts[bucket]++
+	s.count++
+	s.value += value
+}
+
+// WriteTo writes all metrics in the Prometheus text format. The series of a metric are sorted by their labels
+func (registry *Registry) WriteTo(w io.Writer) (int64, error) {
+	registry.mutex.Lock()
+	var text strings.Builder
+	for _, metric := range registry.metrics {
+		metric.write(&text)
+	}
+	registry.mutex.Unlock()
+	n, err := io.WriteString(w, text.String())
+	return int64(n), err
+}
+
+// ServeHTTP serves all metrics in the Prometheus text format
+func (registry *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", CONTENT_TYPE)
+	registry.WriteTo(w)
+}
+
+// write writes the help, type and series of a metric
+func (metric *metric) write(text *strings.Builder) {
+	text.WriteString("# HELP " + metric.name + " " + escapeHelp(metric.help) + "\n")
+	text.WriteString("# TYPE " + metric.name + " " + metric.kind + "\n")
+	keys := make([]string, 0, len(metric.series))
+	for key := range metric.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := metric.series[key]
+		if metric.kind != "histogram" {
+			metric.writeSample(text, "", s.labels, "", s.value)
+			continue
+		}
+		var cumulative uint64
+		for i, upper := range metric.buckets {
+			cumulative += s.counts[i]
+			metric.writeSample(text, "_bucket", s.labels, formatFloat(upper), float64(cumulative))
+		}
+		metric.writeSample(text, "_bucket", s.labels, "+Inf", float64(s.count))
+		metric.writeSample(text, "_sum", s.labels, "", s.value)
+		metric.writeSample(text, "_count", s.labels, "", float64(s.count))
+	}
+}
+
+// writeSample writes a single line with the value of a series. le is the upper bound of a histogram bucket,
+// or empty
+func (metric *metric) writeSample(text *strings.Builder, suffix string, labels []string, le string, value float64) {
+	text.WriteString(metric.name + suffix)
+	pairs := make([]string, 0, len(labels)+1)
+	for i, label := range labels {
+		pairs = append(pairs, metric.labels[i]+"=\""+escapeLabel(label)+"\"")
+	}
+	if le != "" {
+		pairs = append(pairs, "le=\""+le+"\"")
+	}
+	if len(pairs) > 0 {
+		text.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+	text.WriteString(" " + formatFloat(value) + "\n")
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var helpEscaper = strings.NewReplacer("\\", "\\\\", "\n", "\\n")
+var labelEscaper = strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\"", "\\\"")
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func escapeLabel(label string) string {
+	return labelEscaper.Replace(label)
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	registry := NewRegistry()
+	sent := registry.NewCounter("rpcs_sent_total", "Number of RPCs sent.", "type")
+	items := registry.NewGauge("items", "Number of \\ stored\nitems.")
+	rounds := registry.NewHistogram("lookup_rounds", "Rounds of a lookup.", []float64{1, 2, 5}, "lookup")
+	sent.Inc("STORE")
+	sent.Inc("PING")
+	sent.Add(2, "PING")
+	sent.Inc("a\"b\\c\n")
+	items.Set(1.5)
+	rounds.Observe(1, "node")
+	rounds.Observe(3, "node")
+	rounds.Observe(7, "node")
+
+	want := `# HELP rpcs_sent_total Number of RPCs sent.
+# TYPE rpcs_sent_total counter
+rpcs_sent_total{type="PING"} 3
+rpcs_sent_total{type="STORE"} 1
+rpcs_sent_total{type="a\"b\\c\n"} 1
+# HELP items Number of \\ stored\nitems.
+# TYPE items gauge
+items 1.5
+# HELP lookup_rounds Rounds of a lookup.
+# TYPE lookup_rounds histogram
+lookup_rounds_bucket{lookup="node",le="1"} 1
+lookup_rounds_bucket{lookup="node",le="2"} 1
+lookup_rounds_bucket{lookup="node",le="5"} 2
+lookup_rounds_bucket{lookup="node",le="+Inf"} 3
+lookup_rounds_sum{lookup="node"} 11
+lookup_rounds_count{lookup="node"} 3
+`
+	var text strings.Builder
+	registry.WriteTo(&text)
+	if text.String() != want {
+		t.Errorf("WriteTo() = %v, want %v", text.String(), want)
+	}
+
+	items.Reset()
+	text.Reset()
+	registry.WriteTo(&text)
+	if strings.Contains(text.String(), "items 1.5") {
+		t.Errorf("WriteTo() = %v, want no series of items", text.String())
+	}
+}
+
+// A series with the wrong number of label values is a programming error
+func TestCounter_Labels(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Inc() didn't panic")
+		}
+	}()
+	NewRegistry().NewCounter("rpcs_sent_total", "Number of RPCs sent.", "type").Inc()
+}
+
+func TestRegistry_ServeHTTP(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewCounter("requests_total", "Number of requests.").Inc()
+	recorder := httptest.NewRecorder()
+	registry.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if got := recorder.Header().Get("Content-Type"); got != CONTENT_TYPE {
+		t.Errorf("ServeHTTP() Content-Type = %v, want %v", got, CONTENT_TYPE)
+	}
+	if !strings.Contains(recorder.Body.String(), "requests_total 1\n") {
+		t.Errorf("ServeHTTP() = %v, want %v", recorder.Body.String(), "requests_total 1")
+	}
+}
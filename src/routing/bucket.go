@@ -1,24 +1,25 @@
-package main
+package routing
 
 import (
 	"container/list"
 )
 
-// bucket definition. Contains a List
-type bucket struct {
+// Bucket definition. Contains a List
+type Bucket struct {
 	list *list.List
 }
 
-// newBucket returns a new instance of a bucket
-func newBucket() *bucket {
-	bucket := &bucket{}
+// NewBucket returns a new instance of a bucket
+func NewBucket() *Bucket {
+	bucket := &Bucket{}
 	bucket.list = list.New()
 	return bucket
 }
 
 // AddContact adds the Contact to the front of the bucket
-// or moves it to the front of the bucket if it already existed
-func (bucket *bucket) AddContact(contact Contact) {
+// or moves it to the front of the bucket if it already existed (and updates if it accepts streams and when it
+// was last seen)
+func (bucket *Bucket) AddContact(contact Contact) {
 	var element *list.Element
 	for e := bucket.list.Front(); e != nil; e = e.Next() {
 		nodeID := e.Value.(Contact).ID
@@ -28,16 +29,20 @@ func (bucket *bucket) AddContact(contact Contact) {
 		}
 	}
 	if element == nil {
-		if bucket.list.Len() < k {
+		if bucket.list.Len() < K {
 			bucket.list.PushFront(contact)
 		}
 	} else {
+		existing := element.Value.(Contact)
+		existing.Streams = contact.Streams
+		existing.LastSeen = contact.LastSeen
+		element.Value = existing
 		bucket.list.MoveToFront(element)
 	}
 }
 
 // Check what elements exists within a select contact.
-func (bucket *bucket) Contains(contact *Contact)  *list.Element {
+func (bucket *Bucket) Contains(contact *Contact)  *list.Element {
 	var element *list.Element
 	for e := bucket.list.Front(); e != nil; e = e.Next() {
 		nodeID := e.Value.(Contact).ID
@@ -50,7 +55,7 @@ func (bucket *bucket) Contains(contact *Contact)  *list.Element {
 }
 
 // Returns an array of Contacts where the distance has already been calculated
-func (bucket *bucket) GetContactsAndCalcDistances(target *KademliaID) []Contact {
+func (bucket *Bucket) GetContactsAndCalcDistances(target *KademliaID) []Contact {
 	var contacts []Contact
 
 	for elt := bucket.list.Front(); elt != nil; elt = elt.Next() {
@@ -61,7 +66,16 @@ func (bucket *bucket) GetContactsAndCalcDistances(target *KademliaID) []Contact
 	return contacts
 }
 
+// Contacts returns the contacts in the bucket, most recently seen first
+func (bucket *Bucket) Contacts() []Contact {
+	contacts := make([]Contact, 0, bucket.list.Len())
+	for elt := bucket.list.Front(); elt != nil; elt = elt.Next() {
+		contacts = append(contacts, elt.Value.(Contact))
+	}
+	return contacts
+}
+
 // Len return the size of the bucket
-func (bucket *bucket) Len() int {
+func (bucket *Bucket) Len() int {
 	return bucket.list.Len()
 }
@@ -1,4 +1,4 @@
-package main
+package routing
 
 import (
 	"fmt"
@@ -8,19 +8,19 @@ import (
 
 // Tests so that a new Bucket is actually of type bucket.
 func TestNewBucket(t *testing.T) {
-	// Test newBucket
-	testBucket := newBucket()
+	// Test NewBucket
+	testBucket := NewBucket()
 	output1 := testIfBucket(testBucket)
 	groundtruth1 := true
 	if output1 != groundtruth1 {
-		t.Errorf("Type from newBucket is not Bucket")
+		t.Errorf("Type from NewBucket is not Bucket")
 	} else {
 		fmt.Println("TestNewBucket = Passed")
 	}
 }
 func testIfBucket(t interface{}) bool{
 	switch t.(type){
-		case *bucket:
+		case *Bucket:
 			return true
 		default:
 			return false
@@ -29,7 +29,7 @@ func testIfBucket(t interface{}) bool{
 // Tests so that adding a contact increases the length of the bucket. Also tests Len()
 func TestAddContact(t *testing.T) {
 	// Setup
-	testBucket := newBucket()
+	testBucket := NewBucket()
 	testId :=  NewKademliaIDFromData("test")
 	testContact := NewContact(testId,"0.0.0.0")
 
@@ -47,7 +47,7 @@ func TestAddContact(t *testing.T) {
 // Tests so that contains returns elements from the bucket's list.
 func TestContains(t *testing.T) {
 	// Setup
-	testBucket := newBucket()
+	testBucket := NewBucket()
 	testId :=  NewKademliaIDFromData("test")
 	testContact := NewContact(testId,"0.0.0.0")
 
@@ -66,13 +66,13 @@ func TestContains(t *testing.T) {
 // Checks if the returned array's first value's distance has been added.
 func TestGetContactsAndCalcDistances(t *testing.T) {
 	// Setup
-	testBucket := newBucket()
+	testBucket := NewBucket()
 	testId :=  NewKademliaIDFromData("test")
 	testContact := NewContact(testId,"0.0.0.0")
 
 	// Check contains returns the element from the bucket's list.
 	testBucket.AddContact(testContact)
-	input := testBucket.GetContactsAndCalcDistances(testId)[0].distance
+	input := testBucket.GetContactsAndCalcDistances(testId)[0].Distance
 	output1 := reflect.ValueOf(input).IsNil()
 	groundtruth1 := false
 	if output1 != groundtruth1 {
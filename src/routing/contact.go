@@ -1,31 +1,37 @@
-package main
+package routing
 
 import (
+	"d7024e/logging"
 	"fmt"
 	"sort"
+	"time"
 )
 
+var logger = logging.For("routing")
+
 // Contact definition
-// stores the KademliaID, the ip address and the distance
+// stores the KademliaID, the ip address, if the node accepts streams, the distance and when it was last seen
 type Contact struct {
 	ID       *KademliaID
 	Address  string
-	distance *KademliaID
+	Streams  bool
+	Distance *KademliaID // Distance to the target of the last CalcDistance, nil if it wasn't called
+	LastSeen time.Time   // When the routing table was last updated with the contact, zero if it isn't in one
 }
 
 // NewContact returns a new instance of a Contact
 func NewContact(id *KademliaID, address string) Contact {
-	return Contact{id, address, nil}
+	return Contact{id, address, false, nil, time.Time{}}
 }
 
 // CalcDistance calculates the distance to the target and fills the contacts distance field
 func (contact *Contact) CalcDistance(target *KademliaID) {
-	contact.distance = contact.ID.CalcDistance(target)
+	contact.Distance = contact.ID.CalcDistance(target)
 }
 
-// Less returns true if contact.distance < otherContact.distance
+// Less returns true if contact.Distance < otherContact.Distance
 func (contact *Contact) Less(otherContact *Contact) bool {
-	return contact.distance.Less(otherContact.distance)
+	return contact.Distance.Less(otherContact.Distance)
 }
 
 // String returns a simple string representation of a Contact
@@ -36,25 +42,25 @@ func (contact *Contact) String() string {
 // ContactCandidates definition
 // Stores an array of Contacts
 type ContactCandidates struct {
-	contacts []Contact
+	Contacts []Contact
 }
 
 // Append an array of Contacts to the ContactCandidates
 func (candidates *ContactCandidates) Append(contacts []Contact) {
-	candidates.contacts = append(candidates.contacts, contacts...)
+	candidates.Contacts = append(candidates.Contacts, contacts...)
 }
 
 // AppendContact appends a single contact rather than an array of contacts
 func (candidates *ContactCandidates) AppendContact(contact Contact) {
-	candidates.contacts = append(candidates.contacts, contact)
+	candidates.Contacts = append(candidates.Contacts, contact)
 }
 
 // GetContacts returns the first count number of Contacts. The complete list will be returned if there are fewer contacts.
 func (candidates *ContactCandidates) GetContacts(count int) []Contact {
-	if len(candidates.contacts) < count {
-		return candidates.contacts
+	if len(candidates.Contacts) < count {
+		return candidates.Contacts
 	} else {
-		return candidates.contacts[:count]
+		return candidates.Contacts[:count]
 	}
 }
 
@@ -65,19 +71,19 @@ func (candidates *ContactCandidates) Sort() {
 
 // Len returns the length of the ContactCandidates
 func (candidates *ContactCandidates) Len() int {
-	return len(candidates.contacts)
+	return len(candidates.Contacts)
 }
 
 // Swap the position of the Contacts at i and j
 // WARNING does not check if either i or j is within range
 func (candidates *ContactCandidates) Swap(i, j int) {
-	candidates.contacts[i], candidates.contacts[j] = candidates.contacts[j], candidates.contacts[i]
+	candidates.Contacts[i], candidates.Contacts[j] = candidates.Contacts[j], candidates.Contacts[i]
 }
 
 // Less returns true if the Contact at index i is smaller than 
 // the Contact at index j
 func (candidates *ContactCandidates) Less(i, j int) bool {
-	return candidates.contacts[i].Less(&candidates.contacts[j])
+	return candidates.Contacts[i].Less(&candidates.Contacts[j])
 }
 
 // Contains checks if the list of contacts already contains a node. This assumes that the
@@ -85,7 +91,7 @@ func (candidates *ContactCandidates) Less(i, j int) bool {
 // No consideration is taken for unsorted contacts or contacts with improper distance.
 func (candidates *ContactCandidates) Contains(contact *Contact) bool {
 	for i := 0; i < candidates.Len(); i++ {
-		if candidates.contacts[i].ID.Equals(contact.ID) {
+		if candidates.Contacts[i].ID.Equals(contact.ID) {
 			return true
 		}
 	}
@@ -97,11 +103,10 @@ func (candidates *ContactCandidates) Remove(contact *Contact) {
 	if !candidates.Contains(contact) {
 		return
 	}
-	fmt.Println("Len of contact candidates before remove: ", candidates.Len())
-	for i := 0; i < len(candidates.contacts); i++{
-		if candidates.contacts[i].ID.Equals(contact.ID) {
-			candidates.contacts = append(candidates.contacts[:i], candidates.contacts[i+1:]...)
-			fmt.Println("Len of contact candidates after remove: ", candidates.Len())
+	for i := 0; i < len(candidates.Contacts); i++{
+		if candidates.Contacts[i].ID.Equals(contact.ID) {
+			candidates.Contacts = append(candidates.Contacts[:i], candidates.Contacts[i+1:]...)
+			logger.Debug("Removed contact", "peer", contact.ID, "contacts", candidates.Len())
 			return
 		}
 	}
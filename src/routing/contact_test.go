@@ -1,4 +1,4 @@
-package main
+package routing
 
 import (
 	"net"
@@ -29,8 +29,8 @@ func TestContact_CalcDistance(t *testing.T) {
 			contactA := NewContact(tt.args.ID_1,"")
 			contactA.CalcDistance(tt.args.ID_2)
 
-			if !contactA.distance.Equals(tt.want) {
-				t.Errorf("CalcDistance() = %v, want %v", contactA.distance.String(), tt.want.String())
+			if !contactA.Distance.Equals(tt.want) {
+				t.Errorf("CalcDistance() = %v, want %v", contactA.Distance.String(), tt.want.String())
 			}
 		})
 	}
@@ -92,7 +92,7 @@ func TestContact_String(t *testing.T) {
 	type fields struct {
 		ID       *KademliaID
 		Address  string
-		distance *KademliaID
+		Distance *KademliaID
 	}
 	tests := []struct {
 		name   string
@@ -106,7 +106,7 @@ func TestContact_String(t *testing.T) {
 			contact := &Contact{
 				ID:       tt.fields.ID,
 				Address:  tt.fields.Address,
-				distance: tt.fields.distance,
+				Distance: tt.fields.Distance,
 			}
 			if got := contact.String(); got != tt.want {
 				t.Errorf("String() = %v, want %v", got, tt.want)
@@ -119,7 +119,7 @@ func TestContact_String(t *testing.T) {
 // contact information has been stored correctly.
 func TestContactCandidates_Append(t *testing.T) {
 	type fields struct {
-		contacts []Contact
+		Contacts []Contact
 	}
 	type args struct {
 		contacts []Contact
@@ -134,7 +134,7 @@ func TestContactCandidates_Append(t *testing.T) {
 		for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			candidates := &ContactCandidates{
-				contacts: tt.fields.contacts,
+				Contacts: tt.fields.Contacts,
 			}
 			length := candidates.Len()
 			candidates.Append(tt.args.contacts)
@@ -162,7 +162,7 @@ func TestContactCandidates_AppendContact(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 
 			candidates := &ContactCandidates{
-				contacts: []Contact{},
+				Contacts: []Contact{},
 			}
 			len := candidates.Len()
 
@@ -179,7 +179,7 @@ func TestContactCandidates_AppendContact(t *testing.T) {
 // Test if it is possible to request more contacts than the max value in a routing table.
 func TestContactCandidates_GetContacts(t *testing.T) {
 	type fields struct {
-		contacts []Contact
+		Contacts []Contact
 	}
 	type args struct {
 		count int
@@ -197,7 +197,7 @@ func TestContactCandidates_GetContacts(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			candidates := &ContactCandidates{
-				contacts: tt.fields.contacts,
+				Contacts: tt.fields.Contacts,
 			}
 			if temp_len := len(candidates.GetContacts(tt.args.count)); temp_len != tt.want {
 				t.Errorf("GetContacts() = %v, want %v", temp_len, tt.want)
@@ -209,7 +209,7 @@ func TestContactCandidates_GetContacts(t *testing.T) {
 // Just see if the length of contact candidates can be retrieved.
 func TestContactCandidates_Len(t *testing.T) {
 	type fields struct {
-		contacts []Contact
+		Contacts []Contact
 	}
 	tests := []struct {
 		name   string
@@ -222,7 +222,7 @@ func TestContactCandidates_Len(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			candidates := &ContactCandidates{
-				contacts: tt.fields.contacts,
+				Contacts: tt.fields.Contacts,
 			}
 			if got := candidates.Len(); got != tt.want {
 				t.Errorf("Len() = %v, want %v", got, tt.want)
@@ -234,7 +234,7 @@ func TestContactCandidates_Len(t *testing.T) {
 // Swap the order of two contacts.
 func TestContactCandidates_Swap(t *testing.T) {
 	type fields struct {
-		contacts []Contact
+		Contacts []Contact
 	}
 	type args struct {
 		i int
@@ -255,14 +255,14 @@ func TestContactCandidates_Swap(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			candidates := &ContactCandidates{
-				contacts: tt.fields.contacts,
+				Contacts: tt.fields.Contacts,
 			}
 
-			ID_1 := candidates.contacts[tt.args.i].ID
-			ID_2 := candidates.contacts[tt.args.j].ID
+			ID_1 := candidates.Contacts[tt.args.i].ID
+			ID_2 := candidates.Contacts[tt.args.j].ID
 			candidates.Swap(tt.args.i,tt.args.j)
-			new_ID_1 := candidates.contacts[tt.args.i].ID
-			new_ID_2 := candidates.contacts[tt.args.j].ID
+			new_ID_1 := candidates.Contacts[tt.args.i].ID
+			new_ID_2 := candidates.Contacts[tt.args.j].ID
 			if !new_ID_1.Equals(ID_2) || !new_ID_2.Equals(ID_1) {
 				t.Errorf("Len() = %v, want %v and Len() = %v, want %v", new_ID_1.String(), ID_2.String(),new_ID_2.String(),ID_1.String())
 			}
@@ -272,7 +272,7 @@ func TestContactCandidates_Swap(t *testing.T) {
 
 func TestContactCandidates_Contains(t *testing.T) {
 	type fields struct {
-		contacts []Contact
+		Contacts []Contact
 	}
 	type args struct {
 		contact Contact
@@ -289,7 +289,7 @@ func TestContactCandidates_Contains(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			candidates := &ContactCandidates{
-				contacts: tt.fields.contacts,
+				Contacts: tt.fields.Contacts,
 			}
 			if got := candidates.Contains(&tt.args.contact); got != tt.want {
 				t.Errorf("Contains() = %v, want %v", got, tt.want)
@@ -300,7 +300,7 @@ func TestContactCandidates_Contains(t *testing.T) {
 
 func TestContactCandidates_Remove(t *testing.T) {
 	type fields struct {
-		contacts []Contact
+		Contacts []Contact
 	}
 	type args struct {
 		contact Contact
@@ -317,7 +317,7 @@ func TestContactCandidates_Remove(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			candidates := &ContactCandidates{
-				contacts: tt.fields.contacts,
+				Contacts: tt.fields.Contacts,
 			}
 			candidates.Remove(&tt.args.contact)
 			length := candidates.Len()
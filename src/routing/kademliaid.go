@@ -1,8 +1,9 @@
-package main
+package routing
 
 import (
 	"crypto/sha1"
 	"encoding/hex"
+	"errors"
 	"net"
 )
 
@@ -19,16 +20,40 @@ func sha1Hash(content string) []byte {
 	return hashedFileBytes
 }
 
+var ErrInvalidIDLength = errors.New("a kademlia ID must be exactly 20 bytes (40 hex characters)")
+
 // NewKademliaID returns a new instance of a KademliaID based on the string input
+// The input must be a valid ID, otherwise it panics. Use ParseKademliaID for input from users or other nodes
 func NewKademliaID(data string) *KademliaID {
-	decoded, _ := hex.DecodeString(data)
+	newKademliaID, err := ParseKademliaID(data)
+	if err != nil {
+		panic("NewKademliaID(" + data + "): " + err.Error())
+	}
+	return newKademliaID
+}
 
-	newKademliaID := KademliaID{}
-	for i := 0; i < ID_LEN; i++ {
-		newKademliaID[i] = decoded[i]
+// ParseKademliaID returns a new instance of a KademliaID based on a hex string, or an error
+// if the string isn't exactly 40 hex characters
+func ParseKademliaID(data string) (*KademliaID, error) {
+	if len(data) != 2*ID_LEN {
+		return nil, ErrInvalidIDLength
 	}
+	decoded, err := hex.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewKademliaIDFromBytes(decoded)
+}
 
-	return &newKademliaID
+// NewKademliaIDFromBytes returns a copy of the first ID_LEN bytes of a byte slice as a KademliaID,
+// or an error if the slice is too short
+func NewKademliaIDFromBytes(data []byte) (*KademliaID, error) {
+	if len(data) < ID_LEN {
+		return nil, ErrInvalidIDLength
+	}
+	newKademliaID := KademliaID{}
+	copy(newKademliaID[:], data[:ID_LEN])
+	return &newKademliaID, nil
 }
 // NewKademliaIDFromData returns a new instance of a KademliaID based on the hash input
 func NewKademliaIDFromData(data string) *KademliaID {
@@ -0,0 +1,75 @@
+package routing
+
+import (
+	"encoding/hex"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Just make sure we haven't changed anything in the kademlia function.
+// This calculates an ID manually and verifies it.
+func TestNewKademliaID(t *testing.T) {
+	rand.Seed(time.Now().UnixNano())
+	input := ""
+	for i := 0; i < ID_LEN; i++ {
+		r := rand.Intn(100 - 10) + 10
+		input += strconv.Itoa(r)
+	}
+	id := NewKademliaID(input)
+	if id.String() != input {
+		t.Errorf("NewKademliaID got %s, expected %s", id.String(), input)
+	}
+}
+
+// Just make sure we haven't changed anything in the kademlia function.
+// This calculates an ID manually and verifies it.
+func TestNewKademliaIDFromData(t *testing.T) {
+	rand.Seed(time.Now().UnixNano())
+	var input string
+	for i := 0; i < ID_LEN; i++ {
+		r := rand.Intn(11)
+		input = strconv.Itoa(r)
+	}
+	id := NewKademliaIDFromData(input)
+	res, _ := hex.DecodeString(id.String())
+
+	for i := 0; i < ID_LEN; i++ {
+		if res[i] != sha1Hash(input)[i] {
+			t.Errorf("NewKademliaIDFromData error at byte array index %d", i)
+		}
+	}
+}
+
+// Invalid input should be an error, never a panic
+func TestParseKademliaID(t *testing.T) {
+	invalid := []string{"", "00", "000000000000000000000000000000000000000", "00000000000000000000000000000000000000000",
+		"zz00000000000000000000000000000000000000"}
+	for _, input := range invalid {
+		if id, err := ParseKademliaID(input); err == nil {
+			t.Errorf("ParseKademliaID(%q) = %v, want an error", input, id)
+		}
+	}
+	if _, err := NewKademliaIDFromBytes(make([]byte, ID_LEN-1)); err == nil {
+		t.Errorf("NewKademliaIDFromBytes accepted a slice that is too short")
+	}
+
+	id, err := ParseKademliaID("00000000000000000000000000000000000000ff")
+	if err != nil || id[ID_LEN-1] != 0xff {
+		t.Errorf("ParseKademliaID got %v, %v", id, err)
+	}
+}
+
+func FuzzParseKademliaID(f *testing.F) {
+	f.Add("00000000000000000000000000000000000000ff")
+	f.Add("0")
+	f.Add("zz00000000000000000000000000000000000000")
+	f.Fuzz(func(t *testing.T, input string) {
+		id, err := ParseKademliaID(input)
+		if err == nil && id.String() != strings.ToLower(input) {
+			t.Errorf("ParseKademliaID got %s, expected %s", id.String(), input)
+		}
+	})
+}
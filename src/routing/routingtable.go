@@ -1,28 +1,46 @@
-package main
+// Package routing implements the kademlia IDs, contacts and the routing table of a kademlia node.
+package routing
 
-import "sync"
+import (
+	"d7024e/clock"
+	"sync"
+)
 
-const k = 20
-const alpha = 3
+const K = 20    // Number of contacts in a bucket, and of nodes that store each value
+const ALPHA = 3 // Number of nodes a lookup queries at the same time
 
 // RoutingTable definition
 // keeps a reference contact of me and an array of buckets
 type RoutingTable struct {
 	me      Contact
-	buckets [ID_LEN * 8]*bucket
+	buckets [ID_LEN * 8]*Bucket
 	bucketMutex sync.Mutex
+
+	// The clock that the last seen times of the contacts are measured with
+	clock clock.Clock
+}
+
+// Me returns the contact of the node the routing table belongs to
+func (routingTable *RoutingTable) Me() Contact {
+	return routingTable.me
 }
 
 // NewRoutingTable returns a new instance of a RoutingTable
 func NewRoutingTable(me Contact) *RoutingTable {
 	routingTable := &RoutingTable{}
 	for i := 0; i < ID_LEN*8; i++ {
-		routingTable.buckets[i] = newBucket()
+		routingTable.buckets[i] = NewBucket()
 	}
 	routingTable.me = me
+	routingTable.clock = clock.Real
 	return routingTable
 }
 
+// SetClock sets the clock that the last seen times of the contacts are measured with
+func (routingTable *RoutingTable) SetClock(clock clock.Clock) {
+	routingTable.clock = clock
+}
+
 // AddContact add a new contact to the correct Bucket
 func (routingTable *RoutingTable) AddContact(contact Contact) {
 	routingTable.bucketMutex.Lock()
@@ -30,6 +48,7 @@ func (routingTable *RoutingTable) AddContact(contact Contact) {
 
 	bucketIndex := routingTable.getBucketIndex(contact.ID)
 	bucket := routingTable.buckets[bucketIndex]
+	contact.LastSeen = routingTable.clock.Now()
 	bucket.AddContact(contact)
 }
 
@@ -63,6 +82,28 @@ func (routingTable *RoutingTable) FindClosestContacts(target *KademliaID, count
 	return candidates.GetContacts(count)
 }
 
+// Buckets returns a copy of the contacts in each bucket, most recently seen first
+func (routingTable *RoutingTable) Buckets() [][]Contact {
+	routingTable.bucketMutex.Lock()
+	defer routingTable.bucketMutex.Unlock()
+	buckets := make([][]Contact, len(routingTable.buckets))
+	for i, bucket := range routingTable.buckets {
+		buckets[i] = bucket.Contacts()
+	}
+	return buckets
+}
+
+// BucketSizes returns the number of contacts in each bucket
+func (routingTable *RoutingTable) BucketSizes() []int {
+	routingTable.bucketMutex.Lock()
+	defer routingTable.bucketMutex.Unlock()
+	sizes := make([]int, len(routingTable.buckets))
+	for i, bucket := range routingTable.buckets {
+		sizes[i] = bucket.Len()
+	}
+	return sizes
+}
+
 // getBucketIndex get the correct Bucket index for the KademliaID
 func (routingTable *RoutingTable) getBucketIndex(id *KademliaID) int {
 	distance := id.CalcDistance(routingTable.me.ID)
@@ -81,11 +122,13 @@ func (routingTable *RoutingTable) getBucketIndex(id *KademliaID) int {
 func (routingTable *RoutingTable)KickTheBucket(contact *Contact, ping func(*Contact) bool) {
 	bucketIndex := routingTable.getBucketIndex(contact.ID)
 	bucket := routingTable.buckets[bucketIndex]
+	seen := *contact
+	seen.LastSeen = routingTable.clock.Now()
 
-	if bucket.Len() == k {
+	if bucket.Len() == K {
 		element := bucket.Contains(contact)
 		if element != nil {
-			bucket.list.MoveToFront(element)
+			bucket.AddContact(seen)
 		} else {
 			// Choose a node to sacrifice
 			sacrifice := bucket.list.Back().Value.(Contact)
@@ -94,10 +137,10 @@ func (routingTable *RoutingTable)KickTheBucket(contact *Contact, ping func(*Cont
 
 			} else {
 				bucket.list.Remove(bucket.list.Back())
-				bucket.AddContact(*contact)
+				bucket.AddContact(seen)
 			}
 		}
 	} else {
-		bucket.AddContact(*contact)
+		bucket.AddContact(seen)
 	}
 }
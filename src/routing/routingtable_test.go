@@ -0,0 +1,66 @@
+package routing
+
+import (
+	"d7024e/clock"
+	"testing"
+	"time"
+)
+
+// This is basically the same function as in the given code.
+func TestRoutingTable(t *testing.T) {
+	result := []Contact{
+		NewContact(NewKademliaID("0000000000000000000000000000000000000000"), ""),
+		NewContact(NewKademliaID("000000000000000000000000000000000000000F"), ""),
+		NewContact(NewKademliaID("00000000000000000000000000000000000000FF"), ""),
+		NewContact(NewKademliaID("0000000000000000000000000000000000000FFF"), ""),
+		NewContact(NewKademliaID("000000000000000000000000000000000000FFFF"), ""),
+		NewContact(NewKademliaID("00000000000000000000000000000000000FFFFF"), ""),
+		NewContact(NewKademliaID("0000000000000000000000000000000000FFFFFF"), "")}
+
+	rt := NewRoutingTable(NewContact(NewKademliaID("0000000000000000000000000000000000FFFFFF"),""))
+
+	rt.AddContact(result[5])
+	rt.AddContact(result[4])
+	rt.AddContact(result[2])
+	rt.AddContact(result[3])
+	rt.AddContact(result[1])
+	rt.AddContact(result[6])
+	rt.AddContact(result[0])
+
+	// Test the ordering of all contacts
+	t.Run("Closest", func(t *testing.T) {
+		contacts := rt.FindClosestContacts(NewKademliaID("0000000000000000000000000000000000000000"), 20)
+
+		for i := range contacts {
+			if !result[i].ID.Equals(contacts[i].ID) {
+				t.Errorf("Routing table test() = %v, want %v", contacts[i].ID.String(), result[i].ID.String())
+			}
+		}
+	})
+}
+
+// Contacts are stamped with the time they were last seen, and moved to the front of their bucket
+func TestRoutingTable_Buckets(t *testing.T) {
+	manual := clock.NewManual(time.Unix(0, 0))
+	rt := NewRoutingTable(NewContact(NewKademliaID("0000000000000000000000000000000000000000"), ""))
+	rt.SetClock(manual)
+	contact1 := NewContact(NewKademliaID("0000000000000000000000000000000000000002"), "0.0.0.2")
+	contact2 := NewContact(NewKademliaID("0000000000000000000000000000000000000003"), "0.0.0.3")
+	rt.AddContact(contact1)
+	manual.Advance(time.Second)
+	rt.KickTheBucket(&contact2, func(*Contact) bool { return true })
+	manual.Advance(time.Second)
+	rt.KickTheBucket(&contact1, func(*Contact) bool { return true })
+
+	buckets := rt.Buckets()
+	bucket := buckets[ID_LEN*8-2]
+	if len(bucket) != 2 || !bucket[0].ID.Equals(contact1.ID) || !bucket[1].ID.Equals(contact2.ID) {
+		t.Errorf("Buckets() = %v, want %v", bucket, []Contact{contact1, contact2})
+	}
+	if len(bucket) == 2 && (!bucket[0].LastSeen.Equal(time.Unix(2, 0)) || !bucket[1].LastSeen.Equal(time.Unix(1, 0))) {
+		t.Errorf("LastSeen = %v, %v, want %v, %v", bucket[0].LastSeen, bucket[1].LastSeen, time.Unix(2, 0), time.Unix(1, 0))
+	}
+	if sizes := rt.BucketSizes(); sizes[ID_LEN*8-2] != 2 {
+		t.Errorf("BucketSizes() = %v, want %v", sizes[ID_LEN*8-2], 2)
+	}
+}
@@ -0,0 +1,502 @@
+// Package storage implements the local storage of a kademlia node: the data objects and records it stores for
+// others, their time-to-live, and the storage quota.
+package storage
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"d7024e/clock"
+	"d7024e/logging"
+	"d7024e/routing"
+	"encoding/binary"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Default storage quotas of a node. A single noisy client should not be able to exhaust the memory of a container,
+// so every node has an upper limit of how many bytes and data objects it is willing to keep.
+const (
+	DEFAULT_MAX_STORAGE_BYTES = 64 * 1024 * 1024
+	DEFAULT_MAX_STORAGE_ITEMS = 10000
+)
+
+// How long (ms) a signed DELETE request is valid before and after the time it was signed at. A node remembers
+// the DELETE requests it accepted for as long, so that they can't be replayed (see Unpublish)
+const DELETE_MAX_AGE = 60 * 1000
+
+// ErrStorageFull is returned by Store (and Cache) when the data doesn't fit within the storage quota, even after
+// evicting everything that the eviction policy allows.
+var ErrStorageFull = errors.New("storage quota exceeded")
+
+// Errors returned by Unpublish when the data can't be deleted, and by StoreOwned when the owner can't be recorded
+var ErrNotFound = errors.New("no data is stored at the hash")
+var ErrNotOwner = errors.New("the data is not owned by the key")
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// ErrHashMismatch is returned by StoreOwned when the hash is not the hash of the data
+var ErrHashMismatch = errors.New("the hash is not the hash of the data")
+
+// ErrStaleDelete is returned by Unpublish when a DELETE request is too old, or was already accepted
+var ErrStaleDelete = errors.New("the delete request is too old or was replayed")
+
+// The node itself is an object that runs on it's own thread and waits for commands from the networking part
+// of a container. We don't need to perform any udp calls from here, just return messages to the local
+// network thread which then sends it through the network. Okidoki?
+type Node struct {
+	storage map[routing.KademliaID][]byte
+
+	// The ID of the node, used to decide which data objects are the first to go when the storage quota is reached
+	me *routing.KademliaID
+
+	// Map that contains the time when each data object in storage expires (its time-to-live runs out)
+	ttl map[routing.KademliaID]time.Time
+
+	// A list of maximum k contacts that should be refreshed for some data object in storage until
+	// it is forgotten
+	refreshContacts map[routing.KademliaID][]routing.Contact
+	refreshMutex sync.Mutex
+
+	storateMutex sync.Mutex
+
+	// Data objects in storage that are only cached copies (see Cache). These are the first to go when
+	// the storage quota is reached.
+	cached map[routing.KademliaID]bool
+
+	// Storage quota. A value of 0 or less means unlimited
+	maxBytes int
+	maxItems int
+	storedBytes int
+
+	// The public keys of the nodes that stored each data object. Only they are allowed to delete it (see Unpublish)
+	owners map[routing.KademliaID][]ed25519.PublicKey
+
+	// The time of the newest DELETE request that was accepted from each owner of each hash, until it is older than
+	// DELETE_MAX_AGE. A request from the same owner that isn't newer is a replay
+	deletes map[deletion]time.Time
+
+	// The content types of the data objects that were stored with one (see SetContentType)
+	contentTypes map[routing.KademliaID]string
+
+	// The clock that TTLs, refreshes and timeouts are measured with
+	clock clock.Clock
+
+	log *logging.Logger
+
+	// Number of data objects that expired, were refreshed and were evicted since the node was created (see Stats)
+	expirations uint64
+	refreshes uint64
+	evictions uint64
+}
+
+// Stats is a snapshot of the local storage of a node
+type Stats struct {
+	Items       int // Stored data objects and records, including cached copies
+	CachedItems int
+	Bytes       int
+
+	// Counters since the node was created
+	Expirations uint64
+	Refreshes   uint64
+	Evictions   uint64
+}
+
+// Create a new Node
+func NewNode(ID routing.Contact) Node {
+	return Node{make(map[routing.KademliaID][]byte), ID.ID,
+		make(map[routing.KademliaID]time.Time), make(map[routing.KademliaID][]routing.Contact), sync.Mutex{},sync.Mutex{},
+		make(map[routing.KademliaID]bool), DEFAULT_MAX_STORAGE_BYTES, DEFAULT_MAX_STORAGE_ITEMS, 0,
+		make(map[routing.KademliaID][]ed25519.PublicKey), make(map[deletion]time.Time), make(map[routing.KademliaID]string), clock.Real, logging.For("storage").With("node", ID.ID), 0, 0, 0}
+}
+
+// SetClock sets the clock that TTLs are measured with
+func (kademlia *Node) SetClock(clock clock.Clock) {
+	kademlia.clock = clock
+}
+
+// SetQuota sets the maximum number of bytes and data objects this node will store. 0 means unlimited.
+// Nothing is evicted right away, the new quota is enforced on the next Store.
+func (kademlia *Node) SetQuota(maxBytes int, maxItems int) {
+	kademlia.storateMutex.Lock()
+	defer kademlia.storateMutex.Unlock()
+	kademlia.maxBytes = maxBytes
+	kademlia.maxItems = maxItems
+}
+
+// Stats returns the number of stored data objects and bytes, and how many expired, were refreshed and were evicted
+func (kademlia *Node) Stats() Stats {
+	kademlia.storateMutex.Lock()
+	defer kademlia.storateMutex.Unlock()
+	return Stats{len(kademlia.storage), len(kademlia.cached), kademlia.storedBytes,
+		kademlia.expirations, kademlia.refreshes, kademlia.evictions}
+}
+
+// Item describes a data object or record in the local storage
+type Item struct {
+	Hash   routing.KademliaID
+	Size   int
+	Cached bool          // Only a cached copy (see Cache)
+	Owners int           // Number of nodes that published the data (see StoreOwned)
+	TTL    time.Duration // Time until the data expires, unless it is refreshed
+
+	ContentType string // Empty if the data was stored without one
+}
+
+// Items returns the data objects and records in storage that haven't expired, sorted by hash
+func (kademlia *Node) Items() []Item {
+	kademlia.storateMutex.Lock()
+	defer kademlia.storateMutex.Unlock()
+	now := kademlia.clock.Now()
+	items := make([]Item, 0, len(kademlia.storage))
+	for hash, data := range kademlia.storage {
+		hash := hash
+		if !kademlia.expired(&hash) {
+			items = append(items, Item{hash, len(data), kademlia.cached[hash], len(kademlia.owners[hash]),
+				kademlia.ttl[hash].Sub(now), kademlia.contentTypes[hash]})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Hash.Less(&items[j].Hash)
+	})
+	return items
+}
+
+// Lookup data
+func (kademlia *Node) LookupData(hash *routing.KademliaID) []byte {
+	kademlia.storateMutex.Lock()
+	defer kademlia.storateMutex.Unlock()
+	if kademlia.storage[*hash] == nil || kademlia.expired(hash) {
+		return nil
+	}
+	return kademlia.storage[*hash]
+}
+
+// ContentType returns the content type of the data stored at some hash, empty if it has none or nothing is stored
+func (kademlia *Node) ContentType(hash *routing.KademliaID) string {
+	kademlia.storateMutex.Lock()
+	defer kademlia.storateMutex.Unlock()
+	return kademlia.contentTypes[*hash]
+}
+
+// SetContentType sets the content type (MIME type) of the data stored at some hash. It is metadata that is not
+// part of the hash, so the last one set wins. Nothing is set if no data is stored at the hash
+func (kademlia *Node) SetContentType(hash *routing.KademliaID, contentType string) {
+	kademlia.storateMutex.Lock()
+	defer kademlia.storateMutex.Unlock()
+	if kademlia.storage[*hash] == nil {
+		return
+	}
+	if contentType == "" {
+		delete(kademlia.contentTypes, *hash)
+	} else {
+		kademlia.contentTypes[*hash] = contentType
+	}
+}
+
+// Store data. Returns ErrStorageFull if the data can't fit within the storage quota of the node
+func (kademlia *Node) Store(data []byte, hash *routing.KademliaID) error {
+	return kademlia.store(data, hash, false)
+}
+
+// StoreMessage returns the message that the publisher of some immutable data signs to store it as an owner
+func StoreMessage(hash *routing.KademliaID, data []byte) []byte {
+	return append(append([]byte("STORE"), hash[:]...), data...)
+}
+
+// StoreOwned stores immutable data like Store and records the public key of the node that published it,
+// so that the publisher can delete the data later on with a signed DELETE request. The publisher signs the hash
+// and the data (see StoreMessage), and the hash must be the hash of the data. A record is never stored here and its
+// key never gets owners, the publisher of a record is its only owner (see StoreRecord).
+// Returns ErrInvalidSignature, ErrHashMismatch, ErrNotOwner or ErrStorageFull if the data is rejected
+func (kademlia *Node) StoreOwned(data []byte, hash *routing.KademliaID, owner ed25519.PublicKey, signature []byte) error {
+	if len(owner) != ed25519.PublicKeySize || !ed25519.Verify(owner, StoreMessage(hash, data), signature) {
+		return ErrInvalidSignature
+	}
+	if !routing.NewKademliaIDFromData(string(data)).Equals(hash) {
+		return ErrHashMismatch
+	}
+	kademlia.storateMutex.Lock()
+	defer kademlia.storateMutex.Unlock()
+	if old := kademlia.storage[*hash]; old != nil && !kademlia.expired(hash) && !bytes.Equal(old, data) {
+		// Only a record can be stored under the hash of other data
+		return ErrNotOwner
+	}
+	if err := kademlia.add(data, hash, false); err != nil {
+		return err
+	}
+	for _, key := range kademlia.owners[*hash] {
+		if bytes.Equal(key, owner) {
+			return nil
+		}
+	}
+	kademlia.owners[*hash] = append(kademlia.owners[*hash], owner)
+	return nil
+}
+
+// Cache stores a copy of some data that this node is not responsible for, for example data that was found
+// during a DataLookup. Cached copies are evicted before anything else when the storage quota is reached.
+func (kademlia *Node) Cache(data []byte, hash *routing.KademliaID) error {
+	return kademlia.store(data, hash, true)
+}
+
+func (kademlia *Node) store(data []byte, hash *routing.KademliaID, cached bool) error {
+	kademlia.storateMutex.Lock()
+	defer kademlia.storateMutex.Unlock()
+	return kademlia.add(data, hash, cached)
+}
+
+// add stores data unless it is already stored. The storage mutex must be held by the caller.
+func (kademlia *Node) add(data []byte, hash *routing.KademliaID, cached bool) error {
+	if kademlia.storage[*hash] != nil && kademlia.expired(hash) {
+		kademlia.remove(hash)
+	}
+	if  kademlia.storage[*hash] != nil{
+		// A real STORE turns a cached copy into a replica that this node is responsible for
+		if !cached && kademlia.cached[*hash] {
+			delete(kademlia.cached, *hash)
+			kademlia.ttl[*hash] = kademlia.clock.Now().Add(TIME_TO_LIVE * time.Millisecond)
+		}
+		return nil
+	}
+
+	return kademlia.insert(data, hash, cached)
+}
+
+// insert puts data in storage, evicting other data objects if needed to stay within the storage quota.
+// The storage mutex must be held by the caller.
+func (kademlia *Node) insert(data []byte, hash *routing.KademliaID, cached bool) error {
+	victims, ok := kademlia.evictionCandidates(hash, len(data), cached)
+	if !ok {
+		return ErrStorageFull
+	}
+	for i := range victims {
+		kademlia.log.Info("Storage quota reached, evicting data", "hash", victims[i], "cached", kademlia.cached[victims[i]])
+		kademlia.remove(&victims[i])
+		kademlia.evictions++
+	}
+
+	kademlia.storage[*hash] = data
+	kademlia.ttl[*hash] = kademlia.clock.Now().Add(TIME_TO_LIVE * time.Millisecond)
+	kademlia.storedBytes += len(data)
+	if cached {
+		kademlia.cached[*hash] = true
+	}
+	return nil
+}
+
+// StoreRecord stores a mutable record under the hash of its public key. The record is only stored if its
+// signature is valid and it is newer (higher sequence number) than the record that is already stored.
+// Returns ErrInvalidRecord, ErrStaleRecord or ErrStorageFull if the record is rejected
+func (kademlia *Node) StoreRecord(record *Record) error {
+	if !record.Verify() {
+		return ErrInvalidRecord
+	}
+	key := record.Key()
+
+	kademlia.storateMutex.Lock()
+	defer kademlia.storateMutex.Unlock()
+	old := kademlia.storage[*key]
+	if old != nil {
+		oldRecord, err := DeserializeRecord(old)
+		if err == nil && oldRecord.Verify() && oldRecord.Sequence >= record.Sequence {
+			return ErrStaleRecord
+		}
+		kademlia.remove(key)
+	}
+	if err := kademlia.insert(record.Serialize(), key, false); err != nil {
+		if old != nil {
+			// Put the old record back, it fit before so it fits now
+			kademlia.insert(old, key, false)
+		}
+		return err
+	}
+	return nil
+}
+
+// evictionCandidates decides which data objects have to be evicted to make room for size more bytes
+// stored at hash. Cached copies are evicted first, then the keys furthest away from the ID of this node.
+// A key is never evicted in favour of a key that is further away from this node, and replicas are never evicted
+// in favour of a cached copy. Returns false if there is no way to make room.
+// The storage mutex must be held by the caller.
+func (kademlia *Node) evictionCandidates(hash *routing.KademliaID, size int, cached bool) ([]routing.KademliaID, bool) {
+	if kademlia.fits(size, 0, 0) {
+		return nil, true
+	}
+	if kademlia.maxBytes > 0 && size > kademlia.maxBytes {
+		return nil, false
+	}
+
+	me := kademlia.me
+	newDistance := hash.CalcDistance(me)
+
+	var cachedKeys, storedKeys routing.ContactCandidates
+	for key := range kademlia.storage {
+		// Contacts are used here only because ContactCandidates already knows how to sort by distance
+		id := key
+		candidate := routing.NewContact(&id, "")
+		candidate.CalcDistance(me)
+		if kademlia.cached[key] {
+			cachedKeys.AppendContact(candidate)
+		} else if !cached && newDistance.Less(candidate.Distance) {
+			storedKeys.AppendContact(candidate)
+		}
+	}
+	cachedKeys.Sort()
+	storedKeys.Sort()
+
+	var victims []routing.KademliaID
+	freedBytes := 0
+	for _, candidates := range []*routing.ContactCandidates{&cachedKeys, &storedKeys} {
+		// Furthest away first
+		for i := candidates.Len() - 1; i >= 0; i-- {
+			id := *candidates.Contacts[i].ID
+			victims = append(victims, id)
+			freedBytes += len(kademlia.storage[id])
+			if kademlia.fits(size, freedBytes, len(victims)) {
+				return victims, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// fits checks if size more bytes can be stored if freedBytes and freedItems were removed first.
+func (kademlia *Node) fits(size int, freedBytes int, freedItems int) bool {
+	if kademlia.maxBytes > 0 && kademlia.storedBytes-freedBytes+size > kademlia.maxBytes {
+		return false
+	}
+	if kademlia.maxItems > 0 && len(kademlia.storage)-freedItems+1 > kademlia.maxItems {
+		return false
+	}
+	return true
+}
+
+// Delete data stored at some hash
+func (kademlia *Node) Delete(hash *routing.KademliaID) {
+	kademlia.storateMutex.Lock()
+	defer kademlia.storateMutex.Unlock()
+	kademlia.remove(hash)
+}
+
+// remove deletes data and everything associated with it. The storage mutex must be held by the caller.
+func (kademlia *Node) remove(hash *routing.KademliaID) {
+	if kademlia.storage[*hash] != nil { // Only delete if there is actually something there
+		kademlia.storedBytes -= len(kademlia.storage[*hash])
+		delete(kademlia.storage, *hash) // Delete the data
+		delete(kademlia.ttl, *hash) // Delete the ttl associated with the data
+		delete(kademlia.cached, *hash)
+		delete(kademlia.owners, *hash)
+		delete(kademlia.contentTypes, *hash)
+	}
+}
+
+// deletion identifies the DELETE requests of one owner for one hash
+type deletion struct {
+	hash  routing.KademliaID
+	owner [ed25519.PublicKeySize]byte
+}
+
+// DeleteMessage returns the message that the owner of some data signs to delete it at some time
+func DeleteMessage(hash *routing.KademliaID, timestamp time.Time) []byte {
+	var signedAt [8]byte
+	binary.BigEndian.PutUint64(signedAt[:], uint64(timestamp.UnixNano()))
+	return append(append([]byte("DELETE"), hash[:]...), signedAt[:]...)
+}
+
+// Unpublish handles a DELETE request signed by owner at some time. Immutable data is stored by everyone that
+// published the same content, so the owner is only removed from the list of owners, and the data itself is deleted
+// when no owners remain. A mutable record is deleted if the owner is the publisher of the record, and a cached
+// copy (see Cache) is dropped by any DELETE request.
+// The request is only accepted if it was signed less than DELETE_MAX_AGE from now, and after the last request of
+// the owner for the hash that was accepted. Returns ErrInvalidSignature if the signature is invalid, ErrStaleDelete
+// if the request is too old or a replay, and ErrNotFound or ErrNotOwner if nothing could be deleted
+func (kademlia *Node) Unpublish(hash *routing.KademliaID, owner ed25519.PublicKey, timestamp time.Time, signature []byte) error {
+	if len(owner) != ed25519.PublicKeySize || !ed25519.Verify(owner, DeleteMessage(hash, timestamp), signature) {
+		return ErrInvalidSignature
+	}
+	kademlia.storateMutex.Lock()
+	defer kademlia.storateMutex.Unlock()
+	key := deletion{hash: *hash}
+	copy(key.owner[:], owner)
+	now := kademlia.clock.Now()
+	maxAge := DELETE_MAX_AGE * time.Millisecond
+	if timestamp.Before(now.Add(-maxAge)) || timestamp.After(now.Add(maxAge)) || !timestamp.After(kademlia.deletes[key]) {
+		return ErrStaleDelete
+	}
+	data := kademlia.storage[*hash]
+	if data == nil {
+		return ErrNotFound
+	}
+	if kademlia.cached[*hash] {
+		// A cached copy has no owners, and must not keep serving data that its owner deleted
+		kademlia.remove(hash)
+		kademlia.deletes[key] = timestamp
+		return nil
+	}
+
+	owners := kademlia.owners[*hash]
+	for i, ownerKey := range owners {
+		if bytes.Equal(ownerKey, owner) {
+			owners = append(owners[:i], owners[i+1:]...)
+			kademlia.owners[*hash] = owners
+			if len(owners) == 0 {
+				kademlia.remove(hash)
+			}
+			kademlia.deletes[key] = timestamp
+			return nil
+		}
+	}
+
+	if record, err := DeserializeRecord(data); err == nil && record.Key().Equals(hash) &&
+		bytes.Equal(record.PublicKey, owner) {
+		kademlia.remove(hash)
+		kademlia.deletes[key] = timestamp
+		return nil
+	}
+	return ErrNotOwner
+}
+
+// Refresh will update the ttl associated with some data by setting it to some system-wide predetermined parameter
+func (kademlia *Node) Refresh(hash *routing.KademliaID) {
+	kademlia.storateMutex.Lock()
+	defer kademlia.storateMutex.Unlock()
+	if kademlia.storage[*hash] != nil && !kademlia.expired(hash) { // Can't refresh something that is already dead
+		kademlia.ttl[*hash] = kademlia.clock.Now().Add(TIME_TO_LIVE * time.Millisecond)
+		kademlia.refreshes++
+	} else {
+		kademlia.log.Warn("Trying to locally refresh something that is already dead", "hash", hash)
+	}
+}
+
+// Forget will remove the contacts associated to some data hash, which means no more refreshRPCs
+// will be sent to those contacts and the data will eventually be deleted by the contacts, including
+// "this node" if it is one of the associated ones
+func (kademlia *Node) Forget(hash *routing.KademliaID) {
+	kademlia.refreshMutex.Lock()
+	defer kademlia.refreshMutex.Unlock()
+	if len(kademlia.refreshContacts[*hash]) != 0 {
+		delete(kademlia.refreshContacts, *hash)
+	}
+}
+
+// RememberContacts remembers which contacts are associated to some data hash
+// so that they can be refreshed in the future
+func (kademlia *Node) RememberContacts(hash *routing.KademliaID, contacts []routing.Contact) {
+	kademlia.refreshMutex.Lock()
+	defer kademlia.refreshMutex.Unlock()
+	if len(kademlia.refreshContacts[*hash]) == 0 {
+		kademlia.refreshContacts[*hash] = contacts
+	}
+}
+
+// RefreshContacts returns a copy of the contacts associated to each remembered data hash (see RememberContacts)
+func (kademlia *Node) RefreshContacts() map[routing.KademliaID][]routing.Contact {
+	kademlia.refreshMutex.Lock()
+	defer kademlia.refreshMutex.Unlock()
+	refreshContacts := make(map[routing.KademliaID][]routing.Contact, len(kademlia.refreshContacts))
+	for dataHash, contacts := range kademlia.refreshContacts {
+		refreshContacts[dataHash] = contacts
+	}
+	return refreshContacts
+}
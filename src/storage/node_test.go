@@ -0,0 +1,418 @@
+package storage
+
+import (
+	"crypto/ed25519"
+	"d7024e/clock"
+	"d7024e/routing"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Tests so that a new node is actually of type Node.
+func TestNewNode(t *testing.T) {
+	// Setup
+	testId :=  routing.NewKademliaIDFromData("test")
+	testContact := routing.NewContact(testId,"0.0.0.0")
+	testNode := NewNode(testContact)
+
+	output1 := testIfNode(&testNode)
+	groundtruth1 := true
+	if output1 != groundtruth1 {
+		t.Errorf("Type from newNode is not Node")
+	} else {
+		fmt.Println("TestNewNode = Passed") // -v must be added to go test for prints to appear.
+	}
+}
+func testIfNode(t interface{}) bool{
+	switch t.(type){
+	case *Node:
+		return true
+	default:
+		return false
+	}
+}
+// Tests so you can Store data, and then Lookup this data.
+func TestLookupData(t *testing.T) {
+	// Setup
+	testString:= "hello"
+	testStringAsByteArray := []byte(testString)
+	testId :=  routing.NewKademliaIDFromData(testString)
+	testContact := routing.NewContact(testId,"0.0.0.0")
+	testNode := NewNode(testContact)
+
+	// Check if Store adds something to Node
+	testNode.Store(testStringAsByteArray, testId)
+
+	output1 := len(testNode.storage)
+	groundtruth1 := 1
+	if output1 != groundtruth1 {
+		t.Errorf("Answer was incorrect, got: %d, want: %d.", output1, groundtruth1)
+	} else {
+		fmt.Println("TestLookupData - Store = Passed") // -v must be added to go test for prints to appear.
+	}
+
+	// Check if the ID will return testString "hello"
+	output2 := string(testNode.LookupData(testId))
+	groundtruth2 := "hello"
+	if output2 != groundtruth2 {
+		t.Errorf("Answer was incorrect, got: %s, want: %s.", output2, groundtruth2)
+	} else {
+		fmt.Println("TestLookupData - TestLookupData = Passed") // -v must be added to go test for prints to appear.
+	}
+}
+
+func TestNode_Delete(t *testing.T) {
+	type fields struct {
+		contact routing.Contact
+	}
+	type args struct {
+		hash *routing.KademliaID
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+	}{
+		{"delete", fields{routing.NewContact(routing.NewKademliaIDFromData("0.0.0.0"),"")},args{routing.NewKademliaIDFromData("hello")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kademlia := NewNode(tt.fields.contact)
+			kademlia.Store([]byte{0,0,0,0},tt.args.hash)
+			kademlia.Delete(tt.args.hash)
+
+			if data := kademlia.LookupData(tt.args.hash);data != nil {
+				t.Errorf("Delete() = %v, want %v", string(data),nil)
+			}
+		})
+	}
+}
+
+func TestNode_Forget(t *testing.T) {
+	type fields struct {
+		contact routing.Contact
+	}
+	type args struct {
+		hash *routing.KademliaID
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+	}{
+		{"forget", fields{routing.NewContact(routing.NewKademliaIDFromData("0.0.0.0"),"")},args{routing.NewKademliaIDFromData("hello")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kademlia := NewNode(tt.fields.contact)
+			kademlia.Store([]byte{0,0,0,0},tt.args.hash)
+			kademlia.refreshContacts[*tt.args.hash] = []routing.Contact{tt.fields.contact}
+			kademlia.Forget(tt.args.hash)
+
+			if kademlia.refreshContacts[*tt.args.hash] != nil {
+				t.Errorf("Forget() = %v, want %v", kademlia.refreshContacts[*tt.args.hash],nil)
+			}
+		})
+	}
+}
+
+func TestNode_StoreQuota(t *testing.T) {
+	me := routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000000"), "")
+	near := routing.NewKademliaID("0000000000000000000000000000000000000001")
+	middle := routing.NewKademliaID("0000000000000000000000000000000000000100")
+	far := routing.NewKademliaID("1000000000000000000000000000000000000000")
+
+	// Item quota: a key further away than everything in storage is rejected ...
+	{
+		kademlia := NewNode(me)
+		kademlia.SetQuota(0, 2)
+		kademlia.Store([]byte{1}, near)
+		kademlia.Store([]byte{2}, middle)
+		if err := kademlia.Store([]byte{3}, far); err != ErrStorageFull {
+			t.Errorf("Store() = %v, want %v", err, ErrStorageFull)
+		}
+		if kademlia.LookupData(far) != nil || len(kademlia.storage) != 2 {
+			t.Errorf("Store() stored data beyond the item quota")
+		}
+	}
+	// ... and a closer key evicts the furthest one
+	{
+		kademlia := NewNode(me)
+		kademlia.SetQuota(0, 2)
+		kademlia.Store([]byte{2}, middle)
+		kademlia.Store([]byte{3}, far)
+		if err := kademlia.Store([]byte{1}, near); err != nil {
+			t.Errorf("Store() = %v, want %v", err, nil)
+		}
+		if kademlia.LookupData(far) != nil || kademlia.LookupData(near) == nil || kademlia.LookupData(middle) == nil {
+			t.Errorf("Store() did not evict the key furthest away")
+		}
+	}
+	// Cached copies are evicted before anything else, even if they are closer
+	{
+		kademlia := NewNode(me)
+		kademlia.SetQuota(0, 2)
+		kademlia.Cache([]byte{1}, near)
+		kademlia.Store([]byte{3}, far)
+		if err := kademlia.Store([]byte{2}, middle); err != nil {
+			t.Errorf("Store() = %v, want %v", err, nil)
+		}
+		if kademlia.LookupData(near) != nil || kademlia.LookupData(far) == nil {
+			t.Errorf("Store() did not evict the cached copy first")
+		}
+		// A cached copy never evicts a replica
+		if err := kademlia.Cache([]byte{1}, near); err != ErrStorageFull {
+			t.Errorf("Cache() = %v, want %v", err, ErrStorageFull)
+		}
+	}
+	// Byte quota
+	{
+		kademlia := NewNode(me)
+		kademlia.SetQuota(4, 0)
+		if err := kademlia.Store([]byte{1, 2, 3, 4, 5}, near); err != ErrStorageFull {
+			t.Errorf("Store() = %v, want %v", err, ErrStorageFull)
+		}
+		kademlia.Store([]byte{1, 2, 3}, far)
+		if err := kademlia.Store([]byte{1, 2}, near); err != nil {
+			t.Errorf("Store() = %v, want %v", err, nil)
+		}
+		if kademlia.storedBytes != 2 {
+			t.Errorf("storedBytes = %d, want %d", kademlia.storedBytes, 2)
+		}
+	}
+}
+
+func TestNode_StoreRecord(t *testing.T) {
+	_, privateKey, _ := ed25519.GenerateKey(nil)
+	kademlia := NewNode(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000000"), ""))
+
+	first := NewRecord(privateKey, 1, []byte("first"))
+	second := NewRecord(privateKey, 2, []byte("second"))
+	if err := kademlia.StoreRecord(first); err != nil {
+		t.Errorf("StoreRecord() = %v, want %v", err, nil)
+	}
+	if err := kademlia.StoreRecord(second); err != nil {
+		t.Errorf("StoreRecord() = %v, want %v", err, nil)
+	}
+	// Older and equal sequence numbers never replace the stored record
+	if err := kademlia.StoreRecord(first); err != ErrStaleRecord {
+		t.Errorf("StoreRecord() = %v, want %v", err, ErrStaleRecord)
+	}
+	if err := kademlia.StoreRecord(second); err != ErrStaleRecord {
+		t.Errorf("StoreRecord() = %v, want %v", err, ErrStaleRecord)
+	}
+	forged := NewRecord(privateKey, 3, []byte("third"))
+	forged.Value = []byte("forged")
+	if err := kademlia.StoreRecord(forged); err != ErrInvalidRecord {
+		t.Errorf("StoreRecord() = %v, want %v", err, ErrInvalidRecord)
+	}
+
+	stored, _ := DeserializeRecord(kademlia.LookupData(second.Key()))
+	if stored.Sequence != 2 || string(stored.Value) != "second" {
+		t.Errorf("LookupData() = %v, want %v", string(stored.Value), "second")
+	}
+	if kademlia.storedBytes != len(second.Serialize()) {
+		t.Errorf("storedBytes = %d, want %d", kademlia.storedBytes, len(second.Serialize()))
+	}
+}
+
+// Only the signed hash of the data can be stored with an owner, and record keys never get owners
+func TestNode_StoreOwned(t *testing.T) {
+	owner, ownerKey, _ := ed25519.GenerateKey(nil)
+	other, otherKey, _ := ed25519.GenerateKey(nil)
+	data := []byte("hello")
+	hash := routing.NewKademliaIDFromData("hello")
+	record := NewRecord(ownerKey, 1, []byte("record"))
+	kademlia := NewNode(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000000"), ""))
+	kademlia.StoreRecord(record)
+	now := kademlia.clock.Now()
+
+	tests := []struct {
+		name      string
+		data      []byte
+		hash      *routing.KademliaID
+		owner     ed25519.PublicKey
+		signature []byte
+		want      error
+	}{
+		{"Signed by the owner", data, hash, owner, ed25519.Sign(ownerKey, StoreMessage(hash, data)), nil},
+		{"Signed by another key", data, hash, owner, ed25519.Sign(otherKey, StoreMessage(hash, data)), ErrInvalidSignature},
+		{"Signature of other data", data, hash, owner, ed25519.Sign(ownerKey, StoreMessage(hash, []byte("bye"))),
+			ErrInvalidSignature},
+		{"Invalid owner", data, hash, owner[:4], ed25519.Sign(ownerKey, StoreMessage(hash, data)), ErrInvalidSignature},
+		{"Not the hash of the data", data, record.Key(), other, ed25519.Sign(otherKey, StoreMessage(record.Key(), data)),
+			ErrHashMismatch},
+		// The public key is the data that hashes to the key of the record
+		{"Key of a record", record.PublicKey, record.Key(), other,
+			ed25519.Sign(otherKey, StoreMessage(record.Key(), record.PublicKey)), ErrNotOwner},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := kademlia.StoreOwned(tt.data, tt.hash, tt.owner, tt.signature); err != tt.want {
+				t.Errorf("StoreOwned() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if owners := len(kademlia.owners[*hash]); owners != 1 {
+		t.Errorf("len(owners) = %v, want %v", owners, 1)
+	}
+	if owners := len(kademlia.owners[*record.Key()]); owners != 0 {
+		t.Errorf("len(owners) = %v, want %v", owners, 0)
+	}
+	if err := kademlia.Unpublish(record.Key(), other, now, ed25519.Sign(otherKey, DeleteMessage(record.Key(), now))); err != ErrNotOwner {
+		t.Errorf("Unpublish() = %v, want %v", err, ErrNotOwner)
+	}
+}
+
+func TestNode_Unpublish(t *testing.T) {
+	owner, ownerKey, _ := ed25519.GenerateKey(nil)
+	other, otherKey, _ := ed25519.GenerateKey(nil)
+	hash := routing.NewKademliaIDFromData("hello")
+	kademlia := NewNode(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000000"), ""))
+	now := time.Unix(1000, 0)
+	manual := clock.NewManual(now)
+	kademlia.SetClock(manual)
+
+	if err := kademlia.Unpublish(hash, owner, now, ed25519.Sign(ownerKey, DeleteMessage(hash, now))); err != ErrNotFound {
+		t.Errorf("Unpublish() = %v, want %v", err, ErrNotFound)
+	}
+
+	kademlia.StoreOwned([]byte("hello"), hash, owner, ed25519.Sign(ownerKey, StoreMessage(hash, []byte("hello"))))
+	if err := kademlia.Unpublish(hash, other, now, ed25519.Sign(otherKey, DeleteMessage(hash, now))); err != ErrNotOwner {
+		t.Errorf("Unpublish() = %v, want %v", err, ErrNotOwner)
+	}
+	if err := kademlia.Unpublish(hash, owner, now, ed25519.Sign(otherKey, DeleteMessage(hash, now))); err != ErrInvalidSignature {
+		t.Errorf("Unpublish() = %v, want %v", err, ErrInvalidSignature)
+	}
+
+	// Both owners have to delete the data before it is gone
+	kademlia.StoreOwned([]byte("hello"), hash, other, ed25519.Sign(otherKey, StoreMessage(hash, []byte("hello"))))
+	if err := kademlia.Unpublish(hash, owner, now, ed25519.Sign(ownerKey, DeleteMessage(hash, now))); err != nil {
+		t.Errorf("Unpublish() = %v, want %v", err, nil)
+	}
+	if kademlia.LookupData(hash) == nil {
+		t.Errorf("Unpublish() deleted data that is still owned by another node")
+	}
+	if err := kademlia.Unpublish(hash, other, now, ed25519.Sign(otherKey, DeleteMessage(hash, now))); err != nil {
+		t.Errorf("Unpublish() = %v, want %v", err, nil)
+	}
+	if kademlia.LookupData(hash) != nil {
+		t.Errorf("Unpublish() did not delete the data")
+	}
+
+	// Records are owned by their publisher
+	record := NewRecord(ownerKey, 1, []byte("record"))
+	kademlia.StoreRecord(record)
+	if err := kademlia.Unpublish(record.Key(), other, now, ed25519.Sign(otherKey, DeleteMessage(record.Key(), now))); err != ErrNotOwner {
+		t.Errorf("Unpublish() = %v, want %v", err, ErrNotOwner)
+	}
+	if err := kademlia.Unpublish(record.Key(), owner, now, ed25519.Sign(ownerKey, DeleteMessage(record.Key(), now))); err != nil {
+		t.Errorf("Unpublish() = %v, want %v", err, nil)
+	}
+
+	// Cached copies are dropped by anyone who can sign a DELETE request
+	kademlia.Cache([]byte("hello"), hash)
+	signedAt := now.Add(time.Second)
+	if err := kademlia.Unpublish(hash, other, signedAt, ed25519.Sign(otherKey, DeleteMessage(hash, signedAt))); err != nil {
+		t.Errorf("Unpublish() = %v, want %v", err, nil)
+	}
+	if kademlia.LookupData(hash) != nil {
+		t.Errorf("Unpublish() did not drop the cached copy")
+	}
+
+	// A DELETE request can't be replayed after the data is stored again, and expires after DELETE_MAX_AGE
+	kademlia.StoreOwned([]byte("hello"), hash, owner, ed25519.Sign(ownerKey, StoreMessage(hash, []byte("hello"))))
+	if err := kademlia.Unpublish(hash, owner, now, ed25519.Sign(ownerKey, DeleteMessage(hash, now))); err != ErrStaleDelete {
+		t.Errorf("Unpublish() = %v, want %v", err, ErrStaleDelete)
+	}
+	if err := kademlia.Unpublish(hash, owner, now, ed25519.Sign(ownerKey, DeleteMessage(hash, now.Add(time.Second)))); err != ErrInvalidSignature {
+		t.Errorf("Unpublish() = %v, want %v", err, ErrInvalidSignature)
+	}
+	manual.Advance(2 * DELETE_MAX_AGE * time.Millisecond)
+	kademlia.Expire()
+	kademlia.StoreOwned([]byte("hello"), hash, owner, ed25519.Sign(ownerKey, StoreMessage(hash, []byte("hello"))))
+	if err := kademlia.Unpublish(hash, owner, now, ed25519.Sign(ownerKey, DeleteMessage(hash, now))); err != ErrStaleDelete {
+		t.Errorf("Unpublish() = %v, want %v", err, ErrStaleDelete)
+	}
+	if len(kademlia.deletes) != 0 {
+		t.Errorf("Expire() kept %v old DELETE requests, want %v", len(kademlia.deletes), 0)
+	}
+	later := manual.Now()
+	if err := kademlia.Unpublish(hash, owner, later, ed25519.Sign(ownerKey, DeleteMessage(hash, later))); err != nil {
+		t.Errorf("Unpublish() = %v, want %v", err, nil)
+	}
+}
+
+// Data expires exactly TIME_TO_LIVE after it was stored or last refreshed
+func TestNode_Expiry(t *testing.T) {
+	manual := clock.NewManual(time.Unix(0, 0))
+	kademlia := NewNode(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000000"), ""))
+	kademlia.SetClock(manual)
+	ttl := TIME_TO_LIVE * time.Millisecond
+	hash := routing.NewKademliaIDFromData("expiring")
+	kademlia.Store([]byte("expiring"), hash)
+
+	manual.Advance(ttl - time.Millisecond)
+	if data := kademlia.LookupData(hash); string(data) != "expiring" {
+		t.Errorf("LookupData() = %v, want %v", string(data), "expiring")
+	}
+	kademlia.Refresh(hash)
+	manual.Advance(ttl - time.Millisecond)
+	kademlia.Expire()
+	if data := kademlia.LookupData(hash); string(data) != "expiring" {
+		t.Errorf("LookupData() = %v, want %v", string(data), "expiring")
+	}
+	manual.Advance(time.Millisecond)
+	if data := kademlia.LookupData(hash); data != nil {
+		t.Errorf("LookupData() = %v, want %v", string(data), nil)
+	}
+	kademlia.Expire()
+	if kademlia.storedBytes != 0 || len(kademlia.ttl) != 0 {
+		t.Errorf("Expire() left %d bytes and %d ttls, want 0 and 0", kademlia.storedBytes, len(kademlia.ttl))
+	}
+	// Expired data can't be refreshed, but it can be stored again
+	kademlia.Refresh(hash)
+	kademlia.Store([]byte("expiring"), hash)
+	if data := kademlia.LookupData(hash); string(data) != "expiring" {
+		t.Errorf("LookupData() = %v, want %v", string(data), "expiring")
+	}
+	want := Stats{Items: 1, Bytes: len("expiring"), Expirations: 1, Refreshes: 1}
+	if stats := kademlia.Stats(); stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+	manual.Advance(time.Second)
+	wantItem := Item{Hash: *hash, Size: len("expiring"), TTL: ttl - time.Second}
+	if items := kademlia.Items(); len(items) != 1 || items[0] != wantItem {
+		t.Errorf("Items() = %+v, want %+v", items, []Item{wantItem})
+	}
+}
+
+func TestNode_ContentType(t *testing.T) {
+	kademlia := NewNode(routing.NewContact(routing.NewKademliaID("0000000000000000000000000000000000000000"), ""))
+	hash := routing.NewKademliaIDFromData("<p>hello</p>")
+
+	// Nothing is stored yet
+	kademlia.SetContentType(hash, "text/html")
+	if contentType := kademlia.ContentType(hash); contentType != "" {
+		t.Errorf("ContentType() = %v, want %v", contentType, "")
+	}
+	kademlia.Store([]byte("<p>hello</p>"), hash)
+	kademlia.SetContentType(hash, "text/html")
+	if contentType := kademlia.ContentType(hash); contentType != "text/html" {
+		t.Errorf("ContentType() = %v, want %v", contentType, "text/html")
+	}
+	if items := kademlia.Items(); len(items) != 1 || items[0].ContentType != "text/html" {
+		t.Errorf("Items() = %+v, want content type %v", items, "text/html")
+	}
+	// The last content type set wins, and it goes with the data
+	kademlia.SetContentType(hash, "text/plain")
+	if contentType := kademlia.ContentType(hash); contentType != "text/plain" {
+		t.Errorf("ContentType() = %v, want %v", contentType, "text/plain")
+	}
+	kademlia.Delete(hash)
+	kademlia.Store([]byte("<p>hello</p>"), hash)
+	if contentType := kademlia.ContentType(hash); contentType != "" {
+		t.Errorf("ContentType() = %v, want %v", contentType, "")
+	}
+}
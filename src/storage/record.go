@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"crypto/ed25519"
+	"d7024e/routing"
+	"encoding/binary"
+	"errors"
+)
+
+// A record is a mutable value that can be updated under a stable name, as opposed to the immutable data objects
+// that are stored under the hash of their content. The name (key) of a record is the hash of the Ed25519 public key
+// of its publisher, and only the owner of the private key can sign new versions of it.
+// Newer versions have a higher sequence number and replace the older ones in the network.
+
+// Serialized record format:
+// [PUBLIC KEY (32 bytes), SEQUENCE (8 bytes), SIGNATURE (64 bytes), VALUE LENGTH (2 bytes), VALUE...]
+// The value length is needed because received messages are padded with zeros.
+const RECORD_HEADER_LEN = ed25519.PublicKeySize + 8 + ed25519.SignatureSize + 2
+
+var ErrInvalidRecord = errors.New("invalid record signature")
+var ErrStaleRecord = errors.New("a record with the same or a higher sequence number is already stored")
+
+type Record struct {
+	PublicKey ed25519.PublicKey
+	Sequence  uint64
+	Signature []byte
+	Value     []byte
+}
+
+// NewRecord creates a new record with some value and signs it with the private key of the publisher
+func NewRecord(privateKey ed25519.PrivateKey, sequence uint64, value []byte) *Record {
+	record := &Record{PublicKey: privateKey.Public().(ed25519.PublicKey), Sequence: sequence, Value: value}
+	record.Signature = ed25519.Sign(privateKey, record.signedBytes())
+	return record
+}
+
+// NewKademliaIDFromPublicKey returns the key that records signed by some public key are stored under
+func NewKademliaIDFromPublicKey(publicKey ed25519.PublicKey) *routing.KademliaID {
+	return routing.NewKademliaIDFromData(string(publicKey))
+}
+
+// Key returns the kademlia ID the record is stored under
+func (record *Record) Key() *routing.KademliaID {
+	return NewKademliaIDFromPublicKey(record.PublicKey)
+}
+
+// signedBytes returns the part of the record that is covered by the signature: the sequence number and the value.
+// They are prefixed with "RECORD" because the same key signs STORE and DELETE requests (see StoreMessage and
+// DeleteMessage), which must never verify as a record
+func (record *Record) signedBytes() []byte {
+	msg := make([]byte, len("RECORD")+8, len("RECORD")+8+len(record.Value))
+	copy(msg, "RECORD")
+	binary.BigEndian.PutUint64(msg[len("RECORD"):], record.Sequence)
+	return append(msg, record.Value...)
+}
+
+// Verify checks that the record is signed by the owner of the public key
+func (record *Record) Verify() bool {
+	if len(record.PublicKey) != ed25519.PublicKeySize || len(record.Signature) != ed25519.SignatureSize {
+		return false
+	}
+	return ed25519.Verify(record.PublicKey, record.signedBytes(), record.Signature)
+}
+
+// Serialize turns the record into a byte slice that can be sent in a message or stored in a node
+func (record *Record) Serialize() []byte {
+	data := make([]byte, RECORD_HEADER_LEN+len(record.Value))
+	copy(data, record.PublicKey)
+	binary.BigEndian.PutUint64(data[ed25519.PublicKeySize:], record.Sequence)
+	copy(data[ed25519.PublicKeySize+8:], record.Signature)
+	binary.BigEndian.PutUint16(data[RECORD_HEADER_LEN-2:], uint16(len(record.Value)))
+	copy(data[RECORD_HEADER_LEN:], record.Value)
+	return data
+}
+
+// DeserializeRecord is the inverse of Serialize. Trailing bytes after the value are ignored.
+// The signature is not checked, see Verify
+func DeserializeRecord(data []byte) (*Record, error) {
+	if len(data) < RECORD_HEADER_LEN {
+		return nil, errors.New("record is too short")
+	}
+	valueLen := int(binary.BigEndian.Uint16(data[RECORD_HEADER_LEN-2:]))
+	if len(data) < RECORD_HEADER_LEN+valueLen {
+		return nil, errors.New("record value is truncated")
+	}
+	record := &Record{
+		PublicKey: append(ed25519.PublicKey{}, data[:ed25519.PublicKeySize]...),
+		Sequence:  binary.BigEndian.Uint64(data[ed25519.PublicKeySize:]),
+		Signature: append([]byte{}, data[ed25519.PublicKeySize+8:RECORD_HEADER_LEN-2]...),
+		Value:     append([]byte{}, data[RECORD_HEADER_LEN:RECORD_HEADER_LEN+valueLen]...),
+	}
+	return record, nil
+}
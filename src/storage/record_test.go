@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"crypto/ed25519"
+	"d7024e/routing"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// A record should survive a round trip through Serialize and DeserializeRecord, also when padded with zeros
+// like the messages we receive
+func TestRecord_Serialize(t *testing.T) {
+	_, privateKey, _ := ed25519.GenerateKey(nil)
+	record := NewRecord(privateKey, 42, []byte("hello"))
+
+	data := append(record.Serialize(), make([]byte, 10)...)
+	result, err := DeserializeRecord(data)
+	if err != nil {
+		t.Fatalf("DeserializeRecord() = %v, want %v", err, nil)
+	}
+	if result.Sequence != 42 || string(result.Value) != "hello" || !result.Key().Equals(record.Key()) {
+		t.Errorf("DeserializeRecord() = %v, want %v", result, record)
+	}
+	if !result.Verify() {
+		t.Errorf("Verify() = %v, want %v", false, true)
+	}
+
+	if _, err := DeserializeRecord(data[:RECORD_HEADER_LEN-1]); err == nil {
+		t.Errorf("DeserializeRecord() accepted a truncated record")
+	}
+}
+
+// Changing anything that is signed should invalidate the record
+func TestRecord_Verify(t *testing.T) {
+	_, privateKey, _ := ed25519.GenerateKey(nil)
+	_, otherKey, _ := ed25519.GenerateKey(nil)
+
+	record := NewRecord(privateKey, 1, []byte("hello"))
+	record.Value = []byte("world")
+	if record.Verify() {
+		t.Errorf("Verify() accepted a record with a modified value")
+	}
+
+	record = NewRecord(privateKey, 1, []byte("hello"))
+	record.Sequence = 2
+	if record.Verify() {
+		t.Errorf("Verify() accepted a record with a modified sequence number")
+	}
+
+	record = NewRecord(privateKey, 1, []byte("hello"))
+	record.PublicKey = otherKey.Public().(ed25519.PublicKey)
+	if record.Verify() {
+		t.Errorf("Verify() accepted a record signed by someone else")
+	}
+}
+
+// The signature of a STORE or DELETE request can't be turned into a record of the same key
+func TestRecord_VerifyOtherMessages(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(nil)
+	hash := routing.NewKademliaIDFromData("hello")
+	messages := map[string][]byte{
+		"STORE":  StoreMessage(hash, []byte("hello")),
+		"DELETE": DeleteMessage(hash, time.Now()),
+	}
+	for name, message := range messages {
+		forged := &Record{PublicKey: publicKey, Sequence: binary.BigEndian.Uint64(message), Value: message[8:],
+			Signature: ed25519.Sign(privateKey, message)}
+		if forged.Verify() {
+			t.Errorf("Verify() accepted the signature of a %s request as a record", name)
+		}
+	}
+}
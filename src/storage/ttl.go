@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"d7024e/routing"
+	"time"
+)
+
+// How long (ms) a stored data object lives unless it is refreshed
+const TIME_TO_LIVE = 30 * 1000
+
+// UpdateTTL runs a loop that deletes all stored data objects whose ttl has run out (see Expire), until the
+// context is done
+func (kademlia *Node) UpdateTTL(ctx context.Context) {
+	for {
+		kademlia.Expire()
+		// Sleeping to improve performance, no need to work all the time
+		select {
+		case <-kademlia.clock.After(1 * time.Second):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Expire deletes all stored data objects whose ttl has run out. Expired data is never returned by LookupData,
+// even before it is deleted. Also forgets the accepted DELETE requests that are too old to be replayed
+func (kademlia *Node) Expire() {
+	kademlia.storateMutex.Lock()
+	defer kademlia.storateMutex.Unlock()
+	oldest := kademlia.clock.Now().Add(-DELETE_MAX_AGE * time.Millisecond)
+	for key, timestamp := range kademlia.deletes {
+		if timestamp.Before(oldest) {
+			delete(kademlia.deletes, key)
+		}
+	}
+	for dataHash := range kademlia.ttl {
+		dataHash := dataHash
+		if kademlia.expired(&dataHash) {
+			kademlia.remove(&dataHash)
+			kademlia.expirations++
+			kademlia.log.Debug("Deleted expired data", "hash", dataHash)
+		}
+	}
+}
+
+// expired returns true if the ttl of some stored data has run out. The storage mutex must be held by the caller
+func (kademlia *Node) expired(hash *routing.KademliaID) bool {
+	return !kademlia.clock.Now().Before(kademlia.ttl[*hash])
+}
@@ -0,0 +1,131 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// MemoryNetwork connects in-memory transports with each other, so that many nodes can run in a single process
+// without sockets (mostly for tests). Messages behave like datagrams: they are dropped when the queue of the
+// receiver is full, and a request that isn't answered in time fails.
+type MemoryNetwork struct {
+	mutex      sync.Mutex
+	transports map[string]*MemoryTransport
+}
+
+// MemoryTransport is a Transport that delivers messages to the transports of a MemoryNetwork
+type MemoryTransport struct {
+	network *MemoryNetwork
+	ip      string
+	port    string
+	maxSize int
+	timeout time.Duration
+
+	incoming  chan memoryPacket
+	closed    chan bool
+	closeOnce sync.Once
+}
+
+// A request on its way to a MemoryTransport, with the channel its reply is sent on
+type memoryPacket struct {
+	msg   []byte
+	from  string
+	reply chan []byte
+}
+
+const MEMORY_QUEUE_LEN = 64 // Number of requests a MemoryTransport queues before it drops new ones
+
+var ErrUnreachable = errors.New("no transport is listening at the address")
+var ErrTimeout = errors.New("timed out waiting for a reply")
+
+func NewMemoryNetwork() *MemoryNetwork {
+	return &MemoryNetwork{transports: make(map[string]*MemoryTransport)}
+}
+
+// NewTransport returns a transport for the node with some IP address that listens on port. It accepts messages
+// of at most maxSize bytes and waits at most timeout for replies
+func (memoryNetwork *MemoryNetwork) NewTransport(ip string, port string, maxSize int, timeout time.Duration) *MemoryTransport {
+	return &MemoryTransport{network: memoryNetwork, ip: ip, port: port, maxSize: maxSize, timeout: timeout,
+		incoming: make(chan memoryPacket, MEMORY_QUEUE_LEN), closed: make(chan bool)}
+}
+
+func (transport *MemoryTransport) Listen(handler Handler) error {
+	address := transport.ip + ":" + transport.port
+	transport.network.mutex.Lock()
+	select {
+	case <-transport.closed:
+		transport.network.mutex.Unlock()
+		return nil
+	default:
+	}
+	if transport.network.transports[address] != nil {
+		transport.network.mutex.Unlock()
+		return errors.New("address " + address + " is already in use")
+	}
+	transport.network.transports[address] = transport
+	transport.network.mutex.Unlock()
+
+	for {
+		select {
+		case packet := <-transport.incoming:
+			handler(context.Background(), packet.msg, packet.from, func(reply []byte) error {
+				if len(reply) > transport.maxSize {
+					return ErrMessageTooLarge
+				}
+				select {
+				case packet.reply <- append([]byte{}, reply...):
+					return nil
+				default:
+					return errors.New("the request was already answered")
+				}
+			})
+		case <-transport.closed:
+			return nil
+		}
+	}
+}
+
+func (transport *MemoryTransport) SendRequest(ctx context.Context, address string, request []byte, expectReply bool) ([]byte, error) {
+	if len(request) > transport.maxSize {
+		return nil, ErrMessageTooLarge
+	}
+	transport.network.mutex.Lock()
+	remote := transport.network.transports[address+":"+transport.port]
+	transport.network.mutex.Unlock()
+	if remote == nil {
+		return nil, ErrUnreachable
+	}
+
+	packet := memoryPacket{append([]byte{}, request...), transport.ip, make(chan []byte, 1)}
+	select {
+	case remote.incoming <- packet:
+	default:
+		// The queue is full, the request is lost
+	}
+	if !expectReply {
+		return nil, nil
+	}
+	select {
+	case reply := <-packet.reply:
+		return reply, nil
+	case <-time.After(transport.timeout):
+		return nil, ErrTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (transport *MemoryTransport) Close() error {
+	transport.closeOnce.Do(func() {
+		address := transport.ip + ":" + transport.port
+		transport.network.mutex.Lock()
+		if transport.network.transports[address] == transport {
+			delete(transport.network.transports, address)
+		}
+		transport.network.mutex.Unlock()
+		close(transport.closed)
+	})
+	return nil
+}
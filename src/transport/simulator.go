@@ -0,0 +1,578 @@
+package transport
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Simulator is an in-memory network with a virtual clock for tests with many nodes. Every message is delayed,
+// dropped, duplicated or reordered according to the LinkConfig of the link it is sent over, and nodes in
+// different partitions can't reach each other at all.
+//
+// The decisions for a link are drawn from its own random generator, seeded from the seed of the simulator and
+// the addresses of the link, so a run with the same seed that sends the same messages over a link gets the same
+// losses and delays.
+//
+// The simulator tracks the participants that take part in it: the handlers of the requests it delivers, the
+// operations that were given a context with Participate, and the goroutines started with Go. A participant is
+// identified by its context, which it sends its requests with. Virtual time only moves forward, one event at a time,
+// when none of them is running, i.e. when all of them wait for a reply, for Wait or Block, or for the next request.
+// So latencies and timeouts don't take any real time, and a run doesn't depend on how the goroutines are
+// scheduled. A participant that waits for something else than the simulator (like the goroutines it started with
+// Go) must do so in Block, and must call the function returned by Participate when it is done, or time stops.
+// Requests that are sent with a context without a participant are delivered, but nothing waits for their sender.
+type Simulator struct {
+	mutex   sync.Mutex
+	changed *sync.Cond // Broadcast when a participant stops running, and when an event is scheduled or run
+	seed    int64
+
+	now    time.Duration // Virtual time since the simulator was created
+	events simEvents
+
+	transports  map[string]*SimTransport
+	defaultLink LinkConfig
+	links       map[simLinkKey]*simLink
+	partitions  map[string]string // The name of the partition of each IP address. Unlisted addresses are in ""
+
+	// The participants that are running, by ID, and the number of goroutines started with Go that haven't started
+	// running yet. Time is advanced when both are empty and no listener has a request queued that it is free to
+	// handle
+	participants uint64 // Number of participants so far, the ID of the last one
+	running      map[uint64]bool
+	starting     int
+	blocked      map[uint64]bool // The participants in Block
+	stopped      bool
+}
+
+// simParticipantKey is the key of the ID of a participant in its context
+type simParticipantKey struct {
+	sim *Simulator
+}
+
+// LinkConfig describes how messages are transferred from one node to another
+type LinkConfig struct {
+	Latency     time.Duration // Delay of every message
+	Jitter      time.Duration // Random extra delay of up to Jitter. Messages are reordered when it is larger than the time between them
+	Loss        float64       // Probability that a datagram is lost
+	Duplication float64       // Probability that a datagram is delivered twice
+}
+
+// SimTransport is a Transport of a node in a Simulator. Reliable transports (streams) don't lose or duplicate
+// messages, but they are delayed and partitioned like datagrams
+type SimTransport struct {
+	sim      *Simulator
+	ip       string
+	port     string
+	maxSize  int
+	timeout  time.Duration
+	reliable bool
+
+	incoming chan simPacket
+	queued   int  // Number of requests in incoming
+	handling bool // The listener is handling a request, and doesn't take the next one until it is done
+	closed   chan bool
+	shut     bool
+}
+
+type simLinkKey struct {
+	from, to string
+}
+
+type simLink struct {
+	key      string      // Orders the events of different links at the same time
+	config   *LinkConfig // nil for the default config of the simulator
+	rng      *rand.Rand
+	sequence uint64 // Number of events scheduled on the link so far, orders its events at the same time
+}
+
+// A request on its way to a SimTransport
+type simPacket struct {
+	msg  []byte
+	from string
+	call *simCall
+}
+
+// A request that waits for a reply. It is finished by the first reply or by its timeout
+type simCall struct {
+	waiter chan []byte
+	done   bool
+	caller uint64 // The participant that waits, which runs again when the call is finished. 0 if there is none
+}
+
+// Events are ordered by time, then by link and by the order they were scheduled on the link in. Only one
+// participant runs at a time, or several goroutines that were started together with Go, and those send on
+// different links, so the order doesn't depend on the scheduling of goroutines
+type simEvent struct {
+	at       time.Duration
+	link     string
+	sequence uint64
+	run      func()
+}
+
+type simEvents []*simEvent
+
+const SIM_DEFAULT_LATENCY = 1 * time.Millisecond // Latency of links without a LinkConfig
+const SIM_QUEUE_LEN = 1024                       // Number of requests a SimTransport queues before it drops new ones
+
+var ErrSimulatorStopped = errors.New("the simulator is stopped")
+
+// NewSimulator creates a simulator with the default link config and starts its clock
+func NewSimulator(seed int64) *Simulator {
+	sim := &Simulator{seed: seed, transports: make(map[string]*SimTransport),
+		defaultLink: LinkConfig{Latency: SIM_DEFAULT_LATENCY}, links: make(map[simLinkKey]*simLink),
+		partitions: make(map[string]string), running: make(map[uint64]bool), blocked: make(map[uint64]bool)}
+	sim.changed = sync.NewCond(&sim.mutex)
+	go sim.run()
+	return sim
+}
+
+// NewTransport returns a transport for the node with some IP address that listens on port. It accepts messages
+// of at most maxSize bytes and waits at most timeout (in virtual time) for replies
+func (sim *Simulator) NewTransport(ip string, port string, maxSize int, timeout time.Duration, reliable bool) *SimTransport {
+	transport := &SimTransport{sim: sim, ip: ip, port: port, maxSize: maxSize, timeout: timeout, reliable: reliable,
+		incoming: make(chan simPacket, SIM_QUEUE_LEN), closed: make(chan bool)}
+	// The address is taken right away, so requests that arrive before Listen is called wait for it
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	if sim.transports[ip+":"+port] == nil {
+		sim.transports[ip+":"+port] = transport
+	}
+	return transport
+}
+
+// Now returns the virtual time since the simulator was created
+func (sim *Simulator) Now() time.Duration {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	return sim.now
+}
+
+// SetDefaultLink sets the config of all links that don't have their own
+func (sim *Simulator) SetDefaultLink(config LinkConfig) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	sim.defaultLink = config
+}
+
+// SetLink sets the config of the link from one IP address to another. The other direction is not changed
+func (sim *Simulator) SetLink(from string, to string, config LinkConfig) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	sim.link(from, to).config = &config
+}
+
+// Partition moves some IP addresses into the partition with some name. Addresses in different partitions can't
+// reach each other, and messages between them that are on their way are lost
+func (sim *Simulator) Partition(name string, ips ...string) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	for _, ip := range ips {
+		sim.partitions[ip] = name
+	}
+}
+
+// Heal removes all partitions
+func (sim *Simulator) Heal() {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	sim.partitions = make(map[string]string)
+}
+
+// Participate returns a context for an operation that takes part in the simulator, and the function to call when
+// the operation is done. The operation runs until it sends a request with the context and waits for the reply, or
+// waits in Wait or Block. If ctx already belongs to a participant, it is returned as it is
+func (sim *Simulator) Participate(ctx context.Context) (context.Context, func()) {
+	if sim.participant(ctx) != 0 {
+		return ctx, func() {}
+	}
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	id := sim.newParticipant()
+	var once sync.Once
+	return context.WithValue(ctx, simParticipantKey{sim}, id), func() {
+		once.Do(func() {
+			sim.mutex.Lock()
+			defer sim.mutex.Unlock()
+			delete(sim.running, id)
+			sim.changed.Broadcast()
+		})
+	}
+}
+
+// newParticipant returns the ID of a new participant, which is running. Must be called with the mutex locked
+func (sim *Simulator) newParticipant() uint64 {
+	sim.participants++
+	sim.running[sim.participants] = true
+	return sim.participants
+}
+
+// participant returns the ID of the participant of a context, 0 if it has none
+func (sim *Simulator) participant(ctx context.Context) uint64 {
+	id, _ := ctx.Value(simParticipantKey{sim}).(uint64)
+	return id
+}
+
+// Wait blocks until there are no more messages on their way and every node has handled its requests. The
+// participant of ctx, if any, doesn't run while it waits
+func (sim *Simulator) Wait(ctx context.Context) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	id := sim.participant(ctx)
+	if id != 0 {
+		delete(sim.running, id)
+		sim.changed.Broadcast()
+	}
+	for !sim.stopped && (len(sim.events) > 0 || !sim.idle()) {
+		sim.changed.Wait()
+	}
+	if id != 0 {
+		sim.running[id] = true
+	}
+}
+
+// Go runs f in a new goroutine that takes part in the simulator until f returns, with a context of its own that
+// is derived from ctx. Virtual time doesn't advance between the call and the first time f waits, so goroutines
+// that are started together send their requests at the same time
+func (sim *Simulator) Go(ctx context.Context, f func(ctx context.Context)) {
+	parent := sim.participant(ctx)
+	sim.mutex.Lock()
+	sim.starting++
+	sim.mutex.Unlock()
+	go func() {
+		sim.mutex.Lock()
+		sim.starting--
+		id := sim.newParticipant()
+		sim.mutex.Unlock()
+
+		defer func() {
+			sim.mutex.Lock()
+			delete(sim.running, id)
+			if sim.blocked[parent] {
+				// The participant that started f waits for it in Block, and runs as soon as f is done
+				sim.running[parent] = true
+			}
+			sim.changed.Broadcast()
+			sim.mutex.Unlock()
+		}()
+		f(context.WithValue(ctx, simParticipantKey{sim}, id))
+	}()
+}
+
+// Block runs wait, which blocks until one of the goroutines that the participant of ctx started with Go is done,
+// without keeping virtual time from advancing
+func (sim *Simulator) Block(ctx context.Context, wait func()) {
+	id := sim.participant(ctx)
+	if id == 0 {
+		wait()
+		return
+	}
+	sim.mutex.Lock()
+	delete(sim.running, id)
+	sim.blocked[id] = true
+	sim.changed.Broadcast()
+	sim.mutex.Unlock()
+
+	wait()
+
+	sim.mutex.Lock()
+	delete(sim.blocked, id)
+	sim.running[id] = true
+	sim.mutex.Unlock()
+}
+
+// Stop stops the clock. Requests that wait for a reply afterwards never time out
+func (sim *Simulator) Stop() {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	sim.stopped = true
+	sim.changed.Broadcast()
+}
+
+// run advances the virtual clock to the next event whenever no participant is running, until Stop is called
+func (sim *Simulator) run() {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	for !sim.stopped {
+		if len(sim.events) == 0 || !sim.idle() {
+			sim.changed.Wait()
+			continue
+		}
+		event := heap.Pop(&sim.events).(*simEvent)
+		sim.now = event.at
+		event.run()
+		sim.changed.Broadcast()
+	}
+}
+
+// idle returns true if no participant is running or about to. Must be called with the mutex locked
+func (sim *Simulator) idle() bool {
+	if len(sim.running) > 0 || sim.starting > 0 {
+		return false
+	}
+	for _, transport := range sim.transports {
+		if transport.queued > 0 && !transport.handling {
+			return false
+		}
+	}
+	return true
+}
+
+// schedule runs f after delay in virtual time, as the next event of a link. Must be called with the mutex locked,
+// and f is run with the mutex locked
+func (sim *Simulator) schedule(link *simLink, delay time.Duration, f func()) {
+	link.sequence++
+	heap.Push(&sim.events, &simEvent{sim.now + delay, link.key, link.sequence, f})
+	sim.changed.Broadcast()
+}
+
+// link returns the link from one IP address to another. Must be called with the mutex locked
+func (sim *Simulator) link(from string, to string) *simLink {
+	key := simLinkKey{from, to}
+	if sim.links[key] == nil {
+		hash := fnv.New64a()
+		hash.Write([]byte(from + ">" + to))
+		sim.links[key] = &simLink{key: from + ">" + to, rng: rand.New(rand.NewSource(sim.seed ^ int64(hash.Sum64())))}
+	}
+	return sim.links[key]
+}
+
+// transmit sends a message over the link from one IP address to another and calls deliver when (and if) it
+// arrives. Must be called with the mutex locked
+func (sim *Simulator) transmit(from string, to string, reliable bool, deliver func()) {
+	if sim.partitions[from] != sim.partitions[to] {
+		return
+	}
+	link := sim.link(from, to)
+	config := &sim.defaultLink
+	if link.config != nil {
+		config = link.config
+	}
+	copies := 1
+	if !reliable {
+		if link.rng.Float64() < config.Loss {
+			return
+		}
+		if link.rng.Float64() < config.Duplication {
+			copies = 2
+		}
+	}
+	for i := 0; i < copies; i++ {
+		delay := config.Latency
+		if config.Jitter > 0 {
+			delay += time.Duration(link.rng.Int63n(int64(config.Jitter)))
+		}
+		sim.schedule(link, delay, func() {
+			if sim.partitions[from] == sim.partitions[to] {
+				deliver()
+			}
+		})
+	}
+}
+
+// finish finishes a call with a reply, or with nil if it timed out, and the participant that waited runs again.
+// Must be called with the mutex locked
+func (sim *Simulator) finish(call *simCall, reply []byte) {
+	if call.done {
+		return
+	}
+	call.done = true
+	if call.caller != 0 {
+		sim.running[call.caller] = true
+	}
+	call.waiter <- reply
+}
+
+func (transport *SimTransport) Listen(handler Handler) error {
+	sim := transport.sim
+	address := transport.ip + ":" + transport.port
+	sim.mutex.Lock()
+	if transport.shut {
+		sim.mutex.Unlock()
+		return nil
+	}
+	if sim.transports[address] != transport {
+		sim.mutex.Unlock()
+		return errors.New("address " + address + " is already in use")
+	}
+	// The listener is a participant that runs while it handles a request
+	id := sim.newParticipant()
+	delete(sim.running, id)
+	sim.mutex.Unlock()
+	ctx := context.WithValue(context.Background(), simParticipantKey{sim}, id)
+
+	for {
+		select {
+		case packet := <-transport.incoming:
+			sim.mutex.Lock()
+			transport.queued--
+			transport.handling = true
+			sim.running[id] = true
+			sim.mutex.Unlock()
+
+			handler(ctx, packet.msg, packet.from, func(reply []byte) error {
+				return transport.reply(packet, reply)
+			})
+
+			sim.mutex.Lock()
+			transport.handling = false
+			delete(sim.running, id)
+			sim.changed.Broadcast()
+			sim.mutex.Unlock()
+		case <-transport.closed:
+			return nil
+		}
+	}
+}
+
+// reply sends a reply to a request back to its sender
+func (transport *SimTransport) reply(packet simPacket, reply []byte) error {
+	if len(reply) > transport.maxSize {
+		return ErrMessageTooLarge
+	}
+	if packet.call == nil {
+		return errors.New("the request does not expect a reply")
+	}
+	sim := transport.sim
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	msg := append([]byte{}, reply...)
+	sim.transmit(transport.ip, packet.from, transport.reliable, func() {
+		sim.finish(packet.call, msg)
+	})
+	return nil
+}
+
+func (transport *SimTransport) SendRequest(ctx context.Context, address string, request []byte, expectReply bool) ([]byte, error) {
+	if len(request) > transport.maxSize {
+		return nil, ErrMessageTooLarge
+	}
+	sim := transport.sim
+	sim.mutex.Lock()
+	if sim.stopped {
+		sim.mutex.Unlock()
+		return nil, ErrSimulatorStopped
+	}
+	// The participant that sends the request runs until it waits for the reply. A request that expects no reply
+	// doesn't change whether it runs
+	id := sim.participant(ctx)
+
+	var call *simCall
+	if expectReply {
+		call = &simCall{waiter: make(chan []byte, 1), caller: id}
+	}
+	msg := append([]byte{}, request...)
+	sim.transmit(transport.ip, address, transport.reliable, func() {
+		remote := sim.transports[address+":"+transport.port]
+		if remote == nil || remote.shut {
+			return
+		}
+		packet := simPacket{msg, transport.ip, call}
+		select {
+		case remote.incoming <- packet:
+			remote.queued++
+		default:
+			// The queue is full, the request is lost
+		}
+	})
+	if !expectReply {
+		sim.mutex.Unlock()
+		return nil, nil
+	}
+	sim.schedule(sim.link(transport.ip, address), transport.timeout, func() {
+		sim.finish(call, nil)
+	})
+	if id != 0 {
+		delete(sim.running, id)
+		sim.changed.Broadcast()
+	}
+	sim.mutex.Unlock()
+
+	select {
+	case reply := <-call.waiter:
+		if reply != nil {
+			return reply, nil
+		}
+		return nil, ErrTimeout
+	case <-ctx.Done():
+		// The call is abandoned, a reply that arrives later is dropped
+		sim.mutex.Lock()
+		call.done = true
+		if id != 0 {
+			sim.running[id] = true
+		}
+		sim.mutex.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (transport *SimTransport) Close() error {
+	sim := transport.sim
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	if transport.shut {
+		return nil
+	}
+	transport.shut = true
+	address := transport.ip + ":" + transport.port
+	if sim.transports[address] == transport {
+		delete(sim.transports, address)
+	}
+	// Requests that are still queued are never handled
+	for {
+		select {
+		case <-transport.incoming:
+			transport.queued--
+			continue
+		default:
+		}
+		break
+	}
+	close(transport.closed)
+	sim.changed.Broadcast()
+	return nil
+}
+
+// Participate returns a context for an operation that takes part in the simulator of the transport, see
+// Simulator.Participate
+func (transport *SimTransport) Participate(ctx context.Context) (context.Context, func()) {
+	return transport.sim.Participate(ctx)
+}
+
+// Go runs f in a new goroutine that takes part in the simulator of the transport, see Simulator.Go
+func (transport *SimTransport) Go(ctx context.Context, f func(ctx context.Context)) {
+	transport.sim.Go(ctx, f)
+}
+
+// Block runs wait without keeping the virtual time of the simulator of the transport from advancing, see
+// Simulator.Block
+func (transport *SimTransport) Block(ctx context.Context, wait func()) {
+	transport.sim.Block(ctx, wait)
+}
+
+func (events simEvents) Len() int { return len(events) }
+
+func (events simEvents) Less(i, j int) bool {
+	if events[i].at != events[j].at {
+		return events[i].at < events[j].at
+	}
+	if events[i].link != events[j].link {
+		return events[i].link < events[j].link
+	}
+	return events[i].sequence < events[j].sequence
+}
+
+func (events simEvents) Swap(i, j int) { events[i], events[j] = events[j], events[i] }
+
+func (events *simEvents) Push(event interface{}) { *events = append(*events, event.(*simEvent)) }
+
+func (events *simEvents) Pop() interface{} {
+	old := *events
+	event := old[len(old)-1]
+	*events = old[:len(old)-1]
+	return event
+}
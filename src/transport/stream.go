@@ -0,0 +1,130 @@
+package transport
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// A stream carries a single request and its reply, each as [LENGTH (4 bytes), MESSAGE...]
+
+const MAX_STREAM_MESSAGE_SIZE = 16 << 20 // Largest message that is accepted over a stream
+
+var ErrStreamMessageTooLarge = errors.New("stream message is too large")
+
+// writeStreamMessage writes a length prefixed message to a stream
+func writeStreamMessage(conn net.Conn, msg []byte) error {
+	if len(msg) > MAX_STREAM_MESSAGE_SIZE {
+		return ErrStreamMessageTooLarge
+	}
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(msg)))
+	if _, err := conn.Write(length[:]); err != nil {
+		return err
+	}
+	_, err := conn.Write(msg)
+	return err
+}
+
+// readStreamMessage reads a length prefixed message from a stream
+func readStreamMessage(conn net.Conn) ([]byte, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	if binary.BigEndian.Uint32(length[:]) > MAX_STREAM_MESSAGE_SIZE {
+		return nil, ErrStreamMessageTooLarge
+	}
+	msg := make([]byte, binary.BigEndian.Uint32(length[:]))
+	_, err := io.ReadFull(conn, msg)
+	return msg, err
+}
+
+// TCPTransport sends every request and its reply over a new TCP connection
+type TCPTransport struct {
+	port    string
+	timeout time.Duration
+
+	mutex    sync.Mutex
+	listener net.Listener
+	closed   bool
+}
+
+// NewTCPTransport returns a TCP transport that listens on port. A request and its reply may take at most timeout
+func NewTCPTransport(port string, timeout time.Duration) *TCPTransport {
+	return &TCPTransport{port: port, timeout: timeout}
+}
+
+func (transport *TCPTransport) Listen(handler Handler) error {
+	listener, err := net.Listen("tcp", ":"+transport.port)
+	if err != nil {
+		return err
+	}
+	transport.mutex.Lock()
+	if transport.closed {
+		transport.mutex.Unlock()
+		listener.Close()
+		return nil
+	}
+	transport.listener = listener
+	transport.mutex.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			transport.mutex.Lock()
+			closed := transport.closed
+			transport.mutex.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		go transport.serve(conn, handler)
+	}
+}
+
+// serve reads a request from an incoming stream and passes it to handler
+func (transport *TCPTransport) serve(conn net.Conn, handler Handler) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(transport.timeout))
+	msg, err := readStreamMessage(conn)
+	if err != nil {
+		logger.Warn("Could not read from incoming stream", "peer", conn.RemoteAddr(), "error", err)
+		return
+	}
+	from, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+	handler(context.Background(), msg, from, func(reply []byte) error {
+		return writeStreamMessage(conn, reply)
+	})
+}
+
+func (transport *TCPTransport) SendRequest(ctx context.Context, address string, request []byte, expectReply bool) ([]byte, error) {
+	dialer := net.Dialer{Timeout: transport.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address+":"+transport.port)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	defer conn.Close()
+	defer interruptOnDone(ctx, conn)()
+	conn.SetDeadline(time.Now().Add(transport.timeout))
+	if err := writeStreamMessage(conn, request); err != nil || !expectReply {
+		return nil, contextError(ctx, err)
+	}
+	reply, err := readStreamMessage(conn)
+	return reply, contextError(ctx, err)
+}
+
+func (transport *TCPTransport) Close() error {
+	transport.mutex.Lock()
+	defer transport.mutex.Unlock()
+	transport.closed = true
+	if transport.listener != nil {
+		return transport.listener.Close()
+	}
+	return nil
+}
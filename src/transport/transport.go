@@ -0,0 +1,176 @@
+// Package transport implements the ways kademlia nodes exchange messages: UDP datagrams, TCP streams, and
+// in-memory and simulated networks for tests.
+package transport
+
+import (
+	"context"
+	"d7024e/logging"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// A Transport moves encoded messages between nodes. The network uses one transport for datagrams (routing traffic
+// and small values) and optionally one for streams (large values). Transports only deal with bytes
+// and the IP addresses of nodes, every transport listens on its own port on all nodes.
+type Transport interface {
+	// Listen receives requests and passes them to handler until Close is called
+	Listen(handler Handler) error
+	// SendRequest sends a request to the node with some IP address and waits for its reply if expectReply is set.
+	// It gives up as soon as the context is done and returns the error of the context
+	SendRequest(ctx context.Context, address string, request []byte, expectReply bool) ([]byte, error)
+	// Close stops Listen
+	Close() error
+}
+
+// Handler handles a request from the node with the IP address from. reply sends a reply back to that node. The
+// requests that the handler sends on behalf of this one should use ctx
+type Handler func(ctx context.Context, request []byte, from string, reply func([]byte) error)
+
+// A Tracker is implemented by transports that need to know about the operations of a node that send requests,
+// like a SimTransport which only advances virtual time when none of them is running. An operation is identified by
+// the context it sends its requests with
+type Tracker interface {
+	// Participate returns a context for a new operation, and the function to call when it is done. ctx is returned
+	// as it is if it already belongs to an operation
+	Participate(ctx context.Context) (context.Context, func())
+	// Go runs f in a new goroutine, as an operation of its own
+	Go(ctx context.Context, f func(ctx context.Context))
+	// Block runs wait, which waits until one of the goroutines that the operation of ctx started with Go is done
+	Block(ctx context.Context, wait func())
+}
+
+const MAX_DATAGRAM_SIZE = 1024 // Largest request or reply that is sent in a UDP datagram
+
+var logger = logging.For("transport")
+
+var ErrMessageTooLarge = errors.New("message is too large for the transport")
+var ErrTransportClosed = errors.New("transport is closed")
+
+// UDPTransport sends every request and reply in a single UDP datagram
+type UDPTransport struct {
+	port    string
+	timeout time.Duration
+
+	mutex  sync.Mutex
+	conn   *net.UDPConn
+	closed bool
+}
+
+// NewUDPTransport returns a UDP transport that listens on port and waits at most timeout for replies
+func NewUDPTransport(port string, timeout time.Duration) *UDPTransport {
+	return &UDPTransport{port: port, timeout: timeout}
+}
+
+func (transport *UDPTransport) Listen(handler Handler) error {
+	addr, err := net.ResolveUDPAddr("udp", ":"+transport.port)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return err
+	}
+	transport.mutex.Lock()
+	if transport.closed {
+		transport.mutex.Unlock()
+		conn.Close()
+		return nil
+	}
+	transport.conn = conn
+	transport.mutex.Unlock()
+
+	msg := make([]byte, MAX_DATAGRAM_SIZE)
+	for {
+		n, addr, err := conn.ReadFromUDP(msg)
+		if err != nil {
+			transport.mutex.Lock()
+			closed := transport.closed
+			transport.mutex.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		handler(context.Background(), msg[:n], addr.IP.To4().String(), func(reply []byte) error {
+			if len(reply) > MAX_DATAGRAM_SIZE {
+				return ErrMessageTooLarge
+			}
+			_, err := conn.WriteToUDP(reply, addr)
+			return err
+		})
+	}
+}
+
+func (transport *UDPTransport) SendRequest(ctx context.Context, address string, request []byte, expectReply bool) ([]byte, error) {
+	if len(request) > MAX_DATAGRAM_SIZE {
+		return nil, ErrMessageTooLarge
+	}
+	remoteAddr, err := net.ResolveUDPAddr("udp", address+":"+transport.port)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUDP("udp", nil, remoteAddr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	defer interruptOnDone(ctx, conn)()
+	if _, err := conn.Write(request); err != nil || !expectReply {
+		return nil, contextError(ctx, err)
+	}
+
+	reply := make([]byte, MAX_DATAGRAM_SIZE)
+	conn.SetReadDeadline(time.Now().Add(transport.timeout))
+	n, err := conn.Read(reply)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	return reply[:n], nil
+}
+
+// interruptOnDone interrupts the reads and writes on a connection once the context is done.
+// Call the returned function when the connection is no longer used
+func interruptOnDone(ctx context.Context, conn interface{ SetDeadline(time.Time) error }) func() {
+	stop := make(chan bool)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+	return func() {
+		close(stop)
+	}
+}
+
+// IsTimeout returns true if an error means that no reply arrived in time. The error of a context whose deadline
+// passed is not a timeout of the transport
+func IsTimeout(err error) bool {
+	if err == ErrTimeout {
+		return true
+	}
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout() && err != context.DeadlineExceeded
+}
+
+// contextError returns the error of the context if it is done, because that is why an operation failed, and err
+// otherwise
+func contextError(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+func (transport *UDPTransport) Close() error {
+	transport.mutex.Lock()
+	defer transport.mutex.Unlock()
+	transport.closed = true
+	if transport.conn != nil {
+		return transport.conn.Close()
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Requests and replies between two in-memory transports
+func TestMemoryTransport(t *testing.T) {
+	fake := NewMemoryNetwork()
+	transport1 := fake.NewTransport("0.0.0.0", "5001", 8, 50*time.Millisecond)
+	transport2 := fake.NewTransport("0.0.0.1", "5001", 8, 50*time.Millisecond)
+
+	if _, err := transport2.SendRequest(context.Background(), "0.0.0.0", []byte("ping"), true); err != ErrUnreachable {
+		t.Errorf("SendRequest() = %v, want %v", err, ErrUnreachable)
+	}
+
+	wait := make(chan bool)
+	go func() {
+		transport1.Listen(func(ctx context.Context, request []byte, from string, reply func([]byte) error) {
+			if string(request) == "ping" {
+				reply([]byte(from))
+			}
+		})
+		wait <- true
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	if reply, err := transport2.SendRequest(context.Background(), "0.0.0.0", []byte("ping"), true); err != nil || string(reply) != "0.0.0.1" {
+		t.Errorf("SendRequest() = %v, %v, want %v, %v", string(reply), err, "0.0.0.1", nil)
+	}
+	// The request is not answered
+	if _, err := transport2.SendRequest(context.Background(), "0.0.0.0", []byte("pong"), true); err != ErrTimeout {
+		t.Errorf("SendRequest() = %v, want %v", err, ErrTimeout)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := transport2.SendRequest(ctx, "0.0.0.0", []byte("pong"), true); err != context.Canceled {
+		t.Errorf("SendRequest() = %v, want %v", err, context.Canceled)
+	}
+	if _, err := transport2.SendRequest(context.Background(), "0.0.0.0", []byte("too large"), true); err != ErrMessageTooLarge {
+		t.Errorf("SendRequest() = %v, want %v", err, ErrMessageTooLarge)
+	}
+
+	transport1.Close()
+	select {
+	case <-wait:
+	case <-time.After(1 * time.Second):
+		t.Errorf("Listen() = %v, want %v", "running", "stopped")
+	}
+	if _, err := transport2.SendRequest(context.Background(), "0.0.0.0", []byte("ping"), true); err != ErrUnreachable {
+		t.Errorf("SendRequest() = %v, want %v", err, ErrUnreachable)
+	}
+}
+
+func TestStreamMessage(t *testing.T) {
+	local, remote := net.Pipe()
+	msg := []byte("Hello stream!")
+	go func() {
+		writeStreamMessage(local, msg)
+		local.Close()
+	}()
+	if result, err := readStreamMessage(remote); err != nil || string(result) != string(msg) {
+		t.Errorf("readStreamMessage() = %v, %v, want %v, %v", string(result), err, string(msg), nil)
+	}
+	if _, err := readStreamMessage(remote); err != io.EOF {
+		t.Errorf("readStreamMessage() = %v, want %v", err, io.EOF)
+	}
+	if err := writeStreamMessage(local, make([]byte, MAX_STREAM_MESSAGE_SIZE+1)); err != ErrStreamMessageTooLarge {
+		t.Errorf("writeStreamMessage() = %v, want %v", err, ErrStreamMessageTooLarge)
+	}
+}
+
+// The same seed gives the same losses, duplicates and delays
+func TestSimulator_Deterministic(t *testing.T) {
+	run := func(seed int64) ([]bool, int, time.Duration) {
+		sim := NewSimulator(seed)
+		defer sim.Stop()
+		sim.SetDefaultLink(LinkConfig{Latency: 5 * time.Millisecond, Jitter: 10 * time.Millisecond, Loss: 0.3,
+			Duplication: 0.2})
+		transport1 := sim.NewTransport("0.0.0.0", "5001", MAX_DATAGRAM_SIZE, 50*time.Millisecond, false)
+		transport2 := sim.NewTransport("0.0.0.1", "5001", MAX_DATAGRAM_SIZE, 50*time.Millisecond, false)
+		received := 0
+		go transport1.Listen(func(ctx context.Context, request []byte, from string, reply func([]byte) error) {
+			received++
+			reply(request)
+		})
+		defer transport1.Close()
+		ctx, done := sim.Participate(context.Background())
+		defer done()
+		sim.Wait(ctx)
+
+		replies := make([]bool, 50)
+		for i := range replies {
+			reply, err := transport2.SendRequest(ctx, "0.0.0.0", []byte{byte(i)}, true)
+			replies[i] = err == nil && reply[0] == byte(i)
+		}
+		sim.Wait(ctx)
+		return replies, received, sim.Now()
+	}
+
+	replies1, received1, now1 := run(1)
+	replies2, received2, now2 := run(1)
+	if fmt.Sprint(replies1) != fmt.Sprint(replies2) || received1 != received2 || now1 != now2 {
+		t.Errorf("run() = %v, %v, %v, want %v, %v, %v", replies2, received2, now2, replies1, received1, now1)
+	}
+	lost := 0
+	for _, ok := range replies1 {
+		if !ok {
+			lost++
+		}
+	}
+	if lost == 0 || lost == len(replies1) || received1 <= len(replies1)-lost {
+		t.Errorf("run() lost %d of %d requests and handled %d, want some lost and some duplicated",
+			lost, len(replies1), received1)
+	}
+	if now1 < time.Duration(len(replies1))*5*time.Millisecond {
+		t.Errorf("Now() = %v, want at least %v", now1, time.Duration(len(replies1))*5*time.Millisecond)
+	}
+}
+
+// Requests that goroutines started together send at the same time are handled in the same order in every run
+func TestSimulator_Go(t *testing.T) {
+	run := func() ([]byte, time.Duration) {
+		sim := NewSimulator(1)
+		defer sim.Stop()
+		sim.SetDefaultLink(LinkConfig{Latency: 5 * time.Millisecond})
+		server := sim.NewTransport("0.0.0.0", "5001", MAX_DATAGRAM_SIZE, 50*time.Millisecond, false)
+		var handled []byte
+		go server.Listen(func(ctx context.Context, request []byte, from string, reply func([]byte) error) {
+			handled = append(handled, request[0])
+			reply(request)
+		})
+		defer server.Close()
+		ctx, done := sim.Participate(context.Background())
+		defer done()
+
+		results := make(chan error, 10)
+		for i := 0; i < 10; i++ {
+			client := sim.NewTransport("0.0.1."+strconv.Itoa(i), "5001", MAX_DATAGRAM_SIZE, 50*time.Millisecond, false)
+			i := i
+			client.Go(ctx, func(ctx context.Context) {
+				_, err := client.SendRequest(ctx, "0.0.0.0", []byte{byte(i)}, true)
+				results <- err
+			})
+		}
+		for i := 0; i < 10; i++ {
+			sim.Block(ctx, func() {
+				if err := <-results; err != nil {
+					t.Errorf("SendRequest() error = %v", err)
+				}
+			})
+		}
+		return handled, sim.Now()
+	}
+
+	handled1, now1 := run()
+	handled2, now2 := run()
+	if !bytes.Equal(handled1, handled2) || len(handled1) != 10 || now1 != now2 || now1 != 10*time.Millisecond {
+		t.Errorf("run() = %v, %v, want %v, %v", handled2, now2, handled1, now1)
+	}
+}
+
+// A request that expects no reply, like the ones that refresh the TTL of data, doesn't keep virtual time from
+// advancing
+func TestSimulator_NoReply(t *testing.T) {
+	sim := NewSimulator(1)
+	defer sim.Stop()
+	sim.SetDefaultLink(LinkConfig{Latency: 5 * time.Millisecond})
+	server := sim.NewTransport("0.0.0.0", "5001", MAX_DATAGRAM_SIZE, 50*time.Millisecond, false)
+	go server.Listen(func(ctx context.Context, request []byte, from string, reply func([]byte) error) {})
+	defer server.Close()
+	client := sim.NewTransport("0.0.0.1", "5001", MAX_DATAGRAM_SIZE, 50*time.Millisecond, false)
+
+	finished := make(chan error, 1)
+	go func() {
+		if _, err := client.SendRequest(context.Background(), "0.0.0.0", []byte{1}, false); err != nil {
+			finished <- err
+			return
+		}
+		ctx, done := sim.Participate(context.Background())
+		defer done()
+		// The server never replies, so the request only returns once virtual time reaches its timeout
+		_, err := client.SendRequest(ctx, "0.0.0.0", []byte{2}, true)
+		finished <- err
+	}()
+
+	select {
+	case err := <-finished:
+		if !IsTimeout(err) {
+			t.Errorf("SendRequest() error = %v, want a timeout", err)
+		}
+		if sim.Now() < 50*time.Millisecond {
+			t.Errorf("Now() = %v, want at least %v", sim.Now(), 50*time.Millisecond)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Virtual time stopped after a request that expects no reply")
+	}
+}
+
+func TestIsTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"No error", nil, false},
+		{"Memory transport", ErrTimeout, true},
+		{"Read deadline", &net.OpError{Op: "read", Net: "udp", Err: os.ErrDeadlineExceeded}, true},
+		{"Context deadline", context.DeadlineExceeded, false},
+		{"Other error", ErrUnreachable, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTimeout(tt.err); got != tt.want {
+				t.Errorf("IsTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}